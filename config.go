@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// The tuning knobs of the server and of the simulation.
+// loadConfig overrides them from the command line or the environment.
+var (
+	listenAddr   = ":8080"
+	numDrivers   = 10000
+	treeCapacity = 4
+	moveInterval = 2 * time.Second
+	// Largest step of an average driver at each move, in degrees
+	// (0.05 is about 5 km): each driver moves at 0.5x-1.5x this speed
+	driverSpeed = 0.05
+	// Default half-size (in degrees) of the /find-nearby search box
+	searchRadiusX = 20.0
+	searchRadiusY = 20.0
+	// Requests per second allowed to each client IP (0: no limit),
+	// and the burst it can send at once
+	rateLimit = 20.0
+	rateBurst = 40
+	// Comma-separated CORS allowlists (empty: all origins, and the
+	// default methods and headers of gin-contrib/cors)
+	corsOrigins = ""
+	corsMethods = ""
+	corsHeaders = ""
+	// Prometheus metrics on /metrics: "on" or "off"
+	metricsMode = "on"
+	// How long in-flight requests get to finish after SIGINT/SIGTERM
+	shutdownTimeout = 10 * time.Second
+	// Where the fleets are saved at shutdown and restored from at startup
+	// (empty: nowhere), see saveSnapshots and loadSnapshots
+	snapshotPath = ""
+)
+
+// configEnv maps each flag to the environment variable that can set it
+var configEnv = map[string]string{
+	"addr":             "ADDR",
+	"drivers":          "DRIVERS",
+	"capacity":         "CAPACITY",
+	"move-interval":    "MOVE_INTERVAL",
+	"driver-speed":     "DRIVER_SPEED_DEG_PER_TICK",
+	"search-radius-x":  "SEARCH_RADIUS_X",
+	"search-radius-y":  "SEARCH_RADIUS_Y",
+	"world-width":      "WORLD_WIDTH",
+	"world-height":     "WORLD_HEIGHT",
+	"rate-limit":       "RATE_LIMIT",
+	"rate-burst":       "RATE_BURST",
+	"cors-origins":     "CORS_ORIGINS",
+	"cors-methods":     "CORS_METHODS",
+	"cors-headers":     "CORS_HEADERS",
+	"metrics":          "METRICS",
+	"shutdown-timeout": "SHUTDOWN_TIMEOUT",
+	"snapshot-path":    "SNAPSHOT_PATH",
+}
+
+// loadConfig reads the tuning knobs from the command line (e.g.
+// "-drivers=5000 -capacity=8 -addr=:9090"). A flag that is not given
+// falls back to its environment variable (e.g. DRIVERS=5000),
+// and then to the current value.
+func loadConfig(args []string) error {
+	fs := flag.NewFlagSet("georunner", flag.ContinueOnError)
+	fs.StringVar(&listenAddr, "addr", listenAddr, "address the API server listens on")
+	fs.IntVar(&numDrivers, "drivers", numDrivers, "number of simulated drivers")
+	fs.IntVar(&treeCapacity, "capacity", treeCapacity, "points per QuadTree node before it splits")
+	fs.DurationVar(&moveInterval, "move-interval", moveInterval, "time between two moves of a driver")
+	fs.Float64Var(&driverSpeed, "driver-speed", driverSpeed, "largest step of an average driver at each move, in degrees")
+	fs.Float64Var(&searchRadiusX, "search-radius-x", searchRadiusX, "default half-width of the search box, in degrees")
+	fs.Float64Var(&searchRadiusY, "search-radius-y", searchRadiusY, "default half-height of the search box, in degrees")
+	fs.Float64Var(&worldBoundary.Width, "world-width", worldBoundary.Width, "half-width of the world, in degrees of longitude")
+	fs.Float64Var(&worldBoundary.Height, "world-height", worldBoundary.Height, "half-height of the world, in degrees of latitude")
+	fs.Float64Var(&rateLimit, "rate-limit", rateLimit, "requests per second allowed to each client IP (0: no limit)")
+	fs.IntVar(&rateBurst, "rate-burst", rateBurst, "requests a client IP can send at once before being limited")
+	fs.StringVar(&corsOrigins, "cors-origins", corsOrigins, "comma-separated origins allowed by CORS, e.g. https://app.example.com (empty: all)")
+	fs.StringVar(&corsMethods, "cors-methods", corsMethods, "comma-separated methods allowed by CORS (empty: the defaults)")
+	fs.StringVar(&corsHeaders, "cors-headers", corsHeaders, "comma-separated request headers allowed by CORS (empty: the defaults)")
+	fs.StringVar(&metricsMode, "metrics", metricsMode, "Prometheus metrics on /metrics: on or off")
+	fs.DurationVar(&shutdownTimeout, "shutdown-timeout", shutdownTimeout, "time in-flight requests get to finish at shutdown, e.g. 30s")
+	fs.StringVar(&snapshotPath, "snapshot-path", snapshotPath, "file the fleets are saved to at shutdown and restored from at startup, one per fleet (empty: not saved)")
+
+	// The environment first, so the command line wins
+	for name, env := range configEnv {
+		if value, ok := os.LookupEnv(env); ok {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid %s=%q: %w", env, value, err)
+			}
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// --- Validation ---
+	switch {
+	case numDrivers < 0:
+		return errors.New("the number of drivers can't be negative")
+	case treeCapacity < 1:
+		return errors.New("the capacity must be at least 1")
+	case moveInterval <= 0:
+		return errors.New("the move interval must be positive")
+	case !(driverSpeed > 0 && driverSpeed < 1):
+		return errors.New("the driver speed must be between 0 and 1 degree per move (both excluded)")
+	case !(searchRadiusX > 0 && searchRadiusX <= 180) || !(searchRadiusY > 0 && searchRadiusY <= 90):
+		return errors.New("the search radius must be positive and at most 180 (x) / 90 (y)")
+	case !(rateLimit >= 0) || math.IsInf(rateLimit, 0):
+		return errors.New("the rate limit must be a non-negative number (0 disables it)")
+	case rateLimit > 0 && rateBurst < 1:
+		return errors.New("the rate burst must be at least 1")
+	case metricsMode != "on" && metricsMode != "off":
+		return fmt.Errorf("the metrics must be on or off, not %q", metricsMode)
+	case shutdownTimeout <= 0:
+		return errors.New("the shutdown timeout must be positive")
+	}
+	if err := worldBoundary.Validate(); err != nil {
+		return fmt.Errorf("the world boundary is malformed: %w", err)
+	}
+	// Drivers are checked with quadtree.ValidatePoint (longitude in
+	// [-180, 180], latitude in [-90, 90]): a larger world would hold
+	// positions that addDriver and moveDriver reject
+	if worldBoundary.MinX() < -180 || worldBoundary.MaxX() > 180 || worldBoundary.MinY() < -90 || worldBoundary.MaxY() > 90 {
+		return fmt.Errorf("the world must fit in [-180, 180] x [-90, 90], not %+v", worldBoundary)
+	}
+	if err := validateCORS(); err != nil {
+		return fmt.Errorf("the CORS settings are invalid: %w", err)
+	}
+	return nil
+}
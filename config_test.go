@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"GeoRunner/quadtree"
+)
+
+// TestLoadConfig checks the precedence flag > environment > default,
+// and the validation of the values
+func TestLoadConfig(t *testing.T) {
+	// Restore the defaults between the cases and for the other tests
+	saved := []any{listenAddr, numDrivers, treeCapacity, moveInterval, searchRadiusX, searchRadiusY, worldBoundary, driverSpeed, rateLimit, rateBurst, corsOrigins, corsMethods, corsHeaders, metricsMode, shutdownTimeout, snapshotPath}
+	restore := func() {
+		listenAddr, numDrivers, treeCapacity = saved[0].(string), saved[1].(int), saved[2].(int)
+		moveInterval, searchRadiusX, searchRadiusY = saved[3].(time.Duration), saved[4].(float64), saved[5].(float64)
+		worldBoundary, driverSpeed = saved[6].(quadtree.Boundary), saved[7].(float64)
+		rateLimit, rateBurst = saved[8].(float64), saved[9].(int)
+		corsOrigins, corsMethods, corsHeaders = saved[10].(string), saved[11].(string), saved[12].(string)
+		metricsMode, shutdownTimeout, snapshotPath = saved[13].(string), saved[14].(time.Duration), saved[15].(string)
+	}
+	t.Cleanup(restore)
+
+	// --- Test 1: flags and environment ---
+	t.Setenv("DRIVERS", "5000")
+	t.Setenv("CAPACITY", "16")
+	t.Setenv("DRIVER_SPEED_DEG_PER_TICK", "0.2")
+	if err := loadConfig([]string{"-capacity=8", "-addr=:9090", "-move-interval=500ms"}); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if numDrivers != 5000 {
+		t.Errorf("DRIVERS=5000: 5000 drivers expected, got %d", numDrivers)
+	}
+	if treeCapacity != 8 {
+		t.Errorf("The flag must win over the environment: capacity 8 expected, got %d", treeCapacity)
+	}
+	if driverSpeed != 0.2 {
+		t.Errorf("DRIVER_SPEED_DEG_PER_TICK=0.2: speed 0.2 expected, got %v", driverSpeed)
+	}
+	if listenAddr != ":9090" || moveInterval != 500*time.Millisecond {
+		t.Errorf("Unexpected addr %q or move interval %s", listenAddr, moveInterval)
+	}
+	if err := loadConfig([]string{"-rate-limit=0", "-rate-burst=0"}); err != nil {
+		t.Errorf("No burst is needed without rate limit, got %v", err)
+	}
+	if searchRadiusX != 20 || worldBoundary.Width != 180 || metricsMode != "on" {
+		t.Errorf("The knobs not set must keep their defaults")
+	}
+	t.Setenv("METRICS", "off")
+	if err := loadConfig(nil); err != nil || metricsMode != "off" {
+		t.Errorf("METRICS=off: metrics off expected, got %q (%v)", metricsMode, err)
+	}
+	t.Setenv("SHUTDOWN_TIMEOUT", "30s")
+	t.Setenv("SNAPSHOT_PATH", "/tmp/drivers.snapshot")
+	if err := loadConfig([]string{"-snapshot-path=/var/lib/georunner/drivers.snapshot"}); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if shutdownTimeout != 30*time.Second || snapshotPath != "/var/lib/georunner/drivers.snapshot" {
+		t.Errorf("Unexpected shutdown timeout %s or snapshot path %q", shutdownTimeout, snapshotPath)
+	}
+
+	// A smaller world is fine, within the coordinates ValidatePoint accepts
+	if err := loadConfig([]string{"-world-width=90", "-world-height=90"}); err != nil {
+		t.Errorf("-world-width=90 -world-height=90: no error expected, got %v", err)
+	}
+
+	// --- Test 2: invalid values are rejected ---
+	for _, args := range [][]string{{"-capacity=0"}, {"-drivers=-1"}, {"-search-radius-x=0"}, {"-search-radius-y=91"}, {"-drivers=many"}, {"-world-width=0"}, {"-world-height=NaN"}, {"-world-width=200"}, {"-world-height=90.5"}, {"-driver-speed=0"}, {"-driver-speed=1"}, {"-rate-limit=-1"}, {"-rate-limit=Inf"}, {"-rate-burst=0"}, {"-cors-origins=app.example.com"}, {"-cors-origins=https://*.example.com"}, {"-metrics=maybe"}, {"-shutdown-timeout=0"}} {
+		restore()
+		if err := loadConfig(args); err == nil {
+			t.Errorf("loadConfig(%v): error expected", args)
+		}
+	}
+	t.Setenv("MOVE_INTERVAL", "soon")
+	if err := loadConfig(nil); err == nil {
+		t.Errorf("MOVE_INTERVAL=soon: error expected")
+	}
+}
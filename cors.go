@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+)
+
+// corsConfig builds the CORS settings from -cors-origins, -cors-methods and
+// -cors-headers. Without an origin list every origin is allowed, like
+// cors.Default(): fine for the demo, to be restricted in a deployment.
+// Without a method or header list, those of cors.DefaultConfig are used.
+func corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+
+	origins := splitList(corsOrigins)
+	if len(origins) == 0 || slices.Equal(origins, []string{"*"}) {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = origins
+	}
+	if methods := splitList(corsMethods); len(methods) > 0 {
+		config.AllowMethods = methods
+	}
+	if headers := splitList(corsHeaders); len(headers) > 0 {
+		config.AllowHeaders = headers
+	}
+	return config
+}
+
+// validateCORS checks the CORS settings, so a typo stops the server
+// at startup instead of making cors.New panic
+func validateCORS() error {
+	config := corsConfig()
+	for _, origin := range config.AllowOrigins {
+		// Wildcards are not enabled: a "*" can only stand alone
+		if strings.Contains(origin, "*") {
+			return errors.New("a CORS origin can't contain '*' (use -cors-origins='*' alone to allow all)")
+		}
+	}
+	return config.Validate()
+}
+
+// originAllowed tells whether a WebSocket handshake comes from an allowed
+// origin. Browsers can't be stopped by CORS from opening a WebSocket,
+// so the upgrader has to apply the same list itself. Requests without
+// an Origin header don't come from a browser page and are accepted.
+func originAllowed(r *http.Request) bool {
+	config := corsConfig()
+	origin := r.Header.Get("Origin")
+	return config.AllowAllOrigins || origin == "" || slices.Contains(config.AllowOrigins, origin)
+}
+
+// splitList splits a comma-separated flag value, dropping the blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
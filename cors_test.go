@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// TestCORSAllowlist checks the preflight answers with and without an
+// origin allowlist, and that the WebSocket upgrader follows the same list
+func TestCORSAllowlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := []string{corsOrigins, corsMethods, corsHeaders}
+	t.Cleanup(func() { corsOrigins, corsMethods, corsHeaders = saved[0], saved[1], saved[2] })
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(cors.New(corsConfig()))
+		r.GET("/find-nearby", func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodOptions, "/find-nearby", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	wsFrom := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/ws/nearby", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return req
+	}
+
+	// --- Test 1: no allowlist, every origin is allowed (the demo setup) ---
+	corsOrigins, corsMethods, corsHeaders = "", "", ""
+	if got := preflight("https://anywhere.example").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Without allowlist: Access-Control-Allow-Origin * expected, got %q", got)
+	}
+	if !originAllowed(wsFrom("https://anywhere.example")) {
+		t.Error("Without allowlist: every WebSocket origin must be accepted")
+	}
+
+	// --- Test 2: an allowlist, with its own methods and headers ---
+	corsOrigins = " https://app.example.com, http://localhost:3000 "
+	corsMethods = "GET,OPTIONS"
+	corsHeaders = "Content-Type,Authorization"
+	if err := validateCORS(); err != nil {
+		t.Fatalf("validateCORS: %v", err)
+	}
+	w := preflight("http://localhost:3000")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("Allowed origin: echoed back expected, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET,OPTIONS" {
+		t.Errorf("Allowed methods GET,OPTIONS expected, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type,Authorization" {
+		t.Errorf("Allowed headers Content-Type,Authorization expected, got %q", got)
+	}
+	if w := preflight("https://evil.example"); w.Code != http.StatusForbidden {
+		t.Errorf("Other origin: status 403 expected, got %d", w.Code)
+	}
+
+	// --- Test 3: the WebSocket upgrader applies the same list ---
+	if !originAllowed(wsFrom("https://app.example.com")) || !originAllowed(wsFrom("")) {
+		t.Error("Allowed origins and non-browser clients must be accepted")
+	}
+	if originAllowed(wsFrom("https://evil.example")) {
+		t.Error("Other origins must be refused by the upgrader")
+	}
+}
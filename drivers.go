@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errDriverExists = errors.New("driver already registered")
+	errOutsideWorld = errors.New("coordinates outside the world boundary")
+	errNoDriver     = errors.New("driver not registered")
+)
+
+// DriverResponse is the JSON representation of a driver returned by the API
+type DriverResponse struct {
+	ID    string  `json:"id"`
+	Fleet string  `json:"fleet,omitempty"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	// available, busy or offline
+	State string `json:"state,omitempty"`
+	// Speed (km/h) and heading (degrees clockwise from North) of the last move
+	SpeedKmh float64 `json:"speedKmh"`
+	Heading  float64 `json:"heading"`
+	// Distance from the search point (only set by the search endpoints;
+	// /find-nearby sets it only with include_distance=true)
+	DistanceKm float64 `json:"distanceKm,omitempty"`
+}
+
+// DriverData is the Data of a driver's point in the fleet trees
+type DriverData struct {
+	ID string
+	// available, busy or offline (see state.go)
+	State string
+	// Speed of the last move, in km/h (0: parked)
+	SpeedKmh float64
+	// Heading of the last move, in degrees clockwise from North
+	Heading float64
+}
+
+// newDriverResponse converts the point of a driver of fleet f
+func newDriverResponse(f *Fleet, p *quadtree.PointOf[DriverData]) DriverResponse {
+	return DriverResponse{
+		ID:       p.Data.ID,
+		Fleet:    f.Name,
+		Lat:      p.Y,
+		Lon:      p.X,
+		State:    driverState(p),
+		SpeedKmh: p.Data.SpeedKmh,
+		Heading:  p.Data.Heading,
+	}
+}
+
+// driverKey is the ID key of the fleet trees (see quadtree.WithIDKey):
+// a driver is found by its ID alone, so its state can change without
+// the driver changing identity
+func driverKey(d DriverData) DriverData {
+	return DriverData{ID: d.ID}
+}
+
+// byID returns the Data that looks up driver 'id' in a fleet tree
+func byID(id string) DriverData {
+	return DriverData{ID: id}
+}
+
+// The fleet trees are created WithIDKey(driverKey): they find drivers by ID on
+// their own and keep the index consistent with the tree under concurrent changes.
+
+// addDriver inserts a new driver into the tree of its fleet.
+// It rejects invalid coordinates before trying the insert,
+// and an ID already taken in any fleet.
+func addDriver(f *Fleet, p *quadtree.PointOf[DriverData]) error {
+	if err := quadtree.ValidatePoint(p); err != nil {
+		return err
+	}
+
+	registerMu.Lock()
+	defer registerMu.Unlock()
+	if other, _ := fleetOf(p.Data.ID); other != nil {
+		return errDriverExists
+	}
+	switch err := f.Tree.InsertE(p); {
+	case errors.Is(err, quadtree.ErrDuplicateID):
+		return errDriverExists
+	case errors.Is(err, quadtree.ErrOutOfBounds):
+		return errOutsideWorld
+	case err != nil:
+		return err
+	}
+	hub.publish(f, p, false)
+	return nil
+}
+
+// moveDriver sets the position of a registered driver and returns its
+// fleet and its new point. The heading follows the move, and the speed
+// becomes speedKmh (nil keeps the previous one). On error (invalid
+// coordinates, errNoDriver, errOutsideWorld) the driver stays where it was.
+func moveDriver(id string, lat, lon float64, speedKmh *float64) (*Fleet, *quadtree.PointOf[DriverData], error) {
+	if err := quadtree.ValidatePoint(&quadtree.PointOf[DriverData]{X: lon, Y: lat}); err != nil {
+		return nil, nil, err
+	}
+	f, _ := fleetOf(id)
+	if f == nil {
+		return nil, nil, errNoDriver
+	}
+	moved := f.Tree.UpdateByID(byID(id), func(cur quadtree.PointOf[DriverData]) quadtree.PointOf[DriverData] {
+		// A driver that stays put keeps its heading
+		if cur.X != lon || cur.Y != lat {
+			cur.Data.Heading = quadtree.BearingDegrees(cur.Y, cur.X, lat, lon)
+		}
+		if speedKmh != nil {
+			cur.Data.SpeedKmh = *speedKmh
+		}
+		cur.X, cur.Y = lon, lat
+		return cur
+	})
+	if moved == nil {
+		// Removed in the meantime, or a bad position
+		if _, ok := f.Tree.GetByID(byID(id)); !ok {
+			return nil, nil, errNoDriver
+		}
+		return nil, nil, errOutsideWorld
+	}
+	hub.publish(f, moved, false)
+	return f, moved, nil
+}
+
+// removeDriver deletes a driver from the tree of its fleet.
+// It returns false if the driver is not registered.
+func removeDriver(id string) bool {
+	f, last := fleetOf(id)
+	if f == nil || !f.Tree.RemoveByID(byID(id)) {
+		return false
+	}
+	hub.publish(f, last, true)
+	return true
+}
+
+// validCoordinates checks that (lat, lon) lies within the world boundary
+// (edges included). NaN values fail every comparison and are rejected too.
+func validCoordinates(lat, lon float64) bool {
+	return lon >= worldBoundary.X-worldBoundary.Width && lon <= worldBoundary.X+worldBoundary.Width &&
+		lat >= worldBoundary.Y-worldBoundary.Height && lat <= worldBoundary.Y+worldBoundary.Height
+}
+
+// handleCreateDriver registers a new driver:
+// POST /drivers {"id": "...", "lat": ..., "lon": ..., "fleet": "car"} (fleet is optional)
+func handleCreateDriver(c *gin.Context) {
+
+	var req struct {
+		ID    string   `json:"id" binding:"required"`
+		Lat   *float64 `json:"lat" binding:"required"`
+		Lon   *float64 `json:"lon" binding:"required"`
+		Fleet string   `json:"fleet"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a JSON object with 'id', 'lat' and 'lon'"})
+		return
+	}
+
+	if !validCoordinates(*req.Lat, *req.Lon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Coordinates outside the world boundary"})
+		return
+	}
+
+	if req.Fleet == "" {
+		req.Fleet = defaultFleet
+	}
+	f, ok := fleets[req.Fleet]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown fleet '" + req.Fleet + "'"})
+		return
+	}
+
+	p := &quadtree.PointOf[DriverData]{X: *req.Lon, Y: *req.Lat, Data: DriverData{ID: req.ID, State: stateAvailable}}
+
+	switch err := addDriver(f, p); {
+	case errors.Is(err, errDriverExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Driver '" + req.ID + "' already exists"})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, newDriverResponse(f, p))
+	}
+}
+
+// handleMoveDriver reports a new position of a driver:
+// PUT /drivers/:id {"lat": ..., "lon": ..., "speedKmh": ...} (speedKmh is optional)
+func handleMoveDriver(c *gin.Context) {
+
+	id := c.Param("id")
+
+	var req struct {
+		Lat      *float64 `json:"lat" binding:"required"`
+		Lon      *float64 `json:"lon" binding:"required"`
+		SpeedKmh *float64 `json:"speedKmh"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a JSON object with 'lat' and 'lon'"})
+		return
+	}
+
+	if !validCoordinates(*req.Lat, *req.Lon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Coordinates outside the world boundary"})
+		return
+	}
+
+	if req.SpeedKmh != nil && !(*req.SpeedKmh >= 0 && !math.IsInf(*req.SpeedKmh, 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'speedKmh' must be a non-negative number"})
+		return
+	}
+
+	switch f, p, err := moveDriver(id, *req.Lat, *req.Lon, req.SpeedKmh); {
+	case errors.Is(err, errNoDriver):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver '" + id + "' not found"})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, newDriverResponse(f, p))
+	}
+}
+
+// handleDeleteDriver removes a driver that went offline: DELETE /drivers/:id
+func handleDeleteDriver(c *gin.Context) {
+
+	id := c.Param("id")
+
+	if !removeDriver(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver '" + id + "' not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
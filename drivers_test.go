@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDeleteDriver removes a driver through DELETE /drivers/:id,
+// then checks that a second DELETE finds nothing
+func TestDeleteDriver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+
+	r := gin.New()
+	r.DELETE("/drivers/:id", handleDeleteDriver)
+	del := func(id string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/drivers/"+id, nil))
+		return w.Code
+	}
+
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 10, Y: 10, Data: DriverData{ID: "d1"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+
+	// --- Test 1: the driver is removed ---
+	if code := del("d1"); code != http.StatusNoContent {
+		t.Errorf("DELETE d1: 204 expected, got %d", code)
+	}
+	if _, ok := tree.GetByID(byID("d1")); ok {
+		t.Error("d1 is still in the tree")
+	}
+
+	// --- Test 2: it is not there anymore ---
+	if code := del("d1"); code != http.StatusNotFound {
+		t.Errorf("Second DELETE d1: 404 expected, got %d", code)
+	}
+}
+
+// TestCreateDriverWorldEdge registers drivers exactly on the East and
+// North world edges through POST /drivers and finds them in the tree
+func TestCreateDriverWorldEdge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+
+	r := gin.New()
+	r.POST("/drivers", handleCreateDriver)
+
+	edges := []struct {
+		id       string
+		lat, lon float64
+	}{
+		{"east", 0, 180},
+		{"north", 90, 0},
+		{"north-east", 90, 180},
+	}
+	for _, e := range edges {
+		body := fmt.Sprintf(`{"id":%q,"lat":%v,"lon":%v}`, e.id, e.lat, e.lon)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drivers", strings.NewReader(body)))
+
+		// --- Test 1: the insert is accepted ---
+		if w.Code != http.StatusCreated {
+			t.Errorf("POST %s: 201 expected, got %d (%s)", e.id, w.Code, w.Body.String())
+			continue
+		}
+		// --- Test 2: the driver is really stored where it was sent ---
+		if p, ok := tree.GetByID(byID(e.id)); !ok || p.X != e.lon || p.Y != e.lat {
+			t.Errorf("%s: stored at (%v, %v) expected, got %v (found %v)", e.id, e.lon, e.lat, p, ok)
+		}
+	}
+}
+
+// TestMoveDriverMotion moves a driver through PUT /drivers/:id and checks
+// the speed and heading that DriverData keeps next to the state
+func TestMoveDriverMotion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 0, Y: 0, Data: DriverData{ID: "d1", State: stateAvailable}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+
+	r := gin.New()
+	r.PUT("/drivers/:id", handleMoveDriver)
+	put := func(body string) (int, DriverResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/drivers/d1", strings.NewReader(body)))
+		var d DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &d)
+		return w.Code, d
+	}
+
+	// --- Test 1: a move north at 40 km/h ---
+	if code, d := put(`{"lat":1,"lon":0,"speedKmh":40}`); code != http.StatusOK || d.SpeedKmh != 40 || d.Heading != 0 {
+		t.Errorf("North at 40 km/h expected, got %d %+v", code, d)
+	}
+
+	// --- Test 2: without speedKmh the speed is kept, the heading follows the move ---
+	if code, d := put(`{"lat":1,"lon":1}`); code != http.StatusOK || d.SpeedKmh != 40 || math.Abs(d.Heading-90) > 0.1 {
+		t.Errorf("East at 40 km/h expected, got %d %+v", code, d)
+	}
+
+	// --- Test 3: a state change keeps the motion, going offline parks the driver ---
+	setDriverState(fleets[defaultFleet], "d1", stateBusy)
+	if p, _ := tree.GetByID(byID("d1")); p.Data.State != stateBusy || p.Data.SpeedKmh != 40 {
+		t.Errorf("Busy at 40 km/h expected, got %+v", p.Data)
+	}
+	setDriverState(fleets[defaultFleet], "d1", stateOffline)
+	if p, _ := tree.GetByID(byID("d1")); p.Data.State != stateOffline || p.Data.SpeedKmh != 0 {
+		t.Errorf("Offline and parked expected, got %+v", p.Data)
+	}
+
+	// --- Test 4: a negative speed is rejected ---
+	if code, _ := put(`{"lat":2,"lon":1,"speedKmh":-5}`); code != http.StatusBadRequest {
+		t.Errorf("speedKmh=-5: 400 expected, got %d", code)
+	}
+}
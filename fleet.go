@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fleet is one category of vehicles, with its own tree: the searches of a
+// fleet never walk the drivers of the others
+type Fleet struct {
+	Name string
+	Tree *quadtree.QuadTreeOf[DriverData]
+}
+
+// fleetNames lists the fleets, in the order used by GET /fleets and by
+// the searches that span every fleet
+var fleetNames = []string{"car", "bike", "truck"}
+
+// defaultFleet takes the drivers registered without a fleet
+const defaultFleet = "car"
+
+// fleets is built by newFleets before the server starts, and only read afterwards
+var fleets map[string]*Fleet
+
+// registerMu makes "is this ID free in every fleet?" and the insert
+// a single step, so two fleets never get the same driver ID
+var registerMu sync.Mutex
+
+// newFleets creates an empty tree for every fleet, indexed by driver ID
+func newFleets(capacity int) (map[string]*Fleet, error) {
+	fs := make(map[string]*Fleet, len(fleetNames))
+	for _, name := range fleetNames {
+		t, err := quadtree.NewQuadTreeOfChecked[DriverData](worldBoundary, capacity,
+			quadtree.WithIDKey(driverKey), quadtree.WithObserver(treeObserver{fleet: name}))
+		if err != nil {
+			return nil, err
+		}
+		fs[name] = &Fleet{Name: name, Tree: t}
+	}
+	return fs, nil
+}
+
+// allFleets returns every fleet, in the order of fleetNames
+func allFleets() []*Fleet {
+	all := make([]*Fleet, 0, len(fleetNames))
+	for _, name := range fleetNames {
+		all = append(all, fleets[name])
+	}
+	return all
+}
+
+// fleetOf returns the fleet of a registered driver and its point,
+// or nil if no fleet has it
+func fleetOf(id string) (*Fleet, *quadtree.PointOf[DriverData]) {
+	for _, f := range allFleets() {
+		if p, ok := f.Tree.GetByID(byID(id)); ok {
+			return f, p
+		}
+	}
+	return nil, nil
+}
+
+// selectFleets reads the optional 'fleet' parameter of the searches:
+// one fleet, or all of them when it is absent.
+// On an unknown fleet it replies with 400 and returns false.
+func selectFleets(c *gin.Context) ([]*Fleet, bool) {
+	name := c.Query("fleet")
+	if name == "" {
+		return allFleets(), true
+	}
+	f, ok := fleets[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown fleet '" + name + "'"})
+		return nil, false
+	}
+	return []*Fleet{f}, true
+}
+
+// FleetResponse is the JSON representation of a fleet in GET /fleets
+type FleetResponse struct {
+	Name        string `json:"name"`
+	TotalPoints int64  `json:"total_points"`
+}
+
+// handleFleets lists the fleets and their number of drivers: GET /fleets
+func handleFleets(c *gin.Context) {
+	list := make([]FleetResponse, 0, len(fleetNames))
+	for _, f := range allFleets() {
+		list = append(list, FleetResponse{Name: f.Name, TotalPoints: f.Tree.TotalPoints()})
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestFleets checks that each fleet keeps its own drivers, and that the
+// searches can target one fleet or all of them
+func TestFleets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+	car, bike := fleets["car"], fleets["bike"]
+
+	if err := addDriver(car, &quadtree.PointOf[DriverData]{X: 1, Y: 1, Data: DriverData{ID: "c1"}}); err != nil {
+		t.Fatalf("addDriver c1: %v", err)
+	}
+	if err := addDriver(bike, &quadtree.PointOf[DriverData]{X: 2, Y: 2, Data: DriverData{ID: "b1"}}); err != nil {
+		t.Fatalf("addDriver b1: %v", err)
+	}
+
+	// --- Test 1: an ID is unique across the fleets ---
+	if err := addDriver(bike, &quadtree.PointOf[DriverData]{X: 3, Y: 3, Data: DriverData{ID: "c1"}}); !errors.Is(err, errDriverExists) {
+		t.Errorf("c1 in a second fleet: errDriverExists expected, got %v", err)
+	}
+
+	// --- Test 2: moves and removals find the fleet of the driver ---
+	if f, _, err := moveDriver("b1", 2.5, 2.5, nil); err != nil || f != bike {
+		t.Errorf("moveDriver b1: bike fleet expected, got %v %v", f, err)
+	}
+	if !removeDriver("b1") || bike.Tree.Count() != 0 {
+		t.Errorf("removeDriver b1 must empty the bike fleet")
+	}
+	addDriver(bike, &quadtree.PointOf[DriverData]{X: 2, Y: 2, Data: DriverData{ID: "b2"}})
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	r.GET("/fleets", handleFleets)
+	get := func(path string, v any) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		json.Unmarshal(w.Body.Bytes(), v)
+		return w.Code
+	}
+
+	// --- Test 3: ?fleet= selects one tree, no fleet searches them all ---
+	var drivers []DriverResponse
+	if get("/find-nearby?lat=0&lon=0&fleet=bike", &drivers); len(drivers) != 1 || drivers[0].ID != "b2" || drivers[0].Fleet != "bike" {
+		t.Errorf("fleet=bike: [b2] expected, got %v", drivers)
+	}
+	drivers = nil
+	if get("/find-nearby?lat=0&lon=0", &drivers); len(drivers) != 2 || drivers[0].ID != "c1" {
+		t.Errorf("All fleets: [c1 b2] expected, got %v", drivers)
+	}
+	drivers = nil
+	if get("/find-nearby?lat=0&lon=0&nearest=true", &drivers); len(drivers) != 1 || drivers[0].ID != "c1" {
+		t.Errorf("nearest=true over all fleets: [c1] expected, got %v", drivers)
+	}
+	if code := get("/find-nearby?lat=0&lon=0&fleet=boat", &drivers); code != http.StatusBadRequest {
+		t.Errorf("fleet=boat: 400 expected, got %d", code)
+	}
+
+	// --- Test 4: GET /fleets counts the drivers of each fleet ---
+	var list []FleetResponse
+	get("/fleets", &list)
+	expected := []FleetResponse{{"car", 1}, {"bike", 1}, {"truck", 0}}
+	if len(list) != len(expected) {
+		t.Fatalf("%v expected, got %v", expected, list)
+	}
+	for i := range expected {
+		if list[i] != expected[i] {
+			t.Errorf("%v expected, got %v", expected, list)
+			break
+		}
+	}
+}
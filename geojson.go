@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geoJSONMediaType is the media type of GeoJSON documents (RFC 7946)
+const geoJSONMediaType = "application/geo+json"
+
+// FeatureCollection is a GeoJSON FeatureCollection of drivers
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+	// Cursor of the next page of a paginated search (a GeoJSON "foreign member")
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Feature is a GeoJSON Point feature for a single driver
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   Geometry          `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// Geometry is a GeoJSON Point geometry.
+// Coordinates are [lon, lat]: the opposite of the {lat, lon} of DriverResponse.
+type Geometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// FeatureProperties holds the driver fields that are not coordinates
+type FeatureProperties struct {
+	ID         string  `json:"id"`
+	State      string  `json:"state,omitempty"`
+	DistanceKm float64 `json:"distanceKm,omitempty"`
+}
+
+// wantsGeoJSON reports whether the client asked for GeoJSON, either
+// with "Accept: application/geo+json" or with "?format=geojson"
+func wantsGeoJSON(c *gin.Context) bool {
+	return c.Query("format") == "geojson" || strings.Contains(c.GetHeader("Accept"), geoJSONMediaType)
+}
+
+// toFeatureCollection converts drivers into a FeatureCollection
+func toFeatureCollection(drivers []DriverResponse) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(drivers))}
+	for _, d := range drivers {
+		fc.Features = append(fc.Features, Feature{
+			Type: "Feature",
+			Geometry: Geometry{
+				Type: "Point",
+				// GeoJSON order is [lon, lat]
+				Coordinates: [2]float64{d.Lon, d.Lat},
+			},
+			Properties: FeatureProperties{ID: d.ID, State: d.State, DistanceKm: d.DistanceKm},
+		})
+	}
+	return fc
+}
+
+// respondDrivers writes drivers as GeoJSON if the client asked for it,
+// as the usual JSON array of DriverResponse otherwise
+func respondDrivers(c *gin.Context, drivers []DriverResponse) {
+	metrics.observeResultSize(len(drivers))
+
+	if !wantsGeoJSON(c) {
+		c.JSON(http.StatusOK, drivers)
+		return
+	}
+
+	body, err := json.Marshal(toFeatureCollection(drivers))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, geoJSONMediaType, body)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestFindNearbyGeoJSON asks /find-nearby for GeoJSON with the Accept
+// header and checks the FeatureCollection a map library would read
+func TestFindNearbyGeoJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 12.5, Y: 41.9, Data: DriverData{ID: "d1"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	req := httptest.NewRequest(http.MethodGet, "/find-nearby?lat=41.9&lon=12.5", nil)
+	req.Header.Set("Accept", "application/geo+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// --- Test 1: the response is GeoJSON ---
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status: 200 expected, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != geoJSONMediaType {
+		t.Errorf("Content-Type: %q expected, got %q", geoJSONMediaType, ct)
+	}
+
+	// --- Test 2: a FeatureCollection of Points in [lon, lat] order ---
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				ID string `json:"id"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &fc); err != nil {
+		t.Fatalf("Invalid GeoJSON %q: %v", w.Body.String(), err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 1 {
+		t.Fatalf("FeatureCollection with 1 feature expected, got %s", w.Body.String())
+	}
+	f := fc.Features[0]
+	if f.Type != "Feature" || f.Geometry.Type != "Point" {
+		t.Errorf("Point Feature expected, got %q/%q", f.Type, f.Geometry.Type)
+	}
+	if len(f.Geometry.Coordinates) != 2 || f.Geometry.Coordinates[0] != 12.5 || f.Geometry.Coordinates[1] != 41.9 {
+		t.Errorf("Coordinates: [12.5 41.9] expected, got %v", f.Geometry.Coordinates)
+	}
+	if f.Properties.ID != "d1" {
+		t.Errorf("properties.id: d1 expected, got %q", f.Properties.ID)
+	}
+}
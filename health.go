@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthResponse is the body of a healthy /healthz
+type HealthResponse struct {
+	Status    string `json:"status"` // "ok"
+	Drivers   int    `json:"drivers"`
+	TreeDepth int    `json:"tree_depth"`
+}
+
+// handleHealthz is the health check for load balancers and readiness
+// probes: GET /healthz. It answers 200 with the number of drivers (all
+// fleets) and the depth of the deepest fleet tree, or 503 "degraded"
+// while there are no drivers yet (the simulation is still starting).
+func handleHealthz(c *gin.Context) {
+	drivers, depth := 0, 0
+	for _, f := range allFleets() {
+		drivers += f.Tree.Count()
+		depth = max(depth, f.Tree.Depth())
+	}
+
+	if drivers == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "degraded"})
+		return
+	}
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok", Drivers: drivers, TreeDepth: depth})
+}
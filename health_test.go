@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestHealthz checks that /healthz is degraded without drivers,
+// then reports them once there are some
+func TestHealthz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+
+	r := gin.New()
+	r.GET("/healthz", handleHealthz)
+
+	get := func() (int, string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return w.Code, w.Body.String()
+	}
+
+	// --- Test 1: no drivers yet ---
+	if code, body := get(); code != http.StatusServiceUnavailable || body != `{"status":"degraded"}` {
+		t.Errorf("503 degraded expected, got %d %s", code, body)
+	}
+
+	// --- Test 2: drivers in two fleets ---
+	for i, fleet := range []string{"car", "car", "bike"} {
+		p := &quadtree.PointOf[DriverData]{X: float64(i), Y: float64(i), Data: DriverData{ID: fleet + string(rune('a'+i))}}
+		if err := addDriver(fleets[fleet], p); err != nil {
+			t.Fatalf("addDriver: %v", err)
+		}
+	}
+	code, body := get()
+	if code != http.StatusOK {
+		t.Fatalf("200 expected, got %d %s", code, body)
+	}
+	var health HealthResponse
+	if err := json.Unmarshal([]byte(body), &health); err != nil {
+		t.Fatalf("Invalid body %s: %v", body, err)
+	}
+	if health.Status != "ok" || health.Drivers != 3 {
+		t.Errorf("ok with 3 drivers expected, got %+v", health)
+	}
+	// Few drivers: each tree is a single leaf, and the depth is still there
+	if body != `{"status":"ok","drivers":3,"tree_depth":0}` {
+		t.Errorf("Unexpected body %s", body)
+	}
+}
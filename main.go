@@ -1,11 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"GeoRunner/quadtree"
@@ -21,61 +26,196 @@ var worldBoundary = quadtree.Boundary{
 	Height: 90,
 }
 
-var tree *quadtree.QuadTree
+const maxNearestK = 100 // Largest k accepted by /nearest
+
+// keepInWorld brings a position that stepped out of worldBoundary back in
+// without teleporting the driver: crossing the East/West edge continues
+// from the opposite edge (like the antimeridian), and crossing the
+// North/South edge bounces back (like going over a pole).
+func keepInWorld(lat, lon float64) (float64, float64) {
+	minLon, maxLon := worldBoundary.MinX(), worldBoundary.MaxX()
+	minLat, maxLat := worldBoundary.MinY(), worldBoundary.MaxY()
+
+	// Keep the overshoot: 180.03 becomes -179.97, not -180
+	if lon > maxLon {
+		lon -= maxLon - minLon
+	} else if lon < minLon {
+		lon += maxLon - minLon
+	}
 
-const (
-	numDrivers    = 10000
-	moveInterval  = 2 * time.Second
-	searchRadiusX = 20.0
-	searchRadiusY = 20.0
-)
+	// Reflect on the edge: 90.03 becomes 89.97
+	if lat > maxLat {
+		lat = 2*maxLat - lat
+	} else if lat < minLat {
+		lat = 2*minLat - lat
+	}
+	return lat, lon
+}
 
-func simulateDriver(driverID string, seed int64) {
+// simulateDriver registers a driver in fleet f and moves it around until
+// it is removed through the API
+func simulateDriver(f *Fleet, driverID string, seed int64) {
 
 	rng := rand.New(rand.NewSource(time.Now().UnixNano() + seed))
 
 	time.Sleep(time.Duration(rng.Intn(5000)) * time.Millisecond)
 
-	currentPoint := &quadtree.Point{
-		X:    (rng.Float64() * 360) - 180,
-		Y:    (rng.Float64() * 180) - 90,
-		Data: driverID,
+	// A driver restored from a snapshot goes on from where it was...
+	state := stateAvailable
+	restored, currentPoint := fleetOf(driverID)
+	if restored != nil {
+		f, state = restored, driverState(currentPoint)
+	} else {
+		// ...the others start anywhere in the world
+		currentPoint = &quadtree.PointOf[DriverData]{
+			X:    worldBoundary.X + (rng.Float64()*2-1)*worldBoundary.Width,
+			Y:    worldBoundary.Y + (rng.Float64()*2-1)*worldBoundary.Height,
+			Data: DriverData{ID: driverID, State: stateAvailable},
+		}
+		if err := addDriver(f, currentPoint); err != nil {
+			log.Printf("Driver %s not started: %v", driverID, err)
+			return
+		}
 	}
-
-	tree.Insert(currentPoint)
+	// Some drivers are faster than others: 0.5x to 1.5x the configured speed
+	step := driverSpeed * (0.5 + rng.Float64())
 
 	for {
 
 		time.Sleep(moveInterval)
 
-		tree.Remove(currentPoint)
-
-		newLon := currentPoint.X + (rng.Float64()-0.5)*0.1
-		newLat := currentPoint.Y + (rng.Float64()-0.5)*0.1
+		// Take a ride, end it, go offline...
+		if next := nextState(rng, state); next != state {
+			// The driver may have been removed through the API: stop simulating it
+			if !setDriverState(f, driverID, next) {
+				return
+			}
+			state = next
+		}
+		// An offline driver stays parked
+		if state == stateOffline {
+			continue
+		}
 
-		if newLon > 180 {
-			newLon = -180
+		newLat, newLon := keepInWorld(
+			currentPoint.Y+(rng.Float64()*2-1)*step,
+			currentPoint.X+(rng.Float64()*2-1)*step,
+		)
+
+		// The speed of this move, from the distance covered in moveInterval
+		speedKmh := quadtree.HaversineKm(currentPoint.Y, currentPoint.X, newLat, newLon) / moveInterval.Hours()
+		_, newPoint, err := moveDriver(driverID, newLat, newLon, &speedKmh)
+		// The driver may have been removed through the API: stop simulating it
+		if errors.Is(err, errNoDriver) {
+			return
 		}
-		if newLon < -180 {
-			newLon = 180
+		// Otherwise a failed move just leaves the driver where it was
+		if err == nil {
+			currentPoint = newPoint
 		}
-		if newLat > 90 {
-			newLat = -90
+	}
+}
+
+// parseSearchRadius reads the optional search box size of /find-nearby:
+// either 'radiusKm' (converted to degrees at the given latitude),
+// 'radius_x'/'radius_y' (half-sizes in degrees, positive and at most
+// 180/90) or 'width'/'height' in degrees. radius_x/radius_y win over
+// width/height. Absent parameters keep the defaults.
+func parseSearchRadius(c *gin.Context, lat float64) (radiusX, radiusY float64, err error) {
+	radiusX, radiusY = searchRadiusX, searchRadiusY
+
+	// parse reads one non-negative number, if present
+	parse := func(name string, dst *float64) error {
+		str := c.Query(name)
+		if str == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("parameter '%s' must be a non-negative number", name)
 		}
-		if newLat < -90 {
-			newLat = 90
+		*dst = v
+		return nil
+	}
+
+	if c.Query("radiusKm") != "" {
+		var km float64
+		if err := parse("radiusKm", &km); err != nil {
+			return 0, 0, err
 		}
+		// A degree of longitude shrinks towards the poles:
+		// near them the box just covers the whole world
+		box := quadtree.BoundaryFromRadiusKm(lat, 0, km)
+		return box.Width, box.Height, nil
+	}
+
+	if err := parse("width", &radiusX); err != nil {
+		return 0, 0, err
+	}
+	if err := parse("height", &radiusY); err != nil {
+		return 0, 0, err
+	}
 
-		newPoint := &quadtree.Point{
-			X:    newLon,
-			Y:    newLat,
-			Data: driverID,
+	// parseRadius reads one radius in (0, max], if present
+	parseRadius := func(name string, max float64, dst *float64) error {
+		str := c.Query(name)
+		if str == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		// NaN fails both comparisons, +Inf is above max
+		if err != nil || !(v > 0 && v <= max) {
+			return fmt.Errorf("parameter '%s' must be a number in (0, %v]", name, max)
 		}
+		*dst = v
+		return nil
+	}
+	if err := parseRadius("radius_x", 180, &radiusX); err != nil {
+		return 0, 0, err
+	}
+	if err := parseRadius("radius_y", 90, &radiusY); err != nil {
+		return 0, 0, err
+	}
+	return radiusX, radiusY, nil
+}
 
-		tree.Insert(newPoint)
+// byDistance converts the points found in each fleet into DriverResponses
+// carrying their distance from (lat, lon), sorted closest first by
+// quadtree.SortByDistance (ties broken by ID, so the order is stable and
+// matches the cursor of pageByDistance). found[i] are the points of fs[i],
+// copied out of the tree (see pointCopies).
+func byDistance(fs []*Fleet, found [][]quadtree.PointOf[DriverData], lat, lon float64) []DriverResponse {
+	// One list for all the fleets, remembering the fleet of each driver
+	// (an ID is never in two fleets, see registerMu)
+	var all []quadtree.PointOf[DriverData]
+	fleetByID := map[string]*Fleet{}
+	for i, points := range found {
+		all = append(all, points...)
+		for _, p := range points {
+			fleetByID[p.Data.ID] = fs[i]
+		}
+	}
+	quadtree.SortByDistance(all, lon, lat, quadtree.HaversineDistance, func(a, b DriverData) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	results := make([]DriverResponse, len(all))
+	for i := range all {
+		p := &all[i]
+		results[i] = newDriverResponse(fleetByID[p.Data.ID], p)
+		results[i].DistanceKm = quadtree.HaversineKm(lat, lon, p.Y, p.X)
+	}
+	return results
+}
 
-		currentPoint = newPoint
+// pointCopies copies the points a search returned out of the tree, so the
+// handlers never hold on to the tree's own points (see QueryCopy)
+func pointCopies(points []*quadtree.PointOf[DriverData]) []quadtree.PointOf[DriverData] {
+	copies := make([]quadtree.PointOf[DriverData], len(points))
+	for i, p := range points {
+		copies[i] = *p
 	}
+	return copies
 }
 
 func handleFindNearby(c *gin.Context) {
@@ -90,54 +230,347 @@ func handleFindNearby(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Parametri 'lat' e 'lon' non validi o mancanti"})
 		return
 	}
+	if err := quadtree.ValidatePoint(&quadtree.PointOf[DriverData]{X: lon, Y: lat}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	searchArea := &quadtree.Boundary{
-		X:      lon,
-		Y:      lat,
-		Width:  searchRadiusX,
-		Height: searchRadiusY,
+	// Optional cap on the number of drivers returned (0 = unlimited)
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'limit' must be a non-negative integer"})
+			return
+		}
 	}
 
-	foundPoints := tree.Query(searchArea)
+	// Optional pagination: 'cursor' (empty for the first page) switches
+	// the response to a DriverPage of 'limit' drivers ordered by ID, or
+	// closest first with order=distance (see pageByDistance)
+	cursor, paginated := c.GetQuery("cursor")
+	byDistanceOrder := false
+	switch c.DefaultQuery("order", "id") {
+	case "id":
+	case "distance":
+		byDistanceOrder = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'order' must be id or distance"})
+		return
+	}
+	after := ""
+	var afterKey *distanceKey
+	if cursor != "" {
+		var err error
+		if byDistanceOrder {
+			var key distanceKey
+			key, err = decodeDistanceCursor(cursor)
+			afterKey = &key
+		} else {
+			after, err = decodeCursor(cursor)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if paginated && limit == 0 {
+		limit = defaultPageSize
+	}
 
-	type DriverResponse struct {
-		ID  string  `json:"id"`
-		Lat float64 `json:"lat"`
-		Lon float64 `json:"lon"`
+	// Optional state filter (e.g. state=available)
+	state := c.Query("state")
+	if state != "" && !validState(state) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'state' must be available, busy or offline"})
+		return
 	}
 
-	results := make([]DriverResponse, 0, len(foundPoints))
-	for _, p := range foundPoints {
+	// Optional size of the search box (defaults to searchRadiusX/Y)
+	radiusX, radiusY, err := parseSearchRadius(c, lat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		if id, ok := p.Data.(string); ok {
-			results = append(results, DriverResponse{
-				ID:  id,
-				Lat: p.Y,
-				Lon: p.X,
-			})
+	// Optional fleet (all of them by default)
+	fs, ok := selectFleets(c)
+	if !ok {
+		return
+	}
+
+	found := make([][]quadtree.PointOf[DriverData], len(fs))
+	nearest := c.Query("nearest") == "true"
+	for i, f := range fs {
+		if nearest {
+			// Only the single closest driver was requested: the closest of
+			// each fleet (in km) in the requested state, the best one is kept below
+			target := &quadtree.PointOf[DriverData]{X: lon, Y: lat}
+			var inState func(*quadtree.PointOf[DriverData]) bool
+			if state != "" {
+				inState = func(p *quadtree.PointOf[DriverData]) bool { return driverState(p) == state }
+			}
+			if p, _, ok := f.Tree.NearestFilter(target, quadtree.HaversineDistance, inState); ok {
+				found[i] = []quadtree.PointOf[DriverData]{*p}
+			}
+			continue
 		}
+
+		searchArea := &quadtree.Boundary{
+			X:      lon,
+			Y:      lat,
+			Width:  radiusX,
+			Height: radiusY,
+		}
+
+		// The search box may cross the antimeridian (±180).
+		// If the client goes away, the search stops instead of finishing for nobody.
+		// The results are copies: nothing of the tree leaves the handler.
+		var err error
+		found[i], err = f.Tree.QueryWrappedCopyContext(c.Request.Context(), searchArea)
+		if err != nil {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	// Closest drivers first...
+	results := byDistance(fs, found, lat, lon)
+
+	// ...in the requested state, before nearest and the limit are applied
+	if state != "" {
+		kept := results[:0]
+		for _, d := range results {
+			if d.State == state {
+				kept = append(kept, d)
+			}
+		}
+		results = kept
+	}
+	if nearest && len(results) > 1 {
+		results = results[:1]
+	}
+
+	// ...unless paging: pages follow the (stable) ID order,
+	// or stay closest first with order=distance
+	var nextCursor string
+	if paginated && byDistanceOrder {
+		results, nextCursor = pageByDistance(results, afterKey, limit)
+	} else if paginated {
+		results, nextCursor = pageByID(results, after, limit)
+	} else if limit > 0 && len(results) > limit {
+		// Keep only the closest 'limit' ones
+		results = results[:limit]
 	}
 
-	c.JSON(http.StatusOK, results)
+	// The distance is only sent when asked for with include_distance=true
+	// (omitempty drops the zeroed field from the response)
+	if c.Query("include_distance") != "true" {
+		for i := range results {
+			results[i].DistanceKm = 0
+		}
+	}
+
+	// Plain JSON or GeoJSON, depending on what the client asked for
+	if paginated {
+		respondDriverPage(c, results, nextCursor)
+		return
+	}
+	respondDrivers(c, results)
+}
+
+// parseLatLon reads the 'lat' and 'lon' query parameters. Missing values,
+// NaN, ±Inf and positions off the globe are answered with a 400 and ok = false.
+func parseLatLon(c *gin.Context) (lat, lon float64, ok bool) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameters 'lat' and 'lon' are invalid or missing"})
+		return 0, 0, false
+	}
+	// ParseFloat happily accepts "NaN" and "Inf"
+	if err := quadtree.ValidatePoint(&quadtree.PointOf[DriverData]{X: lon, Y: lat}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// handleFindNearbyCircle returns the drivers within radius_m meters of a point,
+// closest first: GET /find-nearby-circle?lat=...&lon=...&radius_m=...
+func handleFindNearbyCircle(c *gin.Context) {
+
+	lat, lon, ok := parseLatLon(c)
+	if !ok {
+		return
+	}
+	radiusM, err := strconv.ParseFloat(c.Query("radius_m"), 64)
+	if err != nil || !(radiusM >= 0) || math.IsInf(radiusM, 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'radius_m' must be a non-negative number"})
+		return
+	}
+
+	// QueryCircle with the great-circle distance, in km: the tree prunes the
+	// nodes out of reach on the sphere (across the antimeridian and over the
+	// poles too), so no bounding box in degrees is needed
+	fs, ok := selectFleets(c)
+	if !ok {
+		return
+	}
+
+	found := make([][]quadtree.PointOf[DriverData], len(fs))
+	for i, f := range fs {
+		found[i] = pointCopies(f.Tree.QueryCircleFunc(lon, lat, radiusM/1000, quadtree.HaversineDistance))
+	}
+
+	respondDrivers(c, byDistance(fs, found, lat, lon))
+}
+
+// handleNearest returns the k drivers closest to a point, closest first:
+// GET /nearest?lat=...&lon=...&k=... (k defaults to 1, at most maxNearestK)
+func handleNearest(c *gin.Context) {
+
+	lat, lon, ok := parseLatLon(c)
+	if !ok {
+		return
+	}
+
+	k := 1
+	if kStr := c.Query("k"); kStr != "" {
+		var err error
+		if k, err = strconv.Atoi(kStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'k' must be an integer"})
+			return
+		}
+	}
+	if k <= 0 {
+		k = 1
+	}
+	if k > maxNearestK {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Parameter 'k' must be at most %d", maxNearestK)})
+		return
+	}
+
+	// The tree ranks the drivers by their great-circle distance, not the
+	// distance in degrees, which is wrong away from the Equator.
+	// With several fleets, the k closest of each one, then the k best overall
+	fs, ok := selectFleets(c)
+	if !ok {
+		return
+	}
+	found := make([][]quadtree.PointOf[DriverData], len(fs))
+	for i, f := range fs {
+		found[i] = pointCopies(f.Tree.QueryKNearestFunc(&quadtree.PointOf[DriverData]{X: lon, Y: lat}, k, quadtree.HaversineDistance))
+	}
+	results := byDistance(fs, found, lat, lon)
+	if len(results) > k {
+		results = results[:k]
+	}
+	respondDrivers(c, results)
+}
+
+// handleFindInBBox returns the drivers inside a box given by its corners,
+// ordered by ID: GET /find-in-bbox?min_lat=...&min_lon=...&max_lat=...&max_lon=...
+// Like Query, the box includes its South/West edges and excludes its
+// North/East ones: to include the drivers exactly on the world's East
+// edge (lon 180), end the box past it.
+func handleFindInBBox(c *gin.Context) {
+
+	var corners [4]float64
+	for i, name := range []string{"min_lon", "min_lat", "max_lon", "max_lat"} {
+		v, err := strconv.ParseFloat(c.Query(name), 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter '" + name + "' is invalid or missing"})
+			return
+		}
+		corners[i] = v
+	}
+	box, err := quadtree.NewBoundaryFromCorners(corners[0], corners[1], corners[2], corners[3])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fs, ok := selectFleets(c)
+	if !ok {
+		return
+	}
+
+	// No search point to measure distances from: a stable order by ID
+	var results []DriverResponse
+	for _, f := range fs {
+		found, err := f.Tree.QueryContext(c.Request.Context(), &box)
+		if err != nil {
+			c.AbortWithStatus(http.StatusServiceUnavailable)
+			return
+		}
+		for _, p := range found {
+			results = append(results, newDriverResponse(f, p))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	respondDrivers(c, results)
 }
 
 func main() {
 
-	tree = quadtree.NewQuadTree(worldBoundary, 4)
+	// Flags and environment variables override the defaults (see config.go)
+	if err := loadConfig(os.Args[1:]); err != nil {
+		log.Fatalf("Configuration: %v", err)
+	}
+	if metricsMode == "on" {
+		metrics = newServerMetrics()
+	}
+
+	var err error
+	if fleets, err = newFleets(treeCapacity); err != nil {
+		log.Fatalf("World: %v", err)
+	}
+	// The drivers saved at the last shutdown, if any (see -snapshot-path)
+	if snapshotPath != "" {
+		if err := loadSnapshots(snapshotPath); err != nil {
+			log.Fatalf("Snapshot: %v", err)
+		}
+	}
+
+	if corsOrigins == "" {
+		log.Println("CORS allows every origin: set -cors-origins (CORS_ORIGINS) in a deployment")
+	}
 
 	log.Printf("Starting simulation with %d driver...", numDrivers)
 	for i := 0; i < numDrivers; i++ {
 		driverID := fmt.Sprintf("driver-%d", i)
-		go simulateDriver(driverID, int64(i))
+		// Spread the drivers evenly over the fleets
+		f := fleets[fleetNames[i%len(fleetNames)]]
+		go simulateDriver(f, driverID, int64(i))
 	}
 	log.Println("Simulation started in the background.")
 
 	r := gin.Default()
 
-	r.Use(cors.Default())
+	// CORS allowlist from -cors-origins (all origins when unset, see cors.go)
+	r.Use(cors.New(corsConfig()))
+	// Per-IP rate limiting, after CORS so the 429s still carry its headers
+	r.Use(newRateLimiter(rateLimit, rateBurst).middleware()...)
 
 	r.GET("/find-nearby", handleFindNearby)
+	r.GET("/nearest", handleNearest)
+	r.GET("/find-nearby-circle", handleFindNearbyCircle)
+	r.GET("/find-in-bbox", handleFindInBBox)
+	r.GET("/fleets", handleFleets)
+	r.GET("/healthz", handleHealthz)
+	r.POST("/drivers", handleCreateDriver)
+	r.PUT("/drivers/:id", handleMoveDriver)
+	r.DELETE("/drivers/:id", handleDeleteDriver)
+	r.GET("/ws/nearby", handleNearbyStream)
+	r.GET("/stream", handleStream)
+	r.GET("/events/nearby", handleNearbyEvents)
+
+	if metrics != nil {
+		r.GET("/metrics", metrics.handler())
+	}
 
-	log.Println("API server listening on http://localhost:8080")
-	r.Run(":8080")
+	log.Printf("API server listening on %s", listenAddr)
+	// Runs until SIGINT/SIGTERM, then drains the requests and exits
+	serveUntilSignal(&http.Server{Addr: listenAddr, Handler: r}, shutdownTimeout)
 }
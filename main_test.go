@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resetFleets gives the test empty fleets and returns the tree of the
+// default one, where the test drivers go
+func resetFleets(t *testing.T) *quadtree.QuadTreeOf[DriverData] {
+	t.Helper()
+	var err error
+	if fleets, err = newFleets(4); err != nil {
+		t.Fatalf("newFleets: %v", err)
+	}
+	return fleets[defaultFleet].Tree
+}
+
+// TestKeepInWorld checks that the simulated drivers stay in the world
+// without jumping across it
+func TestKeepInWorld(t *testing.T) {
+	cases := []struct {
+		lat, lon         float64
+		wantLat, wantLon float64
+	}{
+		{10, 20, 10, 20},                // Inside: unchanged
+		{0, 180, 0, 180},                // On the edge: unchanged
+		{0, 180.03, 0, -179.97},         // East of the antimeridian
+		{0, -180.03, 0, 179.97},         // West of the antimeridian
+		{90.03, 5, 89.97, 5},            // Over the North Pole
+		{-90.03, 5, -89.97, 5},          // Over the South Pole
+		{90.02, 180.01, 89.98, -179.99}, // Both at once
+	}
+	for _, c := range cases {
+		lat, lon := keepInWorld(c.lat, c.lon)
+		if math.Abs(lat-c.wantLat) > 1e-9 || math.Abs(lon-c.wantLon) > 1e-9 {
+			t.Errorf("keepInWorld(%v, %v): (%v, %v) expected, got (%v, %v)", c.lat, c.lon, c.wantLat, c.wantLon, lat, lon)
+		}
+	}
+}
+
+// TestFindNearbyRadius checks the radius_x/radius_y parameters of /find-nearby
+func TestFindNearbyRadius(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 10, Y: 0, Data: DriverData{ID: "east"}})
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 0, Y: 3, Data: DriverData{ID: "north"}})
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	get := func(query string) (int, []DriverResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0"+query, nil))
+		var drivers []DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &drivers)
+		return w.Code, drivers
+	}
+
+	// --- Test 1: the radius narrows the box on each axis ---
+	if code, drivers := get("&radius_x=5&radius_y=5"); code != http.StatusOK || len(drivers) != 1 || drivers[0].ID != "north" {
+		t.Errorf("radius_x=5&radius_y=5: [north] expected, got %d %v", code, drivers)
+	}
+	if _, drivers := get("&radius_x=15&radius_y=1"); len(drivers) != 1 || drivers[0].ID != "east" {
+		t.Errorf("radius_x=15&radius_y=1: [east] expected, got %v", drivers)
+	}
+	// Without them the defaults (20 x 20) find both
+	if _, drivers := get(""); len(drivers) != 2 {
+		t.Errorf("Default radius: 2 drivers expected, got %d", len(drivers))
+	}
+
+	// --- Test 2: invalid radii are rejected ---
+	for _, query := range []string{"&radius_x=0", "&radius_x=-1", "&radius_x=181", "&radius_y=91", "&radius_y=NaN", "&radius_x=Inf"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("%s: 400 expected, got %d", query, code)
+		}
+	}
+}
+
+// TestFindNearbyCursor checks that the cursor pages of /find-nearby
+// cover every driver exactly once
+func TestFindNearbyCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	for i := 0; i < 7; i++ {
+		tree.Insert(&quadtree.PointOf[DriverData]{X: float64(i), Y: 0, Data: DriverData{ID: fmt.Sprintf("driver-%d", i)}})
+	}
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	get := func(query string) (int, DriverPage) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0"+query, nil))
+		var page DriverPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w.Code, page
+	}
+
+	// --- Test 1: pages of 3 cover the 7 drivers in ID order ---
+	var ids []string
+	cursor, pages := "", 0
+	for {
+		code, page := get("&limit=3&cursor=" + cursor)
+		if code != http.StatusOK {
+			t.Fatalf("Page %d: 200 expected, got %d", pages, code)
+		}
+		for _, d := range page.Drivers {
+			ids = append(ids, d.ID)
+		}
+		pages++
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if pages != 3 || len(ids) != 7 || !sort.StringsAreSorted(ids) {
+		t.Errorf("3 pages of sorted IDs expected, got %d pages: %v", pages, ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] == ids[i-1] {
+			t.Errorf("Driver %s returned twice", ids[i])
+		}
+	}
+
+	// --- Test 2: the default page size is 50 ---
+	if _, page := get("&cursor="); len(page.Drivers) != 7 || page.NextCursor != "" {
+		t.Errorf("Single page of 7 expected, got %d (next %q)", len(page.Drivers), page.NextCursor)
+	}
+
+	// --- Test 3: a corrupted cursor is rejected ---
+	if code, _ := get("&cursor=%21%21"); code != http.StatusBadRequest {
+		t.Errorf("Bad cursor: 400 expected, got %d", code)
+	}
+
+	// --- Test 4: the cursor member is "nextCursor", in JSON and in GeoJSON ---
+	for _, format := range []string{"", "&format=geojson"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0&limit=3&cursor="+format, nil))
+		var body map[string]json.RawMessage
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if _, ok := body["nextCursor"]; !ok {
+			t.Errorf("Format %q: nextCursor expected in %s", format, w.Body.String())
+		}
+		if _, ok := body["next_cursor"]; ok {
+			t.Errorf("Format %q: next_cursor is gone, got %s", format, w.Body.String())
+		}
+	}
+}
+
+// TestFindNearbyDistanceCursor checks the pages of /find-nearby in the
+// distance order: closest first, every driver once, ties broken by ID,
+// and no overlap when drivers move between two pages
+func TestFindNearbyDistanceCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	// driver-1 and driver-m1 are at the same distance, on either side
+	for i := 1; i <= 6; i++ {
+		tree.Insert(&quadtree.PointOf[DriverData]{X: float64(i), Y: 0, Data: DriverData{ID: fmt.Sprintf("driver-%d", i)}})
+	}
+	tree.Insert(&quadtree.PointOf[DriverData]{X: -1, Y: 0, Data: DriverData{ID: "driver-m1"}})
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	get := func(query string) (int, DriverPage) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0&order=distance&include_distance=true"+query, nil))
+		var page DriverPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w.Code, page
+	}
+
+	// --- Test 1: pages of 3, closest first, ties by ID ---
+	var ids []string
+	cursor, pages := "", 0
+	for {
+		code, page := get("&limit=3&cursor=" + cursor)
+		if code != http.StatusOK {
+			t.Fatalf("Page %d: 200 expected, got %d", pages, code)
+		}
+		for _, d := range page.Drivers {
+			ids = append(ids, d.ID)
+		}
+		pages++
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	expected := []string{"driver-1", "driver-m1", "driver-2", "driver-3", "driver-4", "driver-5", "driver-6"}
+	if pages != 3 || !reflect.DeepEqual(ids, expected) {
+		t.Errorf("3 pages %v expected, got %d pages: %v", expected, pages, ids)
+	}
+
+	// --- Test 2: drivers moving between two pages ---
+	_, first := get("&limit=3&cursor=")
+	// A driver of the first page moves a little (still before the cursor),
+	// one of the next page moves too, and a new one enters behind the cursor
+	moveDriver("driver-1", 1.1, 0, nil)
+	moveDriver("driver-5", 4.9, 0, nil)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 0.5, Y: 0, Data: DriverData{ID: "driver-new"}})
+	_, second := get("&limit=3&cursor=" + first.NextCursor)
+	seen := map[string]bool{}
+	for _, d := range first.Drivers {
+		seen[d.ID] = true
+	}
+	for _, d := range second.Drivers {
+		if seen[d.ID] {
+			t.Errorf("Driver %s sent on both pages", d.ID)
+		}
+	}
+	if len(second.Drivers) != 3 || second.Drivers[0].ID != "driver-3" || second.Drivers[2].ID != "driver-5" {
+		t.Errorf("driver-3, driver-4, driver-5 expected on the second page, got %+v", second.Drivers)
+	}
+
+	// --- Test 3: bad order and bad cursors are rejected ---
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0&cursor=&order=random", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Bad order: 400 expected, got %d", w.Code)
+	}
+	if code, _ := get("&cursor=" + encodeCursor("driver-1")); code != http.StatusBadRequest {
+		t.Errorf("ID cursor in the distance order: 400 expected, got %d", code)
+	}
+}
+
+// TestNonFiniteCoordinates checks that NaN and Inf positions get a 400
+// from every search endpoint instead of an empty result
+func TestNonFiniteCoordinates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	r.GET("/find-nearby-circle", handleFindNearbyCircle)
+	r.GET("/nearest", handleNearest)
+	r.GET("/events/nearby", handleNearbyEvents)
+
+	for _, path := range []string{"/find-nearby?", "/find-nearby-circle?radius_m=100&", "/nearest?", "/events/nearby?"} {
+		for _, pos := range []string{"lat=NaN&lon=0", "lat=0&lon=NaN", "lat=Inf&lon=0", "lat=0&lon=-Inf"} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+pos, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s%s: 400 expected, got %d", path, pos, w.Code)
+			}
+		}
+	}
+}
+
+// TestFindNearbyState checks the state filter of /find-nearby
+func TestFindNearbyState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	for _, id := range []string{"a", "b", "c"} {
+		tree.Insert(&quadtree.PointOf[DriverData]{X: 1, Y: 1, Data: DriverData{ID: id}})
+	}
+	setDriverState(fleets[defaultFleet], "b", stateBusy)
+	setDriverState(fleets[defaultFleet], "c", stateOffline)
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	get := func(query string) (int, []DriverResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0"+query, nil))
+		var drivers []DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &drivers)
+		return w.Code, drivers
+	}
+
+	// --- Test 1: each state finds its driver (a has none: available) ---
+	for state, id := range map[string]string{stateAvailable: "a", stateBusy: "b", stateOffline: "c"} {
+		if _, drivers := get("&state=" + state); len(drivers) != 1 || drivers[0].ID != id || drivers[0].State != state {
+			t.Errorf("state=%s: [%s] expected, got %v", state, id, drivers)
+		}
+	}
+
+	// --- Test 2: no filter returns everybody, with their state ---
+	if _, drivers := get(""); len(drivers) != 3 || drivers[1].State != stateBusy {
+		t.Errorf("No filter: a, b (busy), c expected, got %v", drivers)
+	}
+
+	// --- Test 3: an unknown state is rejected ---
+	if code, _ := get("&state=sleeping"); code != http.StatusBadRequest {
+		t.Errorf("state=sleeping: 400 expected, got %d", code)
+	}
+
+	// --- Test 4: nearest=true is the closest driver in the state ---
+	// An available driver closer than everybody must not hide the others
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 0.1, Y: 0.1, Data: DriverData{ID: "z", State: stateAvailable}})
+	for state, id := range map[string]string{stateAvailable: "z", stateBusy: "b", stateOffline: "c"} {
+		if _, drivers := get("&nearest=true&state=" + state); len(drivers) != 1 || drivers[0].ID != id {
+			t.Errorf("nearest=true&state=%s: [%s] expected, got %v", state, id, drivers)
+		}
+	}
+}
+
+// TestFindNearbyConcurrentMoves searches /find-nearby while other goroutines
+// move the drivers and change their state. Run it with -race: the handler
+// works on copies of the points, so it never races with the moves.
+func TestFindNearbyConcurrentMoves(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+	for i := 0; i < 100; i++ {
+		f := fleets[fleetNames[i%len(fleetNames)]]
+		p := &quadtree.PointOf[DriverData]{X: float64(i%10) - 5, Y: float64(i/10) - 5, Data: DriverData{ID: fmt.Sprintf("d%d", i)}}
+		if err := addDriver(f, p); err != nil {
+			t.Fatalf("addDriver: %v", err)
+		}
+	}
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				id := fmt.Sprintf("d%d", n%100)
+				moveDriver(id, float64(n%10)-4.5, float64(n%7)-3.5, nil)
+				setDriverState(fleets[fleetNames[(n%100)%len(fleetNames)]], id, stateBusy)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=0&lon=0&radius_x=10&radius_y=10&include_distance=true", nil))
+				// --- Test: every search answers (a driver moving meanwhile
+				// may be seen at either position, see quadtree.Update) ---
+				var drivers []DriverResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &drivers); w.Code != http.StatusOK || err != nil || len(drivers) == 0 {
+					t.Errorf("Status %d, %d drivers (%v)", w.Code, len(drivers), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestFindInBBox checks the corners of /find-in-bbox
+func TestFindInBBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	for id, pos := range map[string][2]float64{"in": {5, 5}, "edge": {10, 0}, "out": {11, 5}, "east": {180, 5}} {
+		tree.Insert(&quadtree.PointOf[DriverData]{X: pos[0], Y: pos[1], Data: DriverData{ID: id}})
+	}
+
+	r := gin.New()
+	r.GET("/find-in-bbox", handleFindInBBox)
+	get := func(query string) (int, []DriverResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-in-bbox?"+query, nil))
+		var drivers []DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &drivers)
+		return w.Code, drivers
+	}
+
+	// --- Test 1: a 10x10 box from (0, 0): the East edge is excluded ---
+	if code, drivers := get("min_lon=0&min_lat=0&max_lon=10&max_lat=10"); code != http.StatusOK || len(drivers) != 1 || drivers[0].ID != "in" {
+		t.Errorf("[in] expected, got %d %v", code, drivers)
+	}
+	// ...so the world's East edge needs a box ending past it
+	if _, drivers := get("min_lon=170&min_lat=0&max_lon=180&max_lat=10"); len(drivers) != 0 {
+		t.Errorf("Box ending on lon 180: no driver expected, got %v", drivers)
+	}
+	if _, drivers := get("min_lon=170&min_lat=0&max_lon=180.5&max_lat=10"); len(drivers) != 1 || drivers[0].ID != "east" {
+		t.Errorf("[east] expected, got %v", drivers)
+	}
+
+	// --- Test 2: swapped or missing corners are rejected ---
+	for _, query := range []string{"min_lon=10&min_lat=0&max_lon=0&max_lat=10", "min_lon=0&min_lat=0&max_lon=10", "min_lon=NaN&min_lat=0&max_lon=10&max_lat=10"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("%s: 400 expected, got %d", query, code)
+		}
+	}
+}
+
+// TestNearestGreatCircle checks that /nearest ranks the drivers in km:
+// at 60°N a degree of longitude is only half a degree of latitude
+func TestNearestGreatCircle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 1.5, Y: 60, Data: DriverData{ID: "east"}}) // ~83 km
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 0, Y: 61, Data: DriverData{ID: "north"}})  // ~111 km
+
+	r := gin.New()
+	r.GET("/nearest", handleNearest)
+	r.GET("/find-nearby", handleFindNearby)
+	for _, path := range []string{"/nearest?lat=60&lon=0&k=1", "/find-nearby?lat=60&lon=0&nearest=true"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var drivers []DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &drivers)
+		if len(drivers) != 1 || drivers[0].ID != "east" {
+			t.Errorf("%s: [east] expected, got %v", path, drivers)
+		}
+	}
+}
+
+// TestFindNearbyCircle checks that /find-nearby-circle keeps the drivers
+// within radius_m on the sphere, across the antimeridian and over the poles
+func TestFindNearbyCircle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree := resetFleets(t)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 1.5, Y: 60, Data: DriverData{ID: "east"}})         // ~83 km from (60, 0)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 0, Y: 61, Data: DriverData{ID: "north"}})          // ~111 km from (60, 0)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 1, Y: 60, Data: DriverData{ID: "closer"}})         // ~56 km from (60, 0)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: -179.95, Y: 0, Data: DriverData{ID: "dateline"}})  // ~11 km from (0, 179.95)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 180, Y: 89.9, Data: DriverData{ID: "other-side"}}) // ~22 km from (89.9, 0)
+
+	r := gin.New()
+	r.GET("/find-nearby-circle", handleFindNearbyCircle)
+	get := func(query string) []string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby-circle?"+query, nil))
+		var drivers []DriverResponse
+		json.Unmarshal(w.Body.Bytes(), &drivers)
+		ids := []string{}
+		for _, d := range drivers {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	// --- Test 1: only the drivers within the radius, closest first ---
+	if ids := get("lat=60&lon=0&radius_m=100000"); !reflect.DeepEqual(ids, []string{"closer", "east"}) {
+		t.Errorf("100 km around (60, 0): [closer east] expected, got %v", ids)
+	}
+
+	// --- Test 2: across the antimeridian ---
+	if ids := get("lat=0&lon=179.95&radius_m=20000"); !reflect.DeepEqual(ids, []string{"dateline"}) {
+		t.Errorf("20 km around (0, 179.95): [dateline] expected, got %v", ids)
+	}
+
+	// --- Test 3: over the pole, where a box of radius_m / (111320 * cos(lat))
+	// degrees of longitude (about 128 here) would miss the other side ---
+	if ids := get("lat=89.9&lon=0&radius_m=25000"); !reflect.DeepEqual(ids, []string{"other-side"}) {
+		t.Errorf("25 km around (89.9, 0): [other-side] expected, got %v", ids)
+	}
+}
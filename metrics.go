@@ -0,0 +1,155 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serverMetrics holds the Prometheus collectors of the API server.
+// A nil *serverMetrics means metrics are disabled: every method
+// returns immediately, so the handlers pay (almost) nothing.
+type serverMetrics struct {
+	registry      *prometheus.Registry
+	queries       *prometheus.CounterVec
+	inserts       prometheus.Counter
+	removes       prometheus.Counter
+	moves         prometheus.Counter
+	queryDuration *prometheus.HistogramVec
+	resultSize    prometheus.Histogram
+}
+
+// metrics is created by main once the configuration is loaded,
+// and stays nil when the server runs with -metrics=off (METRICS=off)
+var metrics *serverMetrics
+
+// newServerMetrics creates the collectors on their own registry,
+// together with the default Go runtime and process collectors
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "georunner_queries_total",
+			Help: "Number of searches run on the fleet trees, by fleet (a request may search several fleets).",
+		}, []string{"fleet"}),
+		inserts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "georunner_inserts_total",
+			Help: "Number of drivers inserted into the tree.",
+		}),
+		removes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "georunner_removes_total",
+			Help: "Number of drivers removed from the tree.",
+		}),
+		moves: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "georunner_moves_total",
+			Help: "Number of driver moves and updates applied to the tree.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "georunner_query_duration_seconds",
+			Help:    "Duration of the searches run on the fleet trees, by fleet.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 8), // 10µs .. ~160ms
+		}, []string{"fleet"}),
+		resultSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "georunner_query_result_size",
+			Help:    "Number of drivers returned by a search query.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8), // 1 .. 16384
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.queries, m.inserts, m.removes, m.moves, m.queryDuration, m.resultSize,
+		newTreeCollector(),
+	)
+	return m
+}
+
+// treeCollector reports the state of the fleet trees. The values are read
+// from the trees at every scrape, so they are never stale and the handlers
+// don't have to keep any gauge up to date.
+type treeCollector struct {
+	points *prometheus.Desc
+	depth  *prometheus.Desc
+}
+
+// newTreeCollector describes the tree gauges, by fleet
+func newTreeCollector() *treeCollector {
+	return &treeCollector{
+		points: prometheus.NewDesc("georunner_tree_points_total",
+			"Number of drivers currently in the tree, by fleet.", []string{"fleet"}, nil),
+		depth: prometheus.NewDesc("georunner_tree_depth",
+			"Depth of the tree (0 for a single leaf), by fleet.", []string{"fleet"}, nil),
+	}
+}
+
+// Describe sends the descriptions of the tree gauges
+func (c *treeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.points
+	ch <- c.depth
+}
+
+// Collect sends the current size and depth of every fleet tree
+func (c *treeCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, f := range allFleets() {
+		// The fleets don't exist yet before the server starts
+		if f == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.points, prometheus.GaugeValue, float64(f.Tree.Count()), f.Name)
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(f.Tree.Depth()), f.Name)
+	}
+}
+
+// handler serves the metrics in the Prometheus text format
+func (m *serverMetrics) handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// observeResultSize records how many drivers a search returned
+func (m *serverMetrics) observeResultSize(n int) {
+	if m == nil {
+		return
+	}
+	m.resultSize.Observe(float64(n))
+}
+
+// treeObserver counts what the tree of a fleet does (see
+// quadtree.WithObserver): inserts, removes, moves and searches are
+// counted by the trees themselves, whoever makes them (the API, the
+// simulation, the watchers, the expiry). It reads metrics at every call,
+// so it also follows the metrics set up after the fleets.
+type treeObserver struct {
+	fleet string
+}
+
+// Inserted counts the drivers added to a tree
+func (treeObserver) Inserted(n int) {
+	if metrics != nil {
+		metrics.inserts.Add(float64(n))
+	}
+}
+
+// Removed counts the drivers removed from a tree
+func (treeObserver) Removed(n int) {
+	if metrics != nil {
+		metrics.removes.Add(float64(n))
+	}
+}
+
+// Moved counts a driver moved or updated in its tree
+func (treeObserver) Moved() {
+	if metrics != nil {
+		metrics.moves.Inc()
+	}
+}
+
+// Searched counts and times a search run on the tree
+func (o treeObserver) Searched(_ int, took time.Duration) {
+	if metrics != nil {
+		metrics.queries.WithLabelValues(o.fleet).Inc()
+		metrics.queryDuration.WithLabelValues(o.fleet).Observe(took.Seconds())
+	}
+}
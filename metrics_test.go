@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// TestMetrics runs a search and an insert, then checks that /metrics
+// reports them
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+	metrics = newServerMetrics()
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+	r.GET("/metrics", metrics.handler())
+
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 10, Y: 10, Data: DriverData{ID: "d1"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=10&lon=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/find-nearby: status 200 expected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	for _, want := range []string{
+		`georunner_queries_total{fleet="car"} 1`,
+		`georunner_inserts_total 1`,
+		`georunner_query_result_size_count 1`,
+		`georunner_query_duration_seconds_count{fleet="car"} 1`,
+		`georunner_tree_points_total{fleet="car"} 1`,
+		`georunner_tree_depth{fleet="car"} 0`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics: %q expected in the output", want)
+		}
+	}
+}
+
+// TestMetricsCounters checks the counters and gauges with the Prometheus
+// test helpers, across inserts, removes and queries
+func TestMetricsCounters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+	metrics = newServerMetrics()
+
+	r := gin.New()
+	r.GET("/find-nearby", handleFindNearby)
+
+	// --- Test 1: inserts and removes ---
+	for i, id := range []string{"d1", "d2", "d3"} {
+		if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 10 + float64(i), Y: 10, Data: DriverData{ID: id}}); err != nil {
+			t.Fatalf("addDriver: %v", err)
+		}
+	}
+	removeDriver("d2")
+	if got := testutil.ToFloat64(metrics.inserts); got != 3 {
+		t.Errorf("3 inserts expected, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.removes); got != 1 {
+		t.Errorf("1 remove expected, got %v", got)
+	}
+
+	// --- Test 2: queries, counted and timed by the trees, by fleet ---
+	car := fleets[defaultFleet].Tree
+	car.Query(&quadtree.Boundary{X: 10, Y: 10, Width: 5, Height: 5})
+	car.QueryKNearest(&quadtree.PointOf[DriverData]{X: 10, Y: 10}, 1)
+	if got := testutil.ToFloat64(metrics.queries.WithLabelValues("car")); got != 2 {
+		t.Errorf("2 queries made straight on the tree expected, got %v", got)
+	}
+	if got := histogramCount(t, metrics.queryDuration.WithLabelValues("car")); got != 2 {
+		t.Errorf("2 timed queries expected, got %d", got)
+	}
+	// Each /find-nearby searches every fleet tree
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/find-nearby?lat=10&lon=10", nil))
+	}
+	for _, name := range fleetNames {
+		want := 2.0
+		if name == defaultFleet {
+			want += 2
+		}
+		if got := testutil.ToFloat64(metrics.queries.WithLabelValues(name)); got != want {
+			t.Errorf("%v queries of %s expected, got %v", want, name, got)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.queryDuration, "georunner_query_duration_seconds"); got != len(fleetNames) {
+		t.Errorf("One duration histogram per fleet expected, got %d", got)
+	}
+
+	// --- Test 3: the tree gauges follow the fleets ---
+	expected := `
+# HELP georunner_tree_points_total Number of drivers currently in the tree, by fleet.
+# TYPE georunner_tree_points_total gauge
+georunner_tree_points_total{fleet="bike"} 0
+georunner_tree_points_total{fleet="car"} 2
+georunner_tree_points_total{fleet="truck"} 0
+`
+	if err := testutil.CollectAndCompare(newTreeCollector(), strings.NewReader(expected), "georunner_tree_points_total"); err != nil {
+		t.Errorf("Tree gauges: %v", err)
+	}
+
+	// --- Test 4: the trees count the changes, whoever makes them ---
+	if _, _, err := moveDriver("d1", 11, 11, nil); err != nil {
+		t.Fatalf("moveDriver: %v", err)
+	}
+	setDriverState(fleets[defaultFleet], "d3", stateBusy)
+	if got := testutil.ToFloat64(metrics.moves); got != 2 {
+		t.Errorf("2 moves (a move and a state change) expected, got %v", got)
+	}
+	// A driver removed straight from the tree, not through the API
+	fleets[defaultFleet].Tree.RemoveByID(byID("d3"))
+	if got := testutil.ToFloat64(metrics.removes); got != 2 {
+		t.Errorf("2 removes expected, got %v", got)
+	}
+}
+
+// histogramCount returns the number of observations of a histogram
+func histogramCount(t *testing.T, h prometheus.Observer) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := h.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Reading the histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageSize is the page size of /find-nearby when 'cursor'
+// is given without 'limit'
+const defaultPageSize = 50
+
+// errBadCursor is returned for a cursor that was not produced by the server
+var errBadCursor = errors.New("parameter 'cursor' is not a valid cursor")
+
+// DriverPage is one page of a paginated /find-nearby
+type DriverPage struct {
+	Drivers []DriverResponse `json:"drivers"`
+	// Pass it as 'cursor' to get the next page (absent on the last page)
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// distanceKey is the position of a driver in the distance order of the
+// pages: by distance from the rider, then by ID between equal distances
+type distanceKey struct {
+	Km float64 `json:"km"`
+	ID string  `json:"id"`
+}
+
+// after reports whether d comes after k in the distance order
+func (k distanceKey) after(d DriverResponse) bool {
+	if d.DistanceKm != k.Km {
+		return d.DistanceKm > k.Km
+	}
+	return d.ID > k.ID
+}
+
+// encodeCursor turns the last driver ID of a page into an opaque cursor
+func encodeCursor(lastID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastID))
+}
+
+// decodeCursor returns the last driver ID of the previous page
+func decodeCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errBadCursor
+	}
+	return string(id), nil
+}
+
+// pageByID orders drivers by ID and returns the first 'limit' ones whose
+// ID comes after 'after' (all of them from the start if after is ""),
+// and the cursor of the next page ("" if this is the last one).
+// The ID order does not change when drivers move, so successive pages
+// never overlap. A driver that enters the area during the paging shows up
+// only if its ID is after the cursor.
+func pageByID(drivers []DriverResponse, after string, limit int) ([]DriverResponse, string) {
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].ID < drivers[j].ID })
+
+	start := 0
+	if after != "" {
+		start = sort.Search(len(drivers), func(i int) bool { return drivers[i].ID > after })
+	}
+	page := drivers[start:]
+	if len(page) <= limit {
+		return page, ""
+	}
+	page = page[:limit]
+	return page, encodeCursor(page[len(page)-1].ID)
+}
+
+// encodeDistanceCursor turns the last driver of a distance-ordered page
+// into an opaque cursor. encoding/json writes the shortest float that
+// reads back as the same float64, so the key survives the round trip.
+func encodeDistanceCursor(last DriverResponse) string {
+	key, _ := json.Marshal(distanceKey{Km: last.DistanceKm, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// decodeDistanceCursor returns the key of the last driver of the previous page
+func decodeDistanceCursor(cursor string) (distanceKey, error) {
+	var key distanceKey
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(raw, &key) != nil || key.ID == "" {
+		return distanceKey{}, errBadCursor
+	}
+	return key, nil
+}
+
+// pageByDistance returns the first 'limit' drivers after 'after' (from
+// the start if nil) in the distance order, and the cursor of the next
+// page ("" if this is the last one). The drivers must already be sorted
+// by distance, then ID (see byDistance).
+//
+// The cursor holds the key of the last driver sent, not an offset, so a
+// page starts right after that key whatever the drivers did in between:
+// drivers that enter or leave the area only shift the pages by themselves,
+// and nobody is sent twice or skipped because of someone else. Only a
+// driver that moves across the cursor between two requests, e.g. one
+// 1.2 km away moving to 1.4 km while the cursor is at 1.3 km, is sent
+// again (or, moving the other way, missed). Only the drivers moving
+// around the boundary of the page are affected, by as much as they moved.
+// Pages in the ID order (pageByID) never have this problem, but are not
+// nearest-first.
+func pageByDistance(drivers []DriverResponse, after *distanceKey, limit int) ([]DriverResponse, string) {
+	start := 0
+	if after != nil {
+		start = sort.Search(len(drivers), func(i int) bool { return after.after(drivers[i]) })
+	}
+	page := drivers[start:]
+	if len(page) <= limit {
+		return page, ""
+	}
+	page = page[:limit]
+	return page, encodeDistanceCursor(page[len(page)-1])
+}
+
+// respondDriverPage writes one page as a DriverPage, or as a GeoJSON
+// FeatureCollection with a "nextCursor" member if the client asked for it
+func respondDriverPage(c *gin.Context, drivers []DriverResponse, nextCursor string) {
+	metrics.observeResultSize(len(drivers))
+
+	if !wantsGeoJSON(c) {
+		c.JSON(http.StatusOK, DriverPage{Drivers: drivers, NextCursor: nextCursor})
+		return
+	}
+
+	fc := toFeatureCollection(drivers)
+	fc.NextCursor = nextCursor
+	body, err := json.Marshal(fc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, geoJSONMediaType, body)
+}
@@ -0,0 +1,60 @@
+package quadtree // Batch insertion under a single lock
+
+// BatchInsert adds all the given points to the tree and returns how many
+// were accepted. Unlike calling Insert once per point, it takes the root's
+// Write Lock only once for the whole batch: nobody can query or change the
+// tree in the meantime, so the nodes below are written without locking them.
+// The points are checked like Insert does: the ones with a NaN or infinite
+// coordinate, outside the boundary or (WithIDIndex) with an ID already
+// in the tree are skipped.
+// On an empty tree, BuildQuadTree is faster still.
+func (qt *QuadTreeOf[T]) BatchInsert(points []*PointOf[T]) int {
+	// The ID index lock, if any, comes first (see idIndex)
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+	// The root's own Write Lock, even on a fixed shard root
+	// (where lockForWrite only takes a Read Lock)
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	// Let the inserts already past the root finish (one lock per node)
+	qt.drain()
+
+	inserted := 0
+	for _, p := range points {
+		if !isFinite(p.X) || !isFinite(p.Y) {
+			continue
+		}
+		if qt.ids != nil {
+			if _, ok := qt.ids.get(p.Data); ok {
+				continue
+			}
+		}
+		if !qt.insertUnsafe(p) {
+			continue
+		}
+		if qt.ids != nil {
+			qt.ids.put(p)
+		}
+		inserted++
+	}
+	qt.observeInsert(inserted)
+	return inserted
+}
+
+// insertUnsafe is insert without taking any lock: the caller holds the
+// root's Write Lock and nobody is inside the tree (see BatchInsert)
+func (qt *QuadTreeOf[T]) insertUnsafe(p *PointOf[T]) bool {
+	if !qt.contains(p) {
+		return false
+	}
+	// Walk down to the leaf, counting the point in every parent
+	node := qt
+	for node.northWest != nil {
+		node.size.Add(1)
+		node = node.childFor(p)
+	}
+	node.addToLeaf(p)
+	return true
+}
@@ -0,0 +1,71 @@
+package quadtree // Tests for the batch insertion
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestBatchInsert verifies that BatchInsert gives the same tree as one
+// Insert per point, in every lock mode, and skips what Insert rejects
+func TestBatchInsert(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	rng := rand.New(rand.NewSource(1))
+
+	points := make([]*Point, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		points = append(points, randomWorldPoint(rng, i))
+	}
+	// A point outside the world and one with a NaN coordinate
+	points = append(points, &Point{X: 500, Y: 0, Data: "outside"}, &Point{X: math.NaN(), Y: 0, Data: "NaN"})
+
+	modes := map[string][]Option{
+		"single":   nil,
+		"per-node": {WithNodeLocks()},
+		"sharded":  {WithShards(2)},
+	}
+	for name, opts := range modes {
+		// --- Test 1: same content and shape as the Insert loop ---
+		batched := NewQuadTree(world, 4, opts...)
+		// Half the points are already in the tree: the batch goes on top
+		for _, p := range points[:2500] {
+			batched.Insert(p)
+		}
+		if n := batched.BatchInsert(points[2500:]); n != 2500 {
+			t.Errorf("%s: 2500 points inserted expected, got %d", name, n)
+		}
+		inserted := NewQuadTree(world, 4, opts...)
+		for _, p := range points {
+			inserted.Insert(p)
+		}
+		if batched.Count() != 5000 {
+			t.Errorf("%s: 5000 points expected, %d found", name, batched.Count())
+		}
+		if batched.Stats() != inserted.Stats() {
+			t.Errorf("%s: different shapes:\nbatched  %+v\ninserted %+v", name, batched.Stats(), inserted.Stats())
+		}
+		area := &Boundary{X: 12, Y: 41, Width: 30, Height: 20}
+		if got, expected := batched.CountInRange(area), len(inserted.Query(area)); got != expected {
+			t.Errorf("%s: CountInRange: %d points expected, %d found", name, expected, got)
+		}
+		if got := len(batched.Query(area)); got != batched.CountInRange(area) {
+			t.Errorf("%s: Query found %d points, CountInRange %d", name, got, batched.CountInRange(area))
+		}
+	}
+
+	// --- Test 2: with an ID index, IDs already taken are skipped ---
+	qt := NewQuadTree(world, 4, WithIDIndex())
+	qt.Insert(&Point{X: 1, Y: 1, Data: "a"})
+	n := qt.BatchInsert([]*Point{
+		{X: 2, Y: 2, Data: "a"}, // Already in the tree
+		{X: 3, Y: 3, Data: "b"},
+		{X: 4, Y: 4, Data: "b"}, // Already in the batch
+		{X: 5, Y: 5, Data: "c"},
+	})
+	if n != 2 || qt.Count() != 3 {
+		t.Errorf("2 points inserted (3 in total) expected, got %d (%d)", n, qt.Count())
+	}
+	if p, ok := qt.GetByID("b"); !ok || p.X != 3 {
+		t.Errorf("GetByID(b): the first b expected, got %v, %v", p, ok)
+	}
+}
@@ -0,0 +1,741 @@
+package quadtree // Benchmarks for the QuadTree
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// randomWorldPoint returns a random point anywhere on the world map
+func randomWorldPoint(rng *rand.Rand, data int) *Point {
+	return &Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: data}
+}
+
+// BenchmarkConcurrentInsert compares a single-root tree with a sharded
+// root under many concurrent inserts spread across the whole map.
+// Run with e.g. -cpu=1,4,8 to see the effect of the contention.
+func BenchmarkConcurrentInsert(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	trees := []struct {
+		name string
+		opts []Option
+	}{
+		{"single-root", nil},
+		{"sharded-16", []Option{WithShards(2)}},
+		{"sharded-64", []Option{WithShards(3)}},
+	}
+
+	for _, tc := range trees {
+		b.Run(tc.name, func(b *testing.B) {
+			qt := NewQuadTree(world, 4, tc.opts...)
+			var seed atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(seed.Add(1)))
+				for i := 0; pb.Next(); i++ {
+					qt.Insert(randomWorldPoint(rng, i))
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkQueryFilter compares filtering at the leaf level with
+// filtering the result of Query, when the predicate rejects most points.
+// Run with -benchmem to see the reduced allocations.
+func BenchmarkQueryFilter(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+
+	area := &Boundary{X: 0, Y: 0, Width: 90, Height: 45}         // A quarter of the world
+	keep := func(p *Point) bool { return p.Data.(int)%100 == 0 } // Keeps ~1%
+
+	b.Run("Query+filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			found := []*Point{}
+			for _, p := range qt.Query(area) {
+				if keep(p) {
+					found = append(found, p)
+				}
+			}
+		}
+	})
+
+	b.Run("QueryFilter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			qt.QueryFilter(area, keep)
+		}
+	})
+}
+
+// BenchmarkDepth shows how the depth grows with the number of points
+// at capacity 4 (see the "depth" metric), to help choose a capacity.
+func BenchmarkDepth(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("points-%d", n), func(b *testing.B) {
+			var depth int
+			for i := 0; i < b.N; i++ {
+				qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+				rng := rand.New(rand.NewSource(1))
+				for j := 0; j < n; j++ {
+					qt.Insert(randomWorldPoint(rng, j))
+				}
+				depth = qt.Depth()
+			}
+			b.ReportMetric(float64(depth), "depth")
+		})
+	}
+}
+
+// BenchmarkBuild compares loading 10000 points with BuildQuadTree
+// and with one Insert per point
+func BenchmarkBuild(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		points = append(points, randomWorldPoint(rng, i))
+	}
+
+	b.Run("Insert-loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt := NewQuadTree(world, 4)
+			for _, p := range points {
+				qt.Insert(p)
+			}
+		}
+	})
+
+	b.Run("BuildQuadTree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			BuildQuadTree(world, 4, points)
+		}
+	})
+}
+
+// BenchmarkLockMode compares the lock modes on 10k points under concurrent
+// queries, alone and mixed with inserts (90% Query / 10% Insert).
+// "per-node" is the old default of the unsharded tree (one lock per node),
+// "single" the current one, "sharded" uses WithShards(2).
+// Run with e.g. -cpu=1,4,8.
+func BenchmarkLockMode(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	modes := []struct {
+		name    string
+		newTree func() *QuadTree
+	}{
+		{"per-node", func() *QuadTree {
+			// Turn the root back into a per-node tree before it has any children
+			qt := NewQuadTree(world, 4)
+			qt.singleLock = false
+			return qt
+		}},
+		{"single", func() *QuadTree { return NewQuadTree(world, 4) }},
+		{"sharded", func() *QuadTree { return NewQuadTree(world, 4, WithShards(2)) }},
+	}
+
+	for _, mode := range modes {
+		qt := mode.newTree()
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			qt.Insert(randomWorldPoint(rng, i))
+		}
+		area := &Boundary{X: 12, Y: 41, Width: 10, Height: 10}
+
+		b.Run(mode.name+"/queries", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					qt.Query(area)
+				}
+			})
+		})
+
+		b.Run(mode.name+"/mixed", func(b *testing.B) {
+			var seed atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(seed.Add(1)))
+				for i := 0; pb.Next(); i++ {
+					// One insert every 10 operations
+					if i%10 == 0 {
+						qt.Insert(randomWorldPoint(rng, i))
+					} else {
+						qt.Query(area)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkQueryContext measures the cost of the periodic context checks
+// of QueryContext compared to the plain Query
+func BenchmarkQueryContext(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 90, Height: 45}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.Run("Query", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt.Query(area)
+		}
+	})
+
+	b.Run("QueryContext", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt.QueryContext(ctx, area)
+		}
+	})
+}
+
+// BenchmarkRemoveInArea compares RemoveInArea with a Query followed
+// by one Remove per point
+func BenchmarkRemoveInArea(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	area := &Boundary{X: 0, Y: 0, Width: 90, Height: 45}
+	fill := func() *QuadTree {
+		qt := NewQuadTree(world, 4)
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			qt.Insert(randomWorldPoint(rng, i))
+		}
+		return qt
+	}
+
+	b.Run("QueryThenRemove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			qt := fill()
+			b.StartTimer()
+			for _, p := range qt.Query(area) {
+				qt.Remove(p)
+			}
+		}
+	})
+
+	b.Run("RemoveInArea", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			qt := fill()
+			b.StartTimer()
+			qt.RemoveInArea(area)
+		}
+	})
+}
+
+// BenchmarkNearest compares the branch-and-bound Nearest
+// with a linear scan of every point, on a deep tree
+func BenchmarkNearest(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		p := randomWorldPoint(rng, i)
+		points = append(points, p)
+		qt.Insert(p)
+	}
+	targets := make([]*Point, 1000)
+	for i := range targets {
+		targets[i] = randomWorldPoint(rng, -1)
+	}
+
+	b.Run("Nearest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt.Nearest(targets[i%len(targets)])
+		}
+	})
+
+	b.Run("LinearScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			target := targets[i%len(targets)]
+			bestDist := -1.0
+			for _, p := range points {
+				dx, dy := p.X-target.X, p.Y-target.Y
+				if d := dx*dx + dy*dy; bestDist < 0 || d < bestDist {
+					bestDist = d
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkSimulateMove replays the simulator's pattern (every driver
+// moves by a small step) on 10k points, with and without WithPooling.
+// Run with -benchmem to see the allocations.
+func BenchmarkSimulateMove(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"pooled", []Option{WithPooling()}},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			qt := NewQuadTree(world, 4, mode.opts...)
+			rng := rand.New(rand.NewSource(1))
+			drivers := make([]*Point, 10000)
+			for i := range drivers {
+				drivers[i] = randomWorldPoint(rng, i)
+				qt.Insert(drivers[i])
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				d := i % len(drivers)
+				old := drivers[d]
+				newX := min(max(old.X+(rng.Float64()-0.5)*0.1, -180), 180)
+				newY := min(max(old.Y+(rng.Float64()-0.5)*0.1, -90), 90)
+				if moved := qt.Update(old, newX, newY); moved != nil {
+					drivers[d] = moved
+					// Nobody else holds the old point: give it back
+					qt.ReleasePoint(old)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkQueryInto compares Query with QueryInto reusing one buffer.
+// Run with -benchmem to see the allocations.
+func BenchmarkQueryInto(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: 12, Y: 41, Width: 20, Height: 20}
+
+	b.Run("Query", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			qt.Query(area)
+		}
+	})
+
+	b.Run("QueryInto", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []*Point
+		for i := 0; i < b.N; i++ {
+			buf = qt.QueryInto(area, buf[:0])
+		}
+	})
+}
+
+// BenchmarkDenseLeafQuery runs small queries on a tree with big leaves
+// (capacity 256, 200k points): most of the time goes into scanning the
+// leaves and rejecting the points outside the area, which is where
+// the memory layout of the leaves matters
+func BenchmarkDenseLeafQuery(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 256)
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 200000)
+	for i := range points {
+		points[i] = randomWorldPoint(rng, i)
+	}
+	// Insert in a different order than allocated, like drivers that
+	// joined at different times: the leaves point all over the heap
+	rng.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+	for _, p := range points {
+		qt.Insert(p)
+	}
+	areas := make([]Boundary, 1024)
+	for i := range areas {
+		c := randomWorldPoint(rng, -1)
+		areas[i] = Boundary{X: c.X, Y: c.Y, Width: 0.5, Height: 0.5}
+	}
+	var buf []*Point
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buf = qt.QueryInto(&areas[i%len(areas)], buf[:0])
+	}
+}
+
+// BenchmarkQueryMulti compares one Query per tile with a single QueryMulti
+// over the 16 tiles of a map viewport (4x4 tiles of 2.5 degrees)
+func BenchmarkQueryMulti(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	tiles := make([]*Boundary, 0, 16)
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			tiles = append(tiles, &Boundary{X: 8.75 + float64(col)*2.5, Y: 37.75 + float64(row)*2.5, Width: 1.25, Height: 1.25})
+		}
+	}
+
+	b.Run("QueryPerTile", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, tile := range tiles {
+				qt.Query(tile)
+			}
+		}
+	})
+
+	b.Run("QueryMulti", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt.QueryMulti(tiles)
+		}
+	})
+}
+
+// BenchmarkSnapshotQuery compares parallel queries on the live tree, while
+// a writer keeps moving points, with the same queries on a Snapshot.
+// TakeSnapshot measures the price of a view (a copy of 10k points).
+func BenchmarkSnapshotQuery(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithShards(2))
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 10000)
+	for i := range points {
+		points[i] = randomWorldPoint(rng, i)
+		qt.Insert(points[i])
+	}
+	area := &Boundary{X: 12, Y: 41, Width: 10, Height: 10}
+
+	// The writer runs for the whole benchmark
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wrng := rand.New(rand.NewSource(2))
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			j := i % len(points)
+			if moved := qt.Update(points[j], wrng.Float64()*360-180, wrng.Float64()*180-90); moved != nil {
+				points[j] = moved
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	b.Run("LockedTree", func(b *testing.B) {
+		b.SetParallelism(8)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				qt.Query(area)
+			}
+		})
+	})
+
+	b.Run("Snapshot", func(b *testing.B) {
+		view := qt.Snapshot()
+		b.ResetTimer()
+		b.SetParallelism(8)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				view.Query(area)
+			}
+		})
+	})
+
+	b.Run("TakeSnapshot", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			qt.Snapshot()
+		}
+	})
+}
+
+// BenchmarkSnapshotWriterStall measures how long a Snapshot holds up the
+// writers, at 10k, 100k and 1M points, with a single lock and with node
+// locks. ns/op is the time of one Snapshot; max-stall-ns is the longest
+// a concurrent Update had to wait meanwhile (about the same: the writers
+// are stopped for the whole copy).
+func BenchmarkSnapshotWriterStall(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"single", nil},
+		{"node-locks", []Option{WithNodeLocks()}},
+	}
+
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		rng := rand.New(rand.NewSource(1))
+		points := make([]*Point, n)
+		for i := range points {
+			points[i] = randomWorldPoint(rng, i)
+		}
+
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("%s/%d", mode.name, n), func(b *testing.B) {
+				qt := BuildQuadTree(world, 4, append([]*Point(nil), points...), mode.opts...)
+				moving := qt.Query(&world)[:1000]
+
+				// The writer times every Update until the Snapshots are done
+				stop := make(chan struct{})
+				maxStall := make(chan time.Duration)
+				go func() {
+					var worst time.Duration
+					wrng := rand.New(rand.NewSource(2))
+					for i := 0; ; i++ {
+						select {
+						case <-stop:
+							maxStall <- worst
+							return
+						default:
+						}
+						j := i % len(moving)
+						start := time.Now()
+						if moved := qt.Update(moving[j], wrng.Float64()*360-180, wrng.Float64()*180-90); moved != nil {
+							moving[j] = moved
+						}
+						worst = max(worst, time.Since(start))
+					}
+				}()
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					qt.Snapshot()
+				}
+				b.StopTimer()
+				close(stop)
+				b.ReportMetric(float64(<-maxStall), "max-stall-ns")
+			})
+		}
+	}
+}
+
+// BenchmarkCountGrid compares CountGrid with querying the area and
+// bucketing the points on the caller's side (a 32x16 heatmap of Europe
+// over 100k points)
+func BenchmarkCountGrid(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: 15, Y: 50, Width: 25, Height: 15}
+
+	b.Run("QueryAndBucket", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g := newGrid(area, 32, 16)
+			for _, p := range qt.Query(area) {
+				g.counts[g.row(p.Y)][g.col(p.X)]++
+			}
+		}
+	})
+
+	b.Run("CountGrid", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			qt.CountGrid(area, 32, 16)
+		}
+	})
+}
+
+// BenchmarkHandOverHand compares the lock modes under 1000 concurrent
+// goroutines, each inserting a point and removing it again, on a tree
+// already holding 10k points. "nested" is the single lock held all the
+// way down, "hand-over-hand" uses WithNodeLocks, "sharded" adds
+// WithShards(2) on top. Run with e.g. -cpu=1,4,8.
+func BenchmarkHandOverHand(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"nested", nil},
+		{"hand-over-hand", []Option{WithNodeLocks()}},
+		{"sharded", []Option{WithShards(2)}},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			qt := NewQuadTree(world, 4, mode.opts...)
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 10000; i++ {
+				qt.Insert(randomWorldPoint(rng, i))
+			}
+			var seed atomic.Int64
+
+			b.ResetTimer()
+			// RunParallel starts parallelism * GOMAXPROCS goroutines
+			b.SetParallelism(max(1, 1000/runtime.GOMAXPROCS(0)))
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(seed.Add(1)))
+				for i := 0; pb.Next(); i++ {
+					p := randomWorldPoint(rng, -i)
+					qt.Insert(p)
+					qt.Remove(p)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkReadFree compares the queries of a tree with one lock per node
+// taking the Read Lock of every node ("nested", as before) with the
+// lock-free crossing of the parents ("read-free"), alone and mixed with
+// inserts (90% Query / 10% Insert). Run with -cpu=8 for GOMAXPROCS=8.
+func BenchmarkReadFree(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	area := &Boundary{X: 12, Y: 41, Width: 10, Height: 10}
+	modes := []struct {
+		name  string
+		query func(qt *QuadTree) []*Point
+	}{
+		{"nested", func(qt *QuadTree) []*Point {
+			found := []*Point{}
+			qt.queryNested(area, nil, &found)
+			return found
+		}},
+		{"read-free", func(qt *QuadTree) []*Point { return qt.Query(area) }},
+	}
+
+	for _, mode := range modes {
+		qt := NewQuadTree(world, 4, WithNodeLocks())
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			qt.Insert(randomWorldPoint(rng, i))
+		}
+
+		b.Run(mode.name+"/queries", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					mode.query(qt)
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+		})
+
+		b.Run(mode.name+"/mixed", func(b *testing.B) {
+			var seed atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(seed.Add(1)))
+				for i := 0; pb.Next(); i++ {
+					// One insert every 10 operations
+					if i%10 == 0 {
+						qt.Insert(randomWorldPoint(rng, i))
+					} else {
+						mode.query(qt)
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+		})
+	}
+}
+
+// BenchmarkCountInRange compares CountInRange with len(Query) for a zone
+// holding about half of 100k points, not aligned with the nodes.
+// Run with -benchmem to see the allocations saved.
+func BenchmarkCountInRange(b *testing.B) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: -85, Y: 5, Width: 95, Height: 85} // 49.8% of the map
+
+	b.Run("QueryLen", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = len(qt.Query(area))
+		}
+	})
+
+	b.Run("CountInRange", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			qt.CountInRange(area)
+		}
+	})
+}
+
+// BenchmarkBatchInsert compares adding 10000 points with BatchInsert
+// (one root lock for the batch) and with one Insert per point
+func BenchmarkBatchInsert(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		points = append(points, randomWorldPoint(rng, i))
+	}
+
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"single", nil},
+		{"per-node", []Option{WithNodeLocks()}},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name+"/Insert-loop", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				qt := NewQuadTree(world, 4, mode.opts...)
+				for _, p := range points {
+					qt.Insert(p)
+				}
+			}
+		})
+		b.Run(mode.name+"/BatchInsert", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				qt := NewQuadTree(world, 4, mode.opts...)
+				qt.BatchInsert(points)
+			}
+		})
+	}
+}
+
+// BenchmarkQueryPairs compares QueryPairs with comparing every pair
+// of the points in the area, on 20k points
+func BenchmarkQueryPairs(b *testing.B) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 8)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 90, Height: 45} // A quarter of the map
+
+	b.Run("Naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			points := qt.Query(area)
+			n := 0
+			for j := range points {
+				for k := j + 1; k < len(points); k++ {
+					dx, dy := points[j].X-points[k].X, points[j].Y-points[k].Y
+					if dx*dx+dy*dy <= 0.25 {
+						n++
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("QueryPairs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qt.QueryPairs(area, 0.5)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package quadtree // Selectivity helpers: sizing a query box from a target count
+
+import (
+	"math" // Import math package (Abs, Max)
+)
+
+// boxSearchSteps is the number of bisection steps used by BoxForCount.
+// Each step halves the uncertainty on the box size.
+const boxSearchSteps = 40
+
+// BoxForCount returns the smallest square box centered on 'center'
+// that contains at least n points, together with the number of points
+// it actually contains. A Query with the returned box is then guaranteed
+// not to come back empty (as long as the tree doesn't change in between).
+// If the tree holds fewer than n points, the box covers the whole tree
+// and the count is the total.
+// It only uses the per-node counts (CountInRange): no result is materialized.
+func (qt *QuadTreeOf[T]) BoxForCount(center *PointOf[T], n int) (Boundary, int) {
+	box := Boundary{X: center.X, Y: center.Y}
+
+	// Nothing to look for
+	if n <= 0 {
+		return box, 0
+	}
+
+	// --- Upper bound ---
+	// A box twice as far as the farthest edge of the tree is
+	// guaranteed to cover it entirely (max edges included)
+	b := qt.boundary
+	far := math.Max(
+		math.Max(math.Abs(center.X-(b.X-b.Width)), math.Abs(center.X-(b.X+b.Width))),
+		math.Max(math.Abs(center.Y-(b.Y-b.Height)), math.Abs(center.Y-(b.Y+b.Height))),
+	)
+	hi := 2 * far
+	box.Width, box.Height = hi, hi
+	hiCount := qt.CountInRange(&box)
+
+	// Not enough points in the whole tree: the whole tree is the answer
+	if hiCount < n {
+		return box, hiCount
+	}
+
+	// --- Bisection ---
+	// Shrink the box while it still contains at least n points
+	lo := 0.0
+	for i := 0; i < boxSearchSteps; i++ {
+		mid := (lo + hi) / 2
+		box.Width, box.Height = mid, mid
+		if count := qt.CountInRange(&box); count >= n {
+			hi, hiCount = mid, count
+		} else {
+			lo = mid
+		}
+	}
+
+	box.Width, box.Height = hi, hi
+	return box, hiCount
+}
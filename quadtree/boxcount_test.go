@@ -0,0 +1,54 @@
+package quadtree // Tests for the selectivity helpers
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreeBoxForCount verifies that the box returned by BoxForCount
+// really contains at least N points, both in a dense and a sparse region.
+func TestQuadTreeBoxForCount(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(3))
+
+	// A dense "city" of 500 points around (10, 45)...
+	for i := 0; i < 500; i++ {
+		qt.Insert(&Point{X: 10 + rng.Float64(), Y: 45 + rng.Float64(), Data: i})
+	}
+	// ...and 50 points scattered over the rest of the world
+	for i := 500; i < 550; i++ {
+		qt.Insert(&Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: i})
+	}
+
+	centers := map[string]*Point{
+		"dense":  {X: 10.5, Y: 45.5},
+		"sparse": {X: -120, Y: -60},
+	}
+
+	for name, center := range centers {
+		box, count := qt.BoxForCount(center, 20)
+
+		// --- Test 1: The reported count is the real one ---
+		actual := len(qt.Query(&box))
+		if actual != count {
+			t.Errorf("%s: BoxForCount reported %d points, Query found %d", name, count, actual)
+		}
+		// --- Test 2: The box contains at least N points ---
+		if actual < 20 {
+			t.Errorf("%s: at least 20 points expected in the box, found %d", name, actual)
+		}
+	}
+
+	// --- Test 3: The dense box is much smaller than the sparse one ---
+	dense, _ := qt.BoxForCount(centers["dense"], 20)
+	sparse, _ := qt.BoxForCount(centers["sparse"], 20)
+	if dense.Width >= sparse.Width {
+		t.Errorf("Dense box (%v) should be smaller than the sparse box (%v)", dense.Width, sparse.Width)
+	}
+
+	// --- Test 4: Asking for more points than exist returns the total ---
+	box, count := qt.BoxForCount(centers["sparse"], 10000)
+	if count != 550 || qt.CountInRange(&box) != 550 {
+		t.Errorf("BoxForCount beyond the total: 550 expected, got %d", count)
+	}
+}
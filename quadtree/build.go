@@ -0,0 +1,63 @@
+package quadtree // Bulk construction of a QuadTree
+
+// BuildQuadTree builds a tree holding all the given points in one pass,
+// top-down: every node splits its points among its four children at once,
+// instead of inserting them one by one (one lock and one descent per point).
+// The result has the same shape as inserting the points one by one, and
+// the points outside the boundary are skipped, exactly like Insert does.
+// With WithIDIndex, a point whose Data is already in the tree is skipped too.
+func BuildQuadTree[T comparable](boundary Boundary, capacity int, points []*PointOf[T], opts ...Option) *QuadTreeOf[T] {
+	qt := NewQuadTreeOf[T](boundary, capacity, opts...)
+
+	// Keep only the points that Insert would accept
+	accepted := make([]*PointOf[T], 0, len(points))
+	for _, p := range points {
+		if !qt.contains(p) {
+			continue
+		}
+		if qt.ids != nil {
+			if _, ok := qt.ids.get(p.Data); ok {
+				continue
+			}
+			qt.ids.put(p)
+		}
+		accepted = append(accepted, p)
+	}
+
+	// Nobody else can see the tree yet: no locks are needed
+	qt.build(accepted)
+	return qt
+}
+
+// build stores 'points' (all contained by this node) in this subtree
+func (qt *QuadTreeOf[T]) build(points []*PointOf[T]) {
+	qt.size.Store(int64(len(points)))
+
+	// Few enough points (or maximum depth): this node is a leaf.
+	// The fixed shard levels are already subdivided and never leaves.
+	if qt.northWest == nil && (len(points) <= qt.capacity || qt.depth >= qt.maxDepth) {
+		qt.setPoints(points)
+		return
+	}
+
+	// Otherwise split the points among the four children, trying them
+	// in the same order as Insert does
+	if qt.northWest == nil {
+		qt.subdivide()
+		qt.publishChildren()
+	}
+	children := [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast}
+	var parts [4][]*PointOf[T]
+	for _, p := range points {
+		for i, child := range children {
+			if child.contains(p) {
+				parts[i] = append(parts[i], p)
+				break
+			}
+		}
+	}
+
+	for i, child := range children {
+		child.build(parts[i])
+	}
+}
@@ -0,0 +1,55 @@
+package quadtree // Tests for the bulk construction
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestBuildQuadTree verifies that a bulk-built tree is the same
+// as one built by inserting the points one by one
+func TestBuildQuadTree(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	rng := rand.New(rand.NewSource(1))
+
+	points := make([]*Point, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		points = append(points, randomWorldPoint(rng, i))
+	}
+	// Points on the world's edges, and one outside the world
+	points = append(points, &Point{X: 180, Y: 90, Data: "corner"}, &Point{X: -180, Y: 0, Data: "West edge"})
+	points = append(points, &Point{X: 500, Y: 0, Data: "outside"})
+
+	built := BuildQuadTree(world, 4, points)
+	inserted := NewQuadTree(world, 4)
+	for _, p := range points {
+		inserted.Insert(p)
+	}
+
+	// --- Test 1: same content, same shape ---
+	if built.Count() != 5002 {
+		t.Errorf("5002 points expected, %d found", built.Count())
+	}
+	if built.Stats() != inserted.Stats() {
+		t.Errorf("Different shapes:\nbuilt    %+v\ninserted %+v", built.Stats(), inserted.Stats())
+	}
+	area := &Boundary{X: 12, Y: 41, Width: 30, Height: 20}
+	if len(built.Query(area)) != len(inserted.Query(area)) {
+		t.Errorf("Query: %d points expected, %d found", len(inserted.Query(area)), len(built.Query(area)))
+	}
+
+	// --- Test 2: the built tree keeps working ---
+	if !built.Remove(points[0]) || !built.Insert(points[0]) || built.Count() != 5002 {
+		t.Errorf("Remove/Insert on a built tree failed")
+	}
+
+	// --- Test 3: options are honored ---
+	sharded := BuildQuadTree(world, 4, points[:10], WithShards(2))
+	if sharded.shardLevels() != 2 || sharded.Count() != 10 {
+		t.Errorf("Sharded build: 2 levels and 10 points expected, got %d and %d", sharded.shardLevels(), sharded.Count())
+	}
+	dup := []*PointOf[string]{{X: 1, Y: 1, Data: "a"}, {X: 2, Y: 2, Data: "a"}, {X: 3, Y: 3, Data: "b"}}
+	indexed := BuildQuadTree(world, 4, dup, WithIDIndex())
+	if p, ok := indexed.GetByID("a"); !ok || p != dup[0] || indexed.Count() != 2 {
+		t.Errorf("With an ID index the first of the duplicate IDs should win")
+	}
+}
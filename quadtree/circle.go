@@ -0,0 +1,66 @@
+package quadtree // Circular-region queries on the QuadTree
+
+// QueryCircle finds the points within 'radius' of (centerX, centerY).
+// Distances are flat Euclidean distances in degree space: points in the
+// corners of the circle's bounding box are excluded, unlike with Query.
+// Points exactly at 'radius' are included.
+// With a DistanceFunc (WithDistance), 'radius' is in the units it returns.
+func (qt *QuadTreeOf[T]) QueryCircle(centerX, centerY, radius float64) []*PointOf[T] {
+	return qt.QueryCircleFunc(centerX, centerY, radius, nil)
+}
+
+// QueryCircleFunc is QueryCircle measuring the distances with fn (nil: the
+// tree's DistanceFunc). 'radius' is in the units fn returns, e.g. km for
+// HaversineDistance (and a squared distance for SquaredEuclidean).
+func (qt *QuadTreeOf[T]) QueryCircleFunc(centerX, centerY, radius float64, fn DistanceFunc) []*PointOf[T] {
+	began := qt.searchStart()
+	found := []*PointOf[T]{}
+
+	// A negative radius can't contain anything
+	if radius < 0 {
+		return found
+	}
+
+	// The default distance is squared: compare with the squared radius
+	// (no square root needed)
+	fn = qt.distanceFunc(fn)
+	if fn == nil {
+		radius *= radius
+	}
+	// Expired points (see InsertWithTTL) are left out
+	qt.queryCircleRecursive(centerX, centerY, radius, fn, qt.expiry.live(nil), &found)
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// queryCircleRecursive is the internal helper that performs the recursive search.
+// 'radius' is squared with the default distance (nil fn). If keep is not
+// nil, only the points it accepts are appended to 'found'.
+func (qt *QuadTreeOf[T]) queryCircleRecursive(x, y, radius float64, fn DistanceFunc, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// --- Rectangle-circle intersection ---
+	// If the closest spot of this node is farther than the radius,
+	// the circle doesn't touch this node: prune the whole branch
+	if qt.minDist(x, y, fn) > radius {
+		return
+	}
+
+	// If this is a "leaf" node, filter every point by its distance
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			if qt.pointDist(i, x, y, fn) <= radius && (keep == nil || keep(p)) {
+				*found = append(*found, p)
+			}
+		}
+		return
+	}
+
+	// If this is a "parent" node, search the four children
+	qt.northWest.queryCircleRecursive(x, y, radius, fn, keep, found)
+	qt.northEast.queryCircleRecursive(x, y, radius, fn, keep, found)
+	qt.southWest.queryCircleRecursive(x, y, radius, fn, keep, found)
+	qt.southEast.queryCircleRecursive(x, y, radius, fn, keep, found)
+}
@@ -0,0 +1,38 @@
+package quadtree // Tests for the circular-region queries
+
+import "testing"
+
+// TestQuadTreeQueryCircle verifies that QueryCircle keeps the points
+// inside the circle and drops the corners of its bounding box.
+func TestQuadTreeQueryCircle(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	qt.Insert(&Point{X: 10, Y: 10, Data: "center"})
+	qt.Insert(&Point{X: 19, Y: 10, Data: "east"})       // Distance 9
+	qt.Insert(&Point{X: 10, Y: 0, Data: "on edge"})     // Distance exactly 10
+	qt.Insert(&Point{X: 17.2, Y: 17.2, Data: "corner"}) // Distance ~10.18: inside the box, outside the circle
+	qt.Insert(&Point{X: -50, Y: -50, Data: "far"})
+
+	found := qt.QueryCircle(10, 10, 10)
+
+	// --- Test 1: Only the 3 points inside the circle are returned ---
+	if len(found) != 3 {
+		t.Fatalf("QueryCircle: 3 points expected, %d found", len(found))
+	}
+	for _, p := range found {
+		if p.Data == "corner" || p.Data == "far" {
+			t.Errorf("Point %v is outside the circle but was returned", p.Data)
+		}
+	}
+
+	// --- Test 2: The corner point is inside the bounding box ---
+	// (so the plain rectangular Query would have returned it)
+	if box := qt.Query(&Boundary{X: 10, Y: 10, Width: 10, Height: 10}); len(box) != 4 {
+		t.Errorf("Bounding box query: 4 points expected (center, east, on edge, corner), %d found", len(box))
+	}
+
+	// --- Test 3: A negative radius finds nothing ---
+	if found := qt.QueryCircle(10, 10, -1); len(found) != 0 {
+		t.Errorf("QueryCircle with negative radius: 0 points expected, %d found", len(found))
+	}
+}
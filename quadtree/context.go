@@ -0,0 +1,113 @@
+package quadtree // Cancellable queries on the QuadTree
+
+import (
+	"context" // Import context package (cancellation)
+)
+
+// ctxCheckEvery is how many nodes are visited between two checks of the context:
+// checking at every node would slow down the normal queries for nothing
+const ctxCheckEvery = 64
+
+// QueryContext is like Query, but gives up as soon as ctx is cancelled
+// (e.g. the HTTP client went away), returning nil and ctx.Err().
+// The context is checked every ctxCheckEvery visited nodes.
+func (qt *QuadTreeOf[T]) QueryContext(ctx context.Context, rangeRect *Boundary) ([]*PointOf[T], error) {
+	began := qt.searchStart()
+	// Don't even start if the context is already done
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := []*PointOf[T]{}
+	visited := 0
+	collect := func(p *PointOf[T]) { found = append(found, p) }
+	if err := qt.queryContextRecursive(ctx, rangeRect, &visited, qt.expiry.live(nil), collect); err != nil {
+		return nil, err
+	}
+	qt.observeSearch(began, len(found))
+	return found, nil
+}
+
+// QueryWrappedContext is QueryWrapped with the cancellation of QueryContext
+func (qt *QuadTreeOf[T]) QueryWrappedContext(ctx context.Context, rangeRect *Boundary) ([]*PointOf[T], error) {
+	began := qt.searchStart()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := []*PointOf[T]{}
+	visited := 0
+	collect := func(p *PointOf[T]) { found = append(found, p) }
+	live := qt.expiry.live(nil)
+	for _, part := range qt.wrapX(rangeRect) {
+		if err := qt.queryContextRecursive(ctx, &part, &visited, live, collect); err != nil {
+			return nil, err
+		}
+	}
+	qt.observeSearch(began, len(found))
+	return found, nil
+}
+
+// QueryWrappedCopyContext is QueryWrappedCopy with the cancellation of
+// QueryContext: detached copies of the points in a box that may cross the
+// antimeridian, or nil and ctx.Err() if ctx is cancelled on the way
+func (qt *QuadTreeOf[T]) QueryWrappedCopyContext(ctx context.Context, rangeRect *Boundary) ([]PointOf[T], error) {
+	began := qt.searchStart()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := []PointOf[T]{}
+	visited := 0
+	// The copies are taken under the Read Lock of each leaf, like QueryCopy
+	collect := func(p *PointOf[T]) { found = append(found, *p) }
+	live := qt.expiry.live(nil)
+	for _, part := range qt.wrapX(rangeRect) {
+		if err := qt.queryContextRecursive(ctx, &part, &visited, live, collect); err != nil {
+			return nil, err
+		}
+	}
+	qt.observeSearch(began, len(found))
+	return found, nil
+}
+
+// queryContextRecursive is queryRecursive with a periodic check of ctx.
+// 'visited' counts the nodes visited so far by the whole query, and
+// 'collect' is called for every point found and accepted by keep (nil:
+// all of them, see expiryIndex.live), under the leaf's Read Lock.
+func (qt *QuadTreeOf[T]) queryContextRecursive(ctx context.Context, rangeRect *Boundary, visited *int, keep func(*PointOf[T]) bool, collect func(*PointOf[T])) error {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// Every ctxCheckEvery nodes, stop if the caller gave up
+	*visited++
+	if *visited%ctxCheckEvery == 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Prune the branches outside the area
+	if !qt.intersects(rangeRect) {
+		return nil
+	}
+
+	// If this is a "leaf" node, collect the points inside the area
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			if rangeRect.ContainsXY(qt.xy[2*i], qt.xy[2*i+1]) && (keep == nil || keep(p)) {
+				collect(p)
+			}
+		}
+		return nil
+	}
+
+	// If this is a "parent" node, search the four children
+	for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+		if err := child.queryContextRecursive(ctx, rangeRect, visited, keep, collect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
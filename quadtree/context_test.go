@@ -0,0 +1,55 @@
+package quadtree // Tests for the cancellable queries
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreeQueryContext verifies that QueryContext finds the same
+// points as Query, and stops when the context is cancelled
+func TestQuadTreeQueryContext(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		qt.Insert(randomWorldPoint(rng, i))
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+
+	// --- Test 1: same result as Query ---
+	found, err := qt.QueryContext(context.Background(), area)
+	if err != nil || len(found) != len(qt.Query(area)) {
+		t.Errorf("QueryContext: %d points expected, got %d (err %v)", len(qt.Query(area)), len(found), err)
+	}
+
+	// --- Test 2: an already cancelled context ---
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if found, err := qt.QueryContext(ctx, area); !errors.Is(err, context.Canceled) || found != nil {
+		t.Errorf("Cancelled context: context.Canceled expected, got %v (%d points)", err, len(found))
+	}
+
+	// --- Test 3: cancelled in the middle of the descent ---
+	// The context reports the cancellation at its 2nd check only,
+	// i.e. after the search already visited some nodes
+	ctx = &countdownContext{Context: context.Background(), checks: 1}
+	if _, err := qt.QueryWrappedContext(ctx, area); !errors.Is(err, context.Canceled) {
+		t.Errorf("Cancelled during the search: context.Canceled expected, got %v", err)
+	}
+}
+
+// countdownContext is a context that becomes cancelled
+// after its Err method has been called 'checks' times
+type countdownContext struct {
+	context.Context
+	checks int
+}
+
+func (c *countdownContext) Err() error {
+	if c.checks <= 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
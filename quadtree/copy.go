@@ -0,0 +1,36 @@
+package quadtree // Queries returning detached copies of the points
+
+// QueryCopy is like Query, but returns copies of the points instead of
+// pointers into the tree. The copies are taken under the Read Lock of
+// each leaf and share nothing with the tree: the caller can keep them,
+// or change them, while other goroutines update the tree.
+//
+// The price is one copy per result (X, Y and Data). A copy can still be
+// passed back to Remove or Update: they find the stored point by value
+// (X, Y and Data). Query stays the fast path for the internal callers.
+func (qt *QuadTreeOf[T]) QueryCopy(rangeRect *Boundary) []PointOf[T] {
+	began := qt.searchStart()
+	found := []PointOf[T]{}
+	qt.visitRange(rangeRect, func(p *PointOf[T]) bool {
+		found = append(found, *p)
+		return true
+	})
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// QueryWrappedCopy is like QueryWrapped (the box may cross the
+// antimeridian), but returns detached copies like QueryCopy.
+// See QueryWrappedCopyContext for the cancellable variant.
+func (qt *QuadTreeOf[T]) QueryWrappedCopy(rangeRect *Boundary) []PointOf[T] {
+	began := qt.searchStart()
+	found := []PointOf[T]{}
+	for _, part := range qt.wrapX(rangeRect) {
+		qt.visitRange(&part, func(p *PointOf[T]) bool {
+			found = append(found, *p)
+			return true
+		})
+	}
+	qt.observeSearch(began, len(found))
+	return found
+}
@@ -0,0 +1,106 @@
+package quadtree // Tests for the queries returning copies
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestQuadTreeQueryCopy checks that the copies are detached from the tree
+func TestQuadTreeQueryCopy(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+	qt.Insert(&PointOf[string]{X: 10, Y: 10, Data: "a"})
+	qt.Insert(&PointOf[string]{X: 11, Y: 11, Data: "b"})
+	qt.Insert(&PointOf[string]{X: 179.5, Y: 0, Data: "east"})
+	area := &Boundary{X: 10, Y: 10, Width: 5, Height: 5}
+
+	// --- Test 1: same content as Query ---
+	copies := qt.QueryCopy(area)
+	if len(copies) != 2 {
+		t.Fatalf("QueryCopy: 2 points expected, got %d", len(copies))
+	}
+
+	// --- Test 2: changing a copy doesn't touch the tree ---
+	copies[0].X, copies[0].Data = 100, "changed"
+	for _, p := range qt.Query(area) {
+		if p.X == 100 || p.Data == "changed" {
+			t.Errorf("Changing a copy changed the stored point %v", p.Data)
+		}
+	}
+
+	// --- Test 3: the wrapped variant crosses the antimeridian ---
+	if found := qt.QueryWrappedCopy(&Boundary{X: -179.5, Y: 0, Width: 1, Height: 1}); len(found) != 1 || found[0].Data != "east" {
+		t.Errorf("QueryWrappedCopy across the antimeridian: [east] expected, got %v", found)
+	}
+
+	// --- Test 4: Update and Remove accept a copy (they match by value) ---
+	copies = qt.QueryCopy(area)
+	if moved := qt.Update(&copies[0], 12, 12); moved == nil || moved.X != 12 {
+		t.Fatalf("Update of a copy: moved to 12 expected, got %v", moved)
+	}
+	if !qt.Remove(&copies[1]) || qt.Count() != 2 {
+		t.Errorf("Remove of a copy: 2 points left expected, got %d", qt.Count())
+	}
+}
+
+// TestQuadTreeQueryWrappedCopyContext checks the cancellable copies
+func TestQuadTreeQueryWrappedCopyContext(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+	qt.Insert(&PointOf[string]{X: 179.5, Y: 0, Data: "east"})
+	qt.Insert(&PointOf[string]{X: -179.5, Y: 0, Data: "west"})
+	qt.Insert(&PointOf[string]{X: 0, Y: 0, Data: "center"})
+	box := &Boundary{X: 180, Y: 0, Width: 1, Height: 1}
+
+	// --- Test 1: the copies of both sides of the antimeridian ---
+	found, err := qt.QueryWrappedCopyContext(context.Background(), box)
+	if err != nil || len(found) != 2 {
+		t.Fatalf("QueryWrappedCopyContext: east and west expected, got %v (%v)", found, err)
+	}
+	found[0].Data = "changed"
+	if len(qt.Query(&Boundary{X: 0, Y: 0, Width: 180, Height: 90})) != 3 || qt.QueryWrappedCopy(box)[0].Data == "changed" {
+		t.Error("Changing a copy changed the tree")
+	}
+
+	// --- Test 2: a cancelled context stops the query ---
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if found, err := qt.QueryWrappedCopyContext(ctx, box); err != context.Canceled || found != nil {
+		t.Errorf("Cancelled context: nil, context.Canceled expected, got %v, %v", found, err)
+	}
+}
+
+// TestQuadTreeQueryCopyConcurrent keeps and changes the copies while other
+// goroutines move the points (run with -race)
+func TestQuadTreeQueryCopyConcurrent(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithIDIndex())
+	for i := 0; i < 200; i++ {
+		qt.Insert(&PointOf[int]{X: float64(i%20) - 10, Y: float64(i/20) - 5, Data: i})
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 20, Height: 20}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				qt.MoveByID(i, float64(i%20)-9.5, float64(i/20)-4.5)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				// The copies are ours: writing them is not a race
+				kept := qt.QueryCopy(area)
+				for j := range kept {
+					kept[j].X++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if qt.Count() != 200 {
+		t.Errorf("200 points expected after the concurrent moves, got %d", qt.Count())
+	}
+}
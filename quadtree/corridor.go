@@ -0,0 +1,70 @@
+package quadtree // Corridor (buffered polyline) queries on the QuadTree
+
+import (
+	"math" // Import math package (Inf, Nextafter)
+)
+
+// QueryCorridor finds the points within widthDeg of the polyline 'path'
+// (e.g. the drivers close to a route). Distances are flat Euclidean
+// distances in degree space, like QueryCircle, and points exactly at
+// widthDeg are included.
+// The bounding box of the path, expanded by widthDeg, is the coarse filter;
+// then every candidate is checked against each segment of the path.
+// Degenerate inputs don't panic: a single-point path is a circle query,
+// a zero width only finds the points lying exactly on the path,
+// and an empty path or a negative width find nothing.
+func (qt *QuadTreeOf[T]) QueryCorridor(path []PointOf[T], widthDeg float64) []*PointOf[T] {
+	began := qt.searchStart()
+	found := []*PointOf[T]{}
+	if len(path) == 0 || widthDeg < 0 {
+		return found
+	}
+
+	// --- Coarse filter: the bounding box of the path, expanded by the width ---
+	minX, minY := path[0].X, path[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range path[1:] {
+		minX, maxX = min(minX, v.X), max(maxX, v.X)
+		minY, maxY = min(minY, v.Y), max(maxY, v.Y)
+	}
+	// The box is exclusive on its max edges: nudge them so the points at
+	// exactly widthDeg (or on the path, with a zero width) are not lost
+	box := fromMinMax(minX-widthDeg, minY-widthDeg,
+		math.Nextafter(maxX+widthDeg, math.Inf(1)), math.Nextafter(maxY+widthDeg, math.Inf(1)))
+
+	// --- Fine filter: distance to the nearest segment, at the leaf level ---
+	widthSq := widthDeg * widthDeg
+	qt.queryRecursive(&box, func(p *PointOf[T]) bool {
+		// A single-point path has no segments: compare with the point itself
+		if len(path) == 1 {
+			return segmentDistSq(p.X, p.Y, &path[0], &path[0]) <= widthSq
+		}
+		for i := 1; i < len(path); i++ {
+			if segmentDistSq(p.X, p.Y, &path[i-1], &path[i]) <= widthSq {
+				return true
+			}
+		}
+		return false
+	}, &found)
+	qt.observeSearch(began, len(found))
+
+	return found
+}
+
+// segmentDistSq returns the squared distance from (x, y) to the segment a-b
+func segmentDistSq[T comparable](x, y float64, a, b *PointOf[T]) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+
+	// Position of the projection of (x, y) on the segment's line:
+	// 0 at a, 1 at b, clamped to stay on the segment.
+	// A zero-length segment is just the point a.
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = ((x-a.X)*dx + (y-a.Y)*dy) / lengthSq
+		t = max(0, min(1, t))
+	}
+
+	// Distance to the closest point of the segment
+	cx, cy := a.X+t*dx-x, a.Y+t*dy-y
+	return cx*cx + cy*cy
+}
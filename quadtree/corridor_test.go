@@ -0,0 +1,58 @@
+package quadtree // Tests for the corridor queries
+
+import "testing"
+
+// TestQuadTreeQueryCorridor verifies the corridor around an L-shaped route
+// and the degenerate inputs
+func TestQuadTreeQueryCorridor(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// Route: East along Y=0 from (0,0) to (40,0), then North to (40,40)
+	route := []PointOf[string]{{X: 0, Y: 0}, {X: 40, Y: 0}, {X: 40, Y: 40}}
+
+	points := map[string][2]float64{
+		"on the route":      {20, 0},
+		"near 1st segment":  {20, 4},
+		"near 2nd segment":  {44, 30},
+		"at the width":      {20, -5}, // Exactly 5 away: included
+		"near the corner":   {43, -3}, // ~4.24 from the corner
+		"inside the L":      {20, 20}, // In the bounding box, far from the route
+		"beyond the start":  {-6, 0},  // 6 away from the first vertex
+		"outside the box":   {80, 80},
+		"on the route, end": {40, 40},
+	}
+	for data, xy := range points {
+		qt.Insert(&PointOf[string]{X: xy[0], Y: xy[1], Data: data})
+	}
+
+	check := func(name string, found []*PointOf[string], expected ...string) {
+		t.Helper()
+		got := map[string]bool{}
+		for _, p := range found {
+			got[p.Data] = true
+		}
+		if len(got) != len(expected) {
+			t.Errorf("%s: %d points expected, %d found (%v)", name, len(expected), len(got), got)
+		}
+		for _, data := range expected {
+			if !got[data] {
+				t.Errorf("%s: %q not found", name, data)
+			}
+		}
+	}
+
+	// --- Test 1: a corridor 5 degrees wide ---
+	check("width 5", qt.QueryCorridor(route, 5),
+		"on the route", "near 1st segment", "near 2nd segment", "at the width",
+		"near the corner", "on the route, end")
+
+	// --- Test 2: a zero width only finds the points on the route ---
+	check("width 0", qt.QueryCorridor(route, 0), "on the route", "on the route, end")
+
+	// --- Test 3: a single-point path is a circle query ---
+	check("single point", qt.QueryCorridor(route[:1], 6), "beyond the start")
+
+	// --- Test 4: nothing to search ---
+	check("empty path", qt.QueryCorridor(nil, 5))
+	check("negative width", qt.QueryCorridor(route, -1))
+}
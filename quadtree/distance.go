@@ -0,0 +1,91 @@
+package quadtree // Configurable distance metric for the distance-based searches
+
+import (
+	"math" // Import math package (Atan, Tan, Cos)
+)
+
+// DistanceFunc returns the distance between (ax, ay) and (bx, by).
+// The distance-based searches (NearestNeighbor, Nearest, QueryKNearest,
+// QueryCircle, QuerySorted) use it to rank and filter the points: set it
+// for the whole tree WithDistance, or for one call with the ...Func
+// variants. Without either, they use SquaredEuclidean.
+//
+// Besides the points, the searches measure the distance to the closest
+// spot of a node's rectangle to skip the nodes that are too far. They take
+// the smallest distance to a few spots of the node: the target clamped
+// into it, which is the closest spot for any distance that grows with
+// |ax-bx| and with |ay-by| (Euclidean, squared Euclidean, Manhattan,
+// Chebyshev), and the closest spots of its two meridian edges on the
+// sphere, which is where a great-circle distance like HaversineDistance
+// reaches a node the target is not above or below. Both are exact, also
+// across the antimeridian: a target at lon -179.95 is 11 km from a node
+// ending at lon 180, not 360 degrees away.
+type DistanceFunc func(ax, ay, bx, by float64) float64
+
+// SquaredEuclidean is the default DistanceFunc: the squared Euclidean
+// distance (in degree space for lat/lon). It ranks the points like the
+// Euclidean distance, without the square root.
+func SquaredEuclidean(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	return dx*dx + dy*dy
+}
+
+// WithDistance makes the distance-based searches of the tree use fn
+// instead of SquaredEuclidean (a nil fn keeps the default)
+func WithDistance(fn DistanceFunc) Option {
+	return func(o *options) {
+		o.distance = fn
+	}
+}
+
+// distanceFunc picks the distance of a search: fn if given, otherwise the
+// one of the tree. nil means SquaredEuclidean, computed inline (no call).
+func (qt *QuadTreeOf[T]) distanceFunc(fn DistanceFunc) DistanceFunc {
+	if fn != nil {
+		return fn
+	}
+	return qt.distance
+}
+
+// minDist returns the distance from (x, y) to the closest spot of this
+// node, using fn (nil: squared Euclidean, see minDistSq).
+// See DistanceFunc for the spots it measures.
+func (qt *QuadTreeOf[T]) minDist(x, y float64, fn DistanceFunc) float64 {
+	if fn == nil {
+		return qt.minDistSq(x, y)
+	}
+	// The closest spot for a planar distance is (x, y) clamped into the node
+	d := fn(x, y, min(max(x, qt.minX), qt.maxX), min(max(y, qt.minY), qt.maxY))
+
+	// On the sphere, the closest spot may be on either meridian edge (the far
+	// one across the antimeridian): the foot of the perpendicular from (x, y),
+	// or a corner if the foot is outside the edge
+	for _, edge := range [2]float64{qt.minX, qt.maxX} {
+		d = min(d,
+			fn(x, y, edge, min(max(meridianFoot(x, y, edge), qt.minY), qt.maxY)),
+			fn(x, y, edge, qt.minY),
+			fn(x, y, edge, qt.maxY))
+	}
+	return d
+}
+
+// meridianFoot returns the latitude of the point of the meridian at
+// longitude lon closest (great-circle) to (x, y). More than 90° of
+// longitude away it is the farthest point instead: the closest one is
+// then a pole, so a corner of the edge, which minDist measures too.
+func meridianFoot(x, y, lon float64) float64 {
+	rad := math.Pi / 180
+	return math.Atan(math.Tan(y*rad)/math.Cos((x-lon)*rad)) / rad
+}
+
+// pointDist returns the distance from (x, y) to the i-th point of this
+// leaf, using fn (nil: squared Euclidean)
+func (qt *QuadTreeOf[T]) pointDist(i int, x, y float64, fn DistanceFunc) float64 {
+	// The coordinates come from the contiguous 'xy' (see leaf.go)
+	px, py := qt.xy[2*i], qt.xy[2*i+1]
+	if fn == nil {
+		dx, dy := px-x, py-y
+		return dx*dx + dy*dy
+	}
+	return fn(x, y, px, py)
+}
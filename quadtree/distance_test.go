@@ -0,0 +1,105 @@
+package quadtree // Tests for the configurable distance metric
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// manhattan is a DistanceFunc for the tests: |dx| + |dy|
+func manhattan(ax, ay, bx, by float64) float64 {
+	return math.Abs(bx-ax) + math.Abs(by-ay)
+}
+
+// TestQuadTreeDistanceFunc verifies that the distance-based searches
+// follow the DistanceFunc of the tree or of the call
+func TestQuadTreeDistanceFunc(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 4, WithDistance(manhattan))
+	plain := NewQuadTree(world, 4)
+	rng := rand.New(rand.NewSource(3))
+	var points []*Point
+	for i := 0; i < 2000; i++ {
+		p := randomWorldPoint(rng, i)
+		points = append(points, p)
+		qt.Insert(p)
+		plain.Insert(p)
+	}
+	center := &Point{X: 12, Y: 41}
+
+	// --- Test 1: QueryKNearest ranks by the tree's distance (brute force) ---
+	sorted := append([]*Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return manhattan(center.X, center.Y, sorted[i].X, sorted[i].Y) < manhattan(center.X, center.Y, sorted[j].X, sorted[j].Y)
+	})
+	found := qt.QueryKNearest(center, 10)
+	for i, p := range found {
+		if p != sorted[i] {
+			t.Fatalf("QueryKNearest #%d: %v expected, got %v", i, sorted[i].Data, p.Data)
+		}
+	}
+
+	// --- Test 2: Nearest returns the distance of the DistanceFunc ---
+	p, dist, ok := qt.Nearest(center)
+	if !ok || p != sorted[0] || dist != manhattan(center.X, center.Y, p.X, p.Y) {
+		t.Errorf("Nearest: %v at %v expected, got %v at %v", sorted[0].Data, manhattan(center.X, center.Y, sorted[0].X, sorted[0].Y), p.Data, dist)
+	}
+
+	// --- Test 3: QueryCircle is a diamond, its radius in the same units ---
+	want := 0
+	for _, p := range points {
+		if manhattan(center.X, center.Y, p.X, p.Y) <= 20 {
+			want++
+		}
+	}
+	if got := len(qt.QueryCircle(center.X, center.Y, 20)); got != want {
+		t.Errorf("QueryCircle: %d points expected, got %d", want, got)
+	}
+
+	// --- Test 4: a per-call DistanceFunc overrides the tree's ---
+	if got := len(plain.QueryCircleFunc(center.X, center.Y, 20, manhattan)); got != want {
+		t.Errorf("QueryCircleFunc: %d points expected, got %d", want, got)
+	}
+	if got := plain.QueryKNearestFunc(center, 10, manhattan); got[9] != sorted[9] {
+		t.Errorf("QueryKNearestFunc: the 10th point differs")
+	}
+
+	// --- Test 5: SquaredEuclidean is the default ---
+	a, b := plain.QueryKNearest(center, 10), plain.QueryKNearestFunc(center, 10, SquaredEuclidean)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("QueryKNearest #%d: the default and SquaredEuclidean differ", i)
+		}
+	}
+	// ...but Nearest still returns the real (not squared) Euclidean distance
+	p, dist, _ = plain.Nearest(center)
+	if want := math.Hypot(p.X-center.X, p.Y-center.Y); dist != want {
+		t.Errorf("Nearest: distance %v expected, got %v", want, dist)
+	}
+}
+
+// TestHaversineDistance verifies that HaversineDistance ranks by km:
+// at 60°N a degree of longitude is half a degree of latitude
+func TestHaversineDistance(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 1)
+	east := &Point{X: 1.5, Y: 60, Data: "east"} // 1.5° away, ~83 km
+	north := &Point{X: 0, Y: 61, Data: "north"} // 1° away, ~111 km
+	qt.Insert(east)
+	qt.Insert(north)
+	center := &Point{X: 0, Y: 60}
+
+	// --- Test 1: in degrees north is closer, in km east is ---
+	if p := qt.NearestNeighbor(center); p != north {
+		t.Errorf("NearestNeighbor: north expected, got %v", p.Data)
+	}
+	p, km, _ := qt.NearestFunc(center, HaversineDistance)
+	if p != east || math.Abs(km-HaversineKm(60, 0, 60, 1.5)) > 1e-9 {
+		t.Errorf("NearestFunc(HaversineDistance): east at %.1f km expected, got %v at %.1f km", HaversineKm(60, 0, 60, 1.5), p.Data, km)
+	}
+
+	// --- Test 2: a radius in km ---
+	if found := qt.QueryCircleFunc(0, 60, 100, HaversineDistance); len(found) != 1 || found[0] != east {
+		t.Errorf("QueryCircleFunc(100 km): [east] expected, got %d points", len(found))
+	}
+}
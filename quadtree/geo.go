@@ -0,0 +1,252 @@
+package quadtree // Geographic helpers built on top of the QuadTree
+
+import (
+	"errors" // Import errors package (New)
+	"fmt"    // Import formatting package (Errorf)
+	"math"   // Import math package (Abs, Cos, Atan2, Asin, IsNaN, IsInf)
+)
+
+// metersPerDegree is the length of one degree of latitude
+// (and of longitude at the Equator) in meters
+const metersPerDegree = 111320.0
+
+// ErrInvalidCoordinate is returned (wrapped) by ValidatePoint for a point
+// that is not a valid longitude/latitude pair
+var ErrInvalidCoordinate = errors.New("quadtree: invalid coordinate")
+
+// ValidatePoint checks that p holds a real geographic position:
+// X a longitude in [-180, 180] and Y a latitude in [-90, 90], both finite.
+// InsertE only rejects NaN/Inf (ErrNonFinite) and points outside its own
+// boundary (ErrOutOfBounds): call ValidatePoint first to tell a bad
+// coordinate apart from a point outside the tree. The error wraps
+// ErrInvalidCoordinate.
+func ValidatePoint[T comparable](p *PointOf[T]) error {
+	// NaN fails every comparison, so check it explicitly
+	if math.IsNaN(p.X) || math.IsNaN(p.Y) {
+		return fmt.Errorf("%w: NaN in (lon %v, lat %v)", ErrInvalidCoordinate, p.X, p.Y)
+	}
+	if p.X < -180 || p.X > 180 {
+		return fmt.Errorf("%w: longitude %v outside [-180, 180]", ErrInvalidCoordinate, p.X)
+	}
+	if p.Y < -90 || p.Y > 90 {
+		return fmt.Errorf("%w: latitude %v outside [-90, 90]", ErrInvalidCoordinate, p.Y)
+	}
+	return nil
+}
+
+// earthRadiusKm is the mean radius of the Earth in km
+const earthRadiusKm = 6371.0
+
+// HaversineKm returns the great-circle distance in km between
+// (lat1, lon1) and (lat2, lon2), given in degrees.
+// Unlike the flat degree distances of the queries, it is correct at
+// every latitude and across the antimeridian.
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const toRad = math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// BearingDegrees returns the initial bearing (the heading to take) from
+// (lat1, lon1) to (lat2, lon2), in degrees clockwise from North in [0, 360).
+// Like HaversineKm, it follows the great circle, across the antimeridian too.
+// It returns 0 for two equal positions.
+func BearingDegrees(lat1, lon1, lat2, lon2 float64) float64 {
+	const toRad = math.Pi / 180
+	dLon := (lon2 - lon1) * toRad
+	y := math.Sin(dLon) * math.Cos(lat2*toRad)
+	x := math.Cos(lat1*toRad)*math.Sin(lat2*toRad) -
+		math.Sin(lat1*toRad)*math.Cos(lat2*toRad)*math.Cos(dLon)
+	bearing := math.Atan2(y, x) / toRad
+	// Atan2 is in (-180, 180]: bring the western half to [180, 360)
+	if bearing < 0 {
+		bearing += 360
+	}
+	return bearing
+}
+
+// HaversineDistance is a DistanceFunc for points holding a longitude in X
+// and a latitude in Y: the great-circle distance in km, as HaversineKm.
+// See DistanceFunc for how the searches use it.
+func HaversineDistance(ax, ay, bx, by float64) float64 {
+	return HaversineKm(ay, ax, by, bx)
+}
+
+// BoundaryFromRadiusMeters returns the smallest Boundary containing every
+// point within radiusMeters (great-circle distance, as in HaversineKm)
+// of (centerLat, centerLon). X/Width are longitudes, Y/Height latitudes.
+// The half-width grows towards the poles, where a degree of longitude
+// shrinks. When the circle contains a pole, every longitude is inside it
+// and the half-width is 180. The box may cross the antimeridian or
+// the poles: search it with QueryWrapped.
+func BoundaryFromRadiusMeters(centerLat, centerLon, radiusMeters float64) Boundary {
+	// The radius as an angle at the center of the Earth
+	angle := radiusMeters / 1000 / earthRadiusKm
+	const toDeg = 180 / math.Pi
+
+	// The widest point of the circle is not at centerLat but a bit
+	// closer to the pole: its half-width is asin(sin(angle) / cos(lat))
+	halfWidth := 180.0
+	if s := math.Sin(angle) / math.Cos(centerLat/toDeg); angle < math.Pi/2 && s < 1 {
+		halfWidth = math.Asin(s) * toDeg
+	}
+
+	return Boundary{
+		X:      centerLon,
+		Y:      centerLat,
+		Width:  halfWidth,
+		Height: angle * toDeg,
+	}
+}
+
+// BoundaryFromRadiusKm is BoundaryFromRadiusMeters with the radius in km,
+// e.g. BoundaryFromRadiusKm(lat, lon, 3) for "3 km around this point".
+// The longitude half-width is scaled for the latitude, and becomes 180
+// (every longitude) near the poles, where a degree of longitude vanishes.
+func BoundaryFromRadiusKm(lat, lon, radiusKm float64) Boundary {
+	return BoundaryFromRadiusMeters(lat, lon, radiusKm*1000)
+}
+
+// metersPerDegreeLon returns the length of one degree of longitude
+// in meters at the given latitude (it shrinks towards the poles)
+func metersPerDegreeLon(lat float64) float64 {
+	return metersPerDegree * math.Cos(lat*math.Pi/180)
+}
+
+// QueryManhattan finds the points reachable from center within a
+// Manhattan (|dx| + |dy|) distance of maxMeters.
+// This approximates travel on a grid city, where you move along streets.
+func (qt *QuadTreeOf[T]) QueryManhattan(center *PointOf[T], maxMeters float64) []*PointOf[T] {
+	found := []*PointOf[T]{}
+
+	// A negative distance can't reach anything
+	if maxMeters < 0 {
+		return found
+	}
+
+	// Convert the distance into degrees on each axis
+	lonScale := metersPerDegreeLon(center.Y)
+	halfHeight := maxMeters / metersPerDegree
+	// Near the poles a degree of longitude is (almost) 0 meters long,
+	// so the diamond would be infinitely wide: clamp it to the whole world
+	halfWidth := 180.0
+	if lonScale > 1e-9 {
+		halfWidth = math.Min(maxMeters/lonScale, 180)
+	}
+
+	// --- Coarse filter ---
+	// The "diamond" of a Manhattan distance is contained in this box,
+	// so we can reuse the standard Query to prune the tree
+	box := &Boundary{X: center.X, Y: center.Y, Width: halfWidth, Height: halfHeight}
+	candidates := qt.Query(box)
+
+	// --- Fine filter ---
+	// The corners of the box are outside the diamond: keep only
+	// the points whose per-axis distances sum up to maxMeters or less
+	for _, p := range candidates {
+		dx := math.Abs(p.X-center.X) * lonScale
+		dy := math.Abs(p.Y-center.Y) * metersPerDegree
+		if dx+dy <= maxMeters {
+			found = append(found, p)
+		}
+	}
+
+	return found
+}
+
+// QueryWrapped is like Query, but treats the tree's X axis as circular
+// (e.g. longitude on a -180..180 world): a box that crosses the West or
+// East edge continues on the other side (the antimeridian).
+// The box is split into non-overlapping sub-queries whose results are unioned.
+func (qt *QuadTreeOf[T]) QueryWrapped(rangeRect *Boundary) []*PointOf[T] {
+	began := qt.searchStart()
+	found := []*PointOf[T]{}
+	for _, part := range qt.wrapX(rangeRect) {
+		qt.queryRecursive(&part, nil, &found)
+	}
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// QueryWrappedLimit is QueryWrapped with the early termination of QueryLimit:
+// it stops as soon as 'max' points have been collected (max <= 0 means unlimited).
+func (qt *QuadTreeOf[T]) QueryWrappedLimit(rangeRect *Boundary, max int) []*PointOf[T] {
+	began := qt.searchStart()
+	if max <= 0 {
+		return qt.QueryWrapped(rangeRect)
+	}
+
+	found := make([]*PointOf[T], 0, max)
+	for _, part := range qt.wrapX(rangeRect) {
+		complete := qt.visitRange(&part, func(p *PointOf[T]) bool {
+			found = append(found, p)
+			return len(found) < max
+		})
+		if !complete {
+			break
+		}
+	}
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// wrapX splits rangeRect into the (at most 3) boxes covering it
+// once the X axis is wrapped around the tree's boundary
+func (qt *QuadTreeOf[T]) wrapX(rangeRect *Boundary) []Boundary {
+	return SplitWrapped(qt.boundary, *rangeRect)
+}
+
+// SplitWrapped splits rangeRect into the (at most 3) non-overlapping boxes
+// that QueryWrapped searches in a tree covering world: the parts beyond
+// the West or East edge continue on the other side. A point of the world
+// is in the wrapped box when one of the parts contains it (ContainsXY),
+// e.g. to check single points against the area of a QueryWrapped.
+func SplitWrapped(world, rangeRect Boundary) []Boundary {
+	worldMin := world.X - world.Width
+	worldMax := world.X + world.Width
+	span := worldMax - worldMin
+
+	qMin := rangeRect.X - rangeRect.Width
+	qMax := rangeRect.X + rangeRect.Width
+
+	// The box is wider than the world: it covers every longitude.
+	// Extend it a bit on the East so the closed max edge is included too.
+	if qMax-qMin >= span {
+		return []Boundary{fromMinMax(worldMin, rangeRect.Y-rangeRect.Height, worldMax+span, rangeRect.Y+rangeRect.Height)}
+	}
+
+	// Whenever a box reaches past the East edge, its max is pushed further
+	// out (nothing is stored there) so points exactly on the closed edge match
+	minY, maxY := rangeRect.Y-rangeRect.Height, rangeRect.Y+rangeRect.Height
+
+	// The part inside the world is queried as is...
+	mainMax := qMax
+	if qMax > worldMax {
+		mainMax = worldMax + span
+	}
+	parts := []Boundary{fromMinMax(max(qMin, worldMin), minY, mainMax, maxY)}
+
+	// ...the part beyond the West edge wraps to the East side...
+	if qMin < worldMin {
+		parts = append(parts, fromMinMax(qMin+span, minY, worldMax+span, maxY))
+	}
+	// ...and the part beyond the East edge wraps to the West side
+	if qMax > worldMax {
+		parts = append(parts, fromMinMax(worldMin, minY, qMax-span, maxY))
+	}
+
+	return parts
+}
+
+// fromMinMax builds a Boundary from its min/max corners
+func fromMinMax(minX, minY, maxX, maxY float64) Boundary {
+	return Boundary{
+		X:      (minX + maxX) / 2,
+		Y:      (minY + maxY) / 2,
+		Width:  (maxX - minX) / 2,
+		Height: (maxY - minY) / 2,
+	}
+}
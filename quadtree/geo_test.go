@@ -0,0 +1,238 @@
+package quadtree // Tests for the geographic helpers
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestQuadTreeQueryManhattan verifies that the Manhattan query
+// keeps the points inside the "diamond" and drops the box corners.
+func TestQuadTreeQueryManhattan(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+
+	// At the Equator 1 degree is ~111 km on both axes.
+	// We search within 2 degrees (Manhattan) of the origin.
+	center := &Point{X: 0, Y: 0}
+	maxMeters := 2 * metersPerDegree
+
+	qt.Insert(&Point{X: 1.5, Y: 0, Data: "east"})          // dx=1.5, dy=0   -> inside
+	qt.Insert(&Point{X: 0, Y: -1.9, Data: "south"})        // dx=0,   dy=1.9 -> inside
+	qt.Insert(&Point{X: 0.9, Y: 0.9, Data: "diagonal"})    // 0.9+0.9=1.8    -> inside
+	qt.Insert(&Point{X: 1.5, Y: 1.5, Data: "corner-ne"})   // 1.5+1.5=3.0    -> outside
+	qt.Insert(&Point{X: -1.9, Y: -1.9, Data: "corner-sw"}) // 1.9+1.9=3.8   -> outside
+	qt.Insert(&Point{X: 50, Y: 50, Data: "far"})           // Outside the bounding box
+
+	found := qt.QueryManhattan(center, maxMeters)
+
+	// Only the 3 points inside the diamond must be returned
+	if len(found) != 3 {
+		t.Fatalf("QueryManhattan: 3 points expected, %d found", len(found))
+	}
+	for _, p := range found {
+		// The corner points are inside the bounding box but outside the diamond
+		if p.Data == "corner-ne" || p.Data == "corner-sw" {
+			t.Errorf("Point %v is outside the Manhattan distance but was returned", p.Data)
+		}
+	}
+
+	// A negative distance must find nothing
+	if found := qt.QueryManhattan(center, -1); len(found) != 0 {
+		t.Errorf("QueryManhattan with negative distance: 0 points expected, %d found", len(found))
+	}
+}
+
+// TestQuadTreeQueryWrapped verifies that a query box crossing the
+// antimeridian finds the drivers on the other side of it.
+func TestQuadTreeQueryWrapped(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+
+	qt.Insert(&Point{X: 179.5, Y: 0, Data: "east of the date line"})
+	qt.Insert(&Point{X: -179.9, Y: 0, Data: "west of the date line"})
+	qt.Insert(&Point{X: 180, Y: 0, Data: "on the date line"})
+	qt.Insert(&Point{X: 0, Y: 0, Data: "greenwich"})
+
+	// --- Test 1: A box centered at -179.5 reaches 179.5 ---
+	west := &Boundary{X: -179.5, Y: 0, Width: 10, Height: 10}
+	if found := qt.Query(west); len(found) != 1 {
+		t.Fatalf("Plain Query: only the west driver expected, %d found", len(found))
+	}
+	if found := qt.QueryWrapped(west); len(found) != 3 {
+		t.Errorf("QueryWrapped west: 3 points expected, %d found", len(found))
+	}
+
+	// --- Test 2: The same from the East side ---
+	east := &Boundary{X: 179.5, Y: 0, Width: 10, Height: 10}
+	if found := qt.QueryWrapped(east); len(found) != 3 {
+		t.Errorf("QueryWrapped east: 3 points expected, %d found", len(found))
+	}
+
+	// --- Test 3: A box that doesn't cross the date line is a plain Query ---
+	if found := qt.QueryWrapped(&Boundary{X: 0, Y: 0, Width: 10, Height: 10}); len(found) != 1 {
+		t.Errorf("QueryWrapped at Greenwich: 1 point expected, %d found", len(found))
+	}
+
+	// --- Test 4: A box wider than the world returns every point once ---
+	if found := qt.QueryWrapped(&Boundary{X: 100, Y: 0, Width: 200, Height: 10}); len(found) != 4 {
+		t.Errorf("QueryWrapped wider than the world: 4 points expected, %d found", len(found))
+	}
+
+	// --- Test 5: SplitWrapped gives the parts QueryWrapped searches ---
+	parts := SplitWrapped(qt.boundary, *west)
+	inside := func(x, y float64) bool {
+		for _, part := range parts {
+			if part.ContainsXY(x, y) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) != 2 || !inside(179.5, 0) || !inside(180, 0) || !inside(-179.9, 0) || inside(0, 0) {
+		t.Errorf("SplitWrapped west: 2 parts holding the date line drivers expected, got %v", parts)
+	}
+}
+
+// TestHaversineKm checks the great-circle distance on known city pairs
+func TestHaversineKm(t *testing.T) {
+	// --- Test 1: New York - London is ~5570 km ---
+	if d := HaversineKm(40.7128, -74.0060, 51.5074, -0.1278); math.Abs(d-5570) > 10 {
+		t.Errorf("New York - London: ~5570 km expected, got %.1f", d)
+	}
+
+	// --- Test 2: symmetric, and 0 for the same point ---
+	if a, b := HaversineKm(10, 20, -30, 40), HaversineKm(-30, 40, 10, 20); math.Abs(a-b) > 1e-9 {
+		t.Errorf("HaversineKm must be symmetric: %f != %f", a, b)
+	}
+	if d := HaversineKm(45, 45, 45, 45); d != 0 {
+		t.Errorf("Same point: 0 km expected, got %f", d)
+	}
+
+	// --- Test 3: across the antimeridian the short way round is taken ---
+	if d := HaversineKm(0, 179.5, 0, -179.5); math.Abs(d-111.2) > 0.5 {
+		t.Errorf("Across the antimeridian: ~111 km expected, got %.1f", d)
+	}
+}
+
+// TestBearingDegrees checks the headings of a few known routes
+func TestBearingDegrees(t *testing.T) {
+	cases := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		expected               float64
+	}{
+		{"north", 0, 0, 10, 0, 0},
+		{"east", 0, 0, 0, 10, 90},
+		{"south", 10, 0, 0, 0, 180},
+		{"west", 0, 10, 0, 0, 270},
+		// The short way round goes east across the antimeridian
+		{"east across the antimeridian", 0, 179.5, 0, -179.5, 90},
+		// The great circle from New York to London starts towards North-East
+		{"New York - London", 40.7128, -74.0060, 51.5074, -0.1278, 51.2},
+	}
+	for _, c := range cases {
+		// --- Test: each heading, within a tenth of a degree ---
+		if b := BearingDegrees(c.lat1, c.lon1, c.lat2, c.lon2); math.Abs(b-c.expected) > 0.1 {
+			t.Errorf("%s: %.1f° expected, got %.2f°", c.name, c.expected, b)
+		}
+	}
+}
+
+// TestBoundaryFromRadiusMeters checks the box of a 10 km radius
+// at several latitudes
+func TestBoundaryFromRadiusMeters(t *testing.T) {
+	// --- Test 1: the half-width grows with the latitude, the half-height doesn't ---
+	cases := []struct {
+		lat, width, height float64
+	}{
+		{0, 0.089932, 0.089932},
+		{45, 0.127183, 0.089932},
+		{80, 0.517906, 0.089932},
+	}
+	for _, c := range cases {
+		b := BoundaryFromRadiusMeters(c.lat, 12, 10000)
+		if b.X != 12 || b.Y != c.lat {
+			t.Errorf("lat %v: box centered on (12, %v) expected, got (%v, %v)", c.lat, c.lat, b.X, b.Y)
+		}
+		if math.Abs(b.Width-c.width) > 1e-6 || math.Abs(b.Height-c.height) > 1e-6 {
+			t.Errorf("lat %v: Width %v, Height %v expected, got %v, %v", c.lat, c.width, c.height, b.Width, b.Height)
+		}
+	}
+
+	// --- Test 2: the box really contains the circle ---
+	// Points exactly east and north of the center, just inside the radius
+	b := BoundaryFromRadiusMeters(60, 0, 50000)
+	for lon := 0.0; HaversineKm(60, 0, 60, lon) < 50; lon += 0.001 {
+		if lon > b.Width {
+			t.Fatalf("Point at lon %v is within 50 km but outside the box", lon)
+		}
+	}
+
+	// --- Test 3: a circle around the pole covers every longitude ---
+	if b := BoundaryFromRadiusMeters(89.9, 0, 50000); b.Width != 180 {
+		t.Errorf("Circle around the pole: Width 180 expected, got %v", b.Width)
+	}
+}
+
+// TestValidatePoint checks the accepted and rejected coordinates
+func TestValidatePoint(t *testing.T) {
+	// --- Test 1: valid positions, edges included ---
+	for _, p := range []*Point{{X: 0, Y: 0}, {X: 180, Y: 90}, {X: -180, Y: -90}, {X: 12.5, Y: 41.9}} {
+		if err := ValidatePoint(p); err != nil {
+			t.Errorf("ValidatePoint(%v, %v): no error expected, got %v", p.X, p.Y, err)
+		}
+	}
+
+	// --- Test 2: invalid positions wrap ErrInvalidCoordinate ---
+	for _, p := range []*Point{{X: 500, Y: 0}, {X: 0, Y: 200}, {X: -180.1, Y: 0}, {X: math.NaN(), Y: 0}, {X: 0, Y: math.Inf(1)}} {
+		if err := ValidatePoint(p); !errors.Is(err, ErrInvalidCoordinate) {
+			t.Errorf("ValidatePoint(%v, %v): ErrInvalidCoordinate expected, got %v", p.X, p.Y, err)
+		}
+	}
+}
+
+// TestBoundaryFromRadiusKm compares the box with points of the circle
+// computed on the sphere, at the equator, at 60°N and at 89°N
+func TestBoundaryFromRadiusKm(t *testing.T) {
+	const toRad, toDeg = math.Pi / 180, 180 / math.Pi
+
+	// destination is the point radiusKm away from (lat, lon) along bearing
+	destination := func(lat, lon, radiusKm, bearing float64) (float64, float64) {
+		d := radiusKm / earthRadiusKm
+		lat1, lon1, b := lat*toRad, lon*toRad, bearing*toRad
+		lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+		lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+		return lat2 * toDeg, lon2 * toDeg
+	}
+
+	// --- Test 1: the box holds the whole circle, and no more ---
+	for _, lat := range []float64{0, 60, 89} {
+		const radiusKm = 3.0
+		b := BoundaryFromRadiusKm(lat, 10, radiusKm)
+		maxDLat, maxDLon := 0.0, 0.0
+		for bearing := 0.0; bearing < 360; bearing += 0.05 {
+			pLat, pLon := destination(lat, 10, radiusKm, bearing)
+			if d := HaversineKm(lat, 10, pLat, pLon); math.Abs(d-radiusKm) > 1e-9 {
+				t.Fatalf("lat %v: the ground truth point is %v km away, not %v", lat, d, radiusKm)
+			}
+			maxDLat = math.Max(maxDLat, math.Abs(pLat-lat))
+			maxDLon = math.Max(maxDLon, math.Abs(pLon-10))
+		}
+		// Inside the box, with a tight fit (within 1e-6 degrees)
+		if maxDLat > b.Height+1e-12 || maxDLon > b.Width+1e-12 {
+			t.Errorf("lat %v: circle reaches (%v, %v), box is only (%v, %v)", lat, maxDLon, maxDLat, b.Width, b.Height)
+		}
+		if b.Height-maxDLat > 1e-6 || b.Width-maxDLon > 1e-6 {
+			t.Errorf("lat %v: box (%v, %v) is larger than the circle (%v, %v)", lat, b.Width, b.Height, maxDLon, maxDLat)
+		}
+	}
+
+	// --- Test 2: same box as BoundaryFromRadiusMeters ---
+	if BoundaryFromRadiusKm(45, 7, 2.5) != BoundaryFromRadiusMeters(45, 7, 2500) {
+		t.Error("BoundaryFromRadiusKm must match BoundaryFromRadiusMeters")
+	}
+
+	// --- Test 3: around the pole every longitude is inside ---
+	if b := BoundaryFromRadiusKm(89, 10, 200); b.Width != 180 {
+		t.Errorf("200 km around 89°N: Width 180 expected, got %v", b.Width)
+	}
+}
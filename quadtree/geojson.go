@@ -0,0 +1,67 @@
+package quadtree // GeoJSON export of the QuadTree
+
+import (
+	"bytes"         // Import bytes package (Buffer)
+	"encoding/json" // Import JSON encoding package
+	"fmt"           // Import formatting package (for errors)
+)
+
+// geoJSONFeature is a single GeoJSON Point feature
+type geoJSONFeature[T comparable] struct {
+	Type       string               `json:"type"`
+	Geometry   geoJSONGeometry      `json:"geometry"`
+	Properties geoJSONProperties[T] `json:"properties"`
+}
+
+// geoJSONGeometry is a GeoJSON Point geometry.
+// Coordinates are [longitude, latitude], as required by the spec.
+type geoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSONProperties holds the point Data (e.g. the driver ID)
+type geoJSONProperties[T comparable] struct {
+	ID T `json:"id"`
+}
+
+// ToGeoJSON exports every point of the tree as a GeoJSON FeatureCollection
+// of Point features, with the point Data in properties.id.
+// The output is built incrementally in a buffer while walking the leaves,
+// and an empty tree produces a valid, empty FeatureCollection.
+func (qt *QuadTreeOf[T]) ToGeoJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"FeatureCollection","features":[`)
+
+	var err error
+	first := true
+	qt.ForEach(func(p *PointOf[T]) bool {
+		feature, e := json.Marshal(geoJSONFeature[T]{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type: "Point",
+				// GeoJSON order is [lon, lat], i.e. [X, Y]
+				Coordinates: [2]float64{p.X, p.Y},
+			},
+			Properties: geoJSONProperties[T]{ID: p.Data},
+		})
+		if e != nil {
+			// Stop the traversal at the first failure
+			err = fmt.Errorf("quadtree: encoding point (%v, %v): %w", p.X, p.Y, e)
+			return false
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(feature)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(`]}`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package quadtree // Tests for the GeoJSON export
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestQuadTreeToGeoJSON verifies the FeatureCollection structure
+// and the [lon, lat] coordinate order.
+func TestQuadTreeToGeoJSON(t *testing.T) {
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				ID string `json:"id"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+
+	// --- Test 1: An empty tree is a valid, empty FeatureCollection ---
+	data, err := qt.ToGeoJSON()
+	if err != nil {
+		t.Fatalf("ToGeoJSON on empty tree failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("ToGeoJSON on empty tree produced invalid JSON: %v", err)
+	}
+	if fc.Type != "FeatureCollection" || fc.Features == nil || len(fc.Features) != 0 {
+		t.Errorf("Empty tree: empty FeatureCollection expected, got %s", data)
+	}
+
+	// --- Test 2: Every point becomes a Feature with [lon, lat] ---
+	qt.Insert(&PointOf[string]{X: 12.49, Y: 41.89, Data: "driver-rome"}) // lon, lat
+	qt.Insert(&PointOf[string]{X: -74.0, Y: 40.71, Data: "driver-nyc"})
+	qt.Insert(&PointOf[string]{X: 151.2, Y: -33.87, Data: "driver-sydney"})
+
+	data, err = qt.ToGeoJSON()
+	if err != nil {
+		t.Fatalf("ToGeoJSON failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("ToGeoJSON produced invalid JSON: %v", err)
+	}
+	if len(fc.Features) != 3 {
+		t.Fatalf("3 features expected, got %d", len(fc.Features))
+	}
+	for _, f := range fc.Features {
+		if f.Type != "Feature" || f.Geometry.Type != "Point" {
+			t.Errorf("Wrong feature types: %s/%s", f.Type, f.Geometry.Type)
+		}
+		if f.Properties.ID == "driver-rome" && (f.Geometry.Coordinates[0] != 12.49 || f.Geometry.Coordinates[1] != 41.89) {
+			t.Errorf("driver-rome: [12.49, 41.89] expected, got %v", f.Geometry.Coordinates)
+		}
+	}
+}
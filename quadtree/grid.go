@@ -0,0 +1,134 @@
+package quadtree // Counting points per cell of a regular grid (heatmaps)
+
+import (
+	"errors" // Import errors package (New)
+	"fmt"    // Import formatting package (Errorf)
+	"math"   // Import math package (Floor, Inf, Nextafter)
+)
+
+// ErrInvalidGrid is returned (wrapped) by DensityGrid for a grid
+// with fewer than one column or row
+var ErrInvalidGrid = errors.New("quadtree: invalid grid")
+
+// grid maps coordinates to the cells of a cols x rows grid over an area
+type grid struct {
+	area         *Boundary
+	minX, minY   float64
+	cellW, cellH float64
+	cols, rows   int
+	counts       [][]int
+}
+
+// newGrid prepares an empty count matrix: counts[row][col], row 0 at the South
+func newGrid(area *Boundary, cols, rows int) *grid {
+	g := &grid{
+		area:  area,
+		minX:  area.X - area.Width,
+		minY:  area.Y - area.Height,
+		cellW: 2 * area.Width / float64(cols),
+		cellH: 2 * area.Height / float64(rows),
+		cols:  cols,
+		rows:  rows,
+	}
+	g.counts = make([][]int, rows)
+	cells := make([]int, rows*cols) // One allocation for the whole matrix
+	for r := range g.counts {
+		g.counts[r] = cells[r*cols : (r+1)*cols]
+	}
+	return g
+}
+
+// col returns the column of x. It never decreases when x grows, so two
+// coordinates in the same column have the whole range between them in it.
+// Rounding can push a value on the area's max edge one column too far:
+// the index is clamped.
+func (g *grid) col(x float64) int {
+	return min(max(int(math.Floor((x-g.minX)/g.cellW)), 0), g.cols-1)
+}
+
+// row is col for y
+func (g *grid) row(y float64) int {
+	return min(max(int(math.Floor((y-g.minY)/g.cellH)), 0), g.rows-1)
+}
+
+// CountGrid splits area into cols x rows equal cells and returns the number
+// of points in each cell, as counts[row][col], with row 0 at the South and
+// column 0 at the West. Like Query, a cell includes its West/South edges and
+// excludes its East/North ones, so every point is counted at most once.
+// It returns nil for cols or rows below 1, or an area that is not valid.
+//
+// Branches outside the area are pruned, and a node that lies entirely in one
+// cell is counted with its subtree size, without visiting its points: on a
+// coarse grid most of the tree is never walked. See BenchmarkCountGrid.
+func (qt *QuadTreeOf[T]) CountGrid(area *Boundary, cols, rows int) [][]int {
+	if cols < 1 || rows < 1 || area == nil || area.Validate() != nil {
+		return nil
+	}
+	g := newGrid(area, cols, rows)
+	// Expired points (see InsertWithTTL) are not counted
+	qt.countGridRecursive(g, qt.expiry.live(nil))
+	return g.counts
+}
+
+// DensityGrid is CountGrid telling why a grid is rejected: the error wraps
+// ErrInvalidGrid for cols or rows below 1, and ErrInvalidBoundary for a
+// missing or invalid rect (see Boundary.Validate).
+func (qt *QuadTreeOf[T]) DensityGrid(rect *Boundary, cols, rows int) ([][]int, error) {
+	if cols < 1 || rows < 1 {
+		return nil, fmt.Errorf("%w: %d columns and %d rows, both must be at least 1", ErrInvalidGrid, cols, rows)
+	}
+	if rect == nil {
+		return nil, fmt.Errorf("%w: no area", ErrInvalidBoundary)
+	}
+	if err := rect.Validate(); err != nil {
+		return nil, err
+	}
+	return qt.CountGrid(rect, cols, rows), nil
+}
+
+// countGridRecursive adds the points of this subtree accepted by keep
+// to the grid (nil: all of them, using the stored sizes)
+func (qt *QuadTreeOf[T]) countGridRecursive(g *grid, keep func(*PointOf[T]) bool) {
+	qt.rlock()
+	defer qt.runlock()
+
+	// Same pruning as queryRecursive
+	if !qt.intersects(g.area) {
+		return
+	}
+
+	// --- Fast path ---
+	// A node inside the area whose first and last possible points fall
+	// in the same cell: the whole subtree goes to that cell
+	if keep == nil && qt.insideRect(g.area) {
+		lastX, lastY := qt.maxX, qt.maxY
+		// An open max edge holds no point: the last one is just before it
+		if !qt.closedEast {
+			lastX = math.Nextafter(lastX, math.Inf(-1))
+		}
+		if !qt.closedNorth {
+			lastY = math.Nextafter(lastY, math.Inf(-1))
+		}
+		col, row := g.col(qt.minX), g.row(qt.minY)
+		if col == g.col(lastX) && row == g.row(lastY) {
+			g.counts[row][col] += int(qt.size.Load())
+			return
+		}
+	}
+
+	// If this is a "leaf" node, bin its points one by one
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			x, y := qt.xy[2*i], qt.xy[2*i+1]
+			if g.area.ContainsXY(x, y) && (keep == nil || keep(p)) {
+				g.counts[g.row(y)][g.col(x)]++
+			}
+		}
+		return
+	}
+
+	qt.northWest.countGridRecursive(g, keep)
+	qt.northEast.countGridRecursive(g, keep)
+	qt.southWest.countGridRecursive(g, keep)
+	qt.southEast.countGridRecursive(g, keep)
+}
@@ -0,0 +1,84 @@
+package quadtree // Tests for the grid counts
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestQuadTreeCountGrid verifies the counts per cell on known points,
+// then against a point-by-point binning on random ones
+func TestQuadTreeCountGrid(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// --- Test 1: known points in a 4x2 grid over [0,40) x [0,20) (cells of 10x10) ---
+	for _, p := range []*Point{
+		{X: 1, Y: 1}, {X: 9, Y: 9}, // Cell (row 0, col 0)
+		{X: 10, Y: 0},  // West/South edges included: (0, 1)
+		{X: 35, Y: 15}, // (1, 3)
+		{X: 40, Y: 5},  // East edge of the area: excluded
+		{X: 5, Y: 20},  // North edge of the area: excluded
+		{X: -1, Y: 5},  // Outside
+	} {
+		qt.Insert(p)
+	}
+	counts := qt.CountGrid(&Boundary{X: 20, Y: 10, Width: 20, Height: 10}, 4, 2)
+	expected := [][]int{{2, 1, 0, 0}, {0, 0, 0, 1}}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("%v expected, got %v", expected, counts)
+	}
+
+	// --- Test 2: random points, same counts as binning them one by one ---
+	big := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 5000; i++ {
+		big.Insert(&Point{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i})
+	}
+	area := &Boundary{X: 10, Y: 20, Width: 60, Height: 40}
+	counts = big.CountGrid(area, 12, 8)
+	g := newGrid(area, 12, 8)
+	total := 0
+	for _, p := range big.Query(area) {
+		g.counts[g.row(p.Y)][g.col(p.X)]++
+		total++
+	}
+	sum := 0
+	for r := range counts {
+		for c := range counts[r] {
+			sum += counts[r][c]
+			if counts[r][c] != g.counts[r][c] {
+				t.Errorf("Cell (%d, %d): %d expected, got %d", r, c, g.counts[r][c], counts[r][c])
+			}
+		}
+	}
+	if sum != total {
+		t.Errorf("The cells must add up to the %d points of the area, got %d", total, sum)
+	}
+
+	// --- Test 3: invalid grids ---
+	if qt.CountGrid(area, 0, 3) != nil || qt.CountGrid(&Boundary{Width: 0, Height: 1}, 2, 2) != nil {
+		t.Error("Invalid grids must return nil")
+	}
+
+	// --- Test 4: DensityGrid returns the same counts, or says why not ---
+	counts, err := big.DensityGrid(area, 8, 4)
+	if err != nil {
+		t.Fatalf("DensityGrid: unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(counts, big.CountGrid(area, 8, 4)) {
+		t.Errorf("DensityGrid and CountGrid disagree:\n%v\n%v", counts, big.CountGrid(area, 8, 4))
+	}
+	if _, err := big.DensityGrid(area, 0, 4); !errors.Is(err, ErrInvalidGrid) {
+		t.Errorf("0 columns: ErrInvalidGrid expected, got %v", err)
+	}
+	if _, err := big.DensityGrid(area, 8, -1); !errors.Is(err, ErrInvalidGrid) {
+		t.Errorf("-1 rows: ErrInvalidGrid expected, got %v", err)
+	}
+	if _, err := big.DensityGrid(&Boundary{Width: 0, Height: 1}, 2, 2); !errors.Is(err, ErrInvalidBoundary) {
+		t.Errorf("Empty area: ErrInvalidBoundary expected, got %v", err)
+	}
+	if _, err := big.DensityGrid(nil, 2, 2); !errors.Is(err, ErrInvalidBoundary) {
+		t.Errorf("No area: ErrInvalidBoundary expected, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package quadtree // Hand-over-hand locking for trees with one lock per node
+
+// With one lock per node (WithNodeLocks, or WithShards without
+// WithSingleLock) each node's lock only guards that node: its points and
+// its children pointers. The rules that keep this safe:
+//
+//   - Children pointers only change under the node's Write Lock
+//     (subdivide runs under the leaf's own lock, collapse under the parent's).
+//   - Inserts walk down hand-over-hand: they take the child's lock before
+//     releasing the parent's, and only hold a Write Lock on the leaf.
+//     They count themselves in 'size' before leaving a node, so a node
+//     with size 0 has no insert running below it.
+//   - Removes, queries and the other operations keep the locks of the
+//     whole path, top-down, so they never overtake an insert.
+
+// nodeLocks reports whether this tree has one lock per node
+func (qt *QuadTreeOf[T]) nodeLocks() bool {
+	return !qt.singleLock && !qt.noLock
+}
+
+// childFor returns the child that holds (or would hold) p.
+// The children cover this node exactly (see minX): West of the shared
+// vertical edge, or East of it; South of the horizontal one, or North.
+func (qt *QuadTreeOf[T]) childFor(p *PointOf[T]) *QuadTreeOf[T] {
+	west := p.X < qt.northWest.maxX
+	north := p.Y >= qt.northWest.minY
+	switch {
+	case north && west:
+		return qt.northWest
+	case north:
+		return qt.northEast
+	case west:
+		return qt.southWest
+	default:
+		return qt.southEast
+	}
+}
+
+// lockNode takes this node's Read Lock if it is a parent, or its Write
+// Lock if it is a leaf (the one an insert writes to), and tells which.
+// The caller holds the parent's lock, so the node can't be dropped
+// while we switch from the Read to the Write Lock.
+func (qt *QuadTreeOf[T]) lockNode() (write bool) {
+	qt.mu.RLock()
+	if qt.northWest != nil {
+		return false
+	}
+	qt.mu.RUnlock()
+	// The node may have been split in between: the caller checks again
+	qt.mu.Lock()
+	return true
+}
+
+// unlockNode releases the lock taken by lockNode
+func (qt *QuadTreeOf[T]) unlockNode(write bool) {
+	if write {
+		qt.mu.Unlock()
+		return
+	}
+	qt.mu.RUnlock()
+}
+
+// insertHandOverHand is insert for trees with one lock per node.
+// At most two nodes are locked at any time, and only the leaf for writing:
+// writers heading to different branches only meet for an instant on the
+// Read Locks of the nodes they share.
+func (qt *QuadTreeOf[T]) insertHandOverHand(p *PointOf[T]) bool {
+	// The root has no parent whose lock keeps it in place,
+	// but it is never dropped: lockNode works for it too
+	node := qt
+	write := node.lockNode()
+
+	// If the point is not within the tree's boundary, reject it
+	if !node.contains(p) {
+		node.unlockNode(write)
+		return false
+	}
+
+	// Walk down the parents. Each one counts the point before we leave it,
+	// so it never looks empty (see collapse) while we are below it.
+	for node.northWest != nil {
+		node.size.Add(1)
+		child := node.childFor(p)
+		childWrite := child.lockNode()
+		node.unlockNode(write)
+		node, write = child, childWrite
+	}
+
+	// A leaf: lockNode gave us its Write Lock
+	node.addToLeaf(p)
+	node.mu.Unlock()
+	return true
+}
+
+// removeShared is remove for trees with one lock per node: the parents
+// on the path are only Read Locked, and only the leaf is Write Locked,
+// so removes in different branches don't block each other.
+// It reports whether a subtree was left empty and can be collapsed:
+// that needs the parent's Write Lock, which we don't hold (see collapse).
+func (qt *QuadTreeOf[T]) removeShared(p *PointOf[T], exact bool) (removed *PointOf[T], emptied bool) {
+	write := qt.lockNode()
+	defer qt.unlockNode(write)
+
+	// If the point can't exist in this boundary, return failure
+	if !qt.contains(p) {
+		return nil, false
+	}
+
+	// A leaf (lockNode gave us its Write Lock)
+	if qt.northWest == nil {
+		return qt.removeFromLeaf(p, exact), false
+	}
+
+	// A parent (possibly split while we waited for its Write Lock):
+	// only the child covering p can hold it
+	removed, emptied = qt.childFor(p).removeShared(p, exact)
+	if removed == nil {
+		return nil, false
+	}
+	// One less point in this subtree: the fixed shard levels are never collapsed
+	if qt.size.Add(-1) == 0 && !qt.fixed {
+		emptied = true
+	}
+	return removed, emptied
+}
+
+// collapse turns the highest empty subtree on the path to (x, y) back into
+// a leaf, like removeRecursive does on the way up. It holds the locks of
+// the path (Write Locks, except on the fixed levels) so nobody is inside
+// the subtree it drops: an insert below would have made it non-empty.
+func (qt *QuadTreeOf[T]) collapse(x, y float64) {
+	qt.lockForWrite()
+	defer qt.unlockForWrite()
+
+	// Already collapsed by someone else
+	if qt.northWest == nil {
+		return
+	}
+	if qt.size.Load() == 0 && !qt.fixed {
+		qt.dropChildren()
+		return
+	}
+	qt.childFor(&PointOf[T]{X: x, Y: y}).collapse(x, y)
+}
+
+// drain waits until the inserts still walking down below this node are
+// done. The caller holds this node's Write Lock, so no new one can start:
+// taking each lock in turn, top-down, always lands behind them.
+// Trees with a single lock have nothing to wait for.
+func (qt *QuadTreeOf[T]) drain() {
+	if !qt.nodeLocks() || qt.northWest == nil {
+		return
+	}
+	for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+		child.mu.Lock()
+		child.drain()
+		child.mu.Unlock()
+	}
+}
+
+// lockWhole stops every writer, for the operations that copy the whole
+// tree and must see one consistent state of it (Clone, Snapshot,
+// SaveSnapshot, MarshalJSON). In a single-lock tree the root's Read Lock
+// is enough. With one lock per node it is not: writers already below the
+// root only hold the locks of their own path, and a fixed root only gets
+// Read Locked by the new ones. So we take the root's Write Lock (no new
+// writer gets in) and drain (the ones inside are done). Readers wait too.
+// The caller reads the root itself without locking it again.
+func (qt *QuadTreeOf[T]) lockWhole() {
+	if !qt.nodeLocks() {
+		qt.rlock()
+		return
+	}
+	qt.mu.Lock()
+	qt.drain()
+}
+
+// unlockWhole releases the lock taken by lockWhole
+func (qt *QuadTreeOf[T]) unlockWhole() {
+	if !qt.nodeLocks() {
+		qt.runlock()
+		return
+	}
+	qt.mu.Unlock()
+}
@@ -0,0 +1,208 @@
+package quadtree // ID index: find and remove points by their Data
+
+import (
+	"sync" // Import concurrency package (Mutex)
+)
+
+// idIndex maps the Data of every point (its "ID") to the point itself.
+// It lives on the root of a tree created WithIDIndex. Every change to the
+// tree is made while holding its lock, so the index and the tree never disagree.
+// Lock order: the index lock is always taken before the node locks.
+type idIndex[T comparable] struct {
+	mu   sync.Mutex
+	byID map[T]*PointOf[T]
+	// key reduces a Data to its ID (nil: the whole Data is the ID, see WithIDKey)
+	key func(T) T
+}
+
+// WithIDIndex keeps an index from the point Data to the point, so points
+// can be found and removed by ID (see GetByID, RemoveByID, MoveByID)
+// without knowing their coordinates. The Data of every point must be unique:
+// Insert rejects a point whose Data is already in the tree.
+func WithIDIndex() Option {
+	return func(o *options) {
+		o.idIndex = true
+	}
+}
+
+// WithIDKey is WithIDIndex for a Data that carries more than an ID, e.g. a
+// struct with the ID and some attributes of the point. key must return the
+// part of a Data that identifies the point (e.g. the struct with only its ID
+// field set): the index is keyed on it, so the other fields can change
+// without the point changing identity (see ReplaceByID and UpdateByID),
+// and GetByID, RemoveByID and MoveByID accept any Data with the right ID.
+func WithIDKey[T comparable](key func(T) T) Option {
+	return func(o *options) {
+		o.idIndex = true
+		o.idKey = key
+	}
+}
+
+// newIDIndex creates the index of a tree created with the given options.
+// It panics if WithIDKey was given a key function for another Data type.
+func newIDIndex[T comparable](o *options) *idIndex[T] {
+	ix := &idIndex[T]{byID: map[T]*PointOf[T]{}}
+	if o.idKey != nil {
+		key, ok := o.idKey.(func(T) T)
+		if !ok {
+			panic("quadtree: WithIDKey used with a different Data type than the tree's")
+		}
+		ix.key = key
+	}
+	return ix
+}
+
+// id returns the ID of a Data (the caller holds the lock)
+func (ix *idIndex[T]) id(data T) T {
+	if ix.key == nil {
+		return data
+	}
+	return ix.key(data)
+}
+
+// get returns the point whose ID is the ID of data (the caller holds the lock)
+func (ix *idIndex[T]) get(data T) (*PointOf[T], bool) {
+	p, ok := ix.byID[ix.id(data)]
+	return p, ok
+}
+
+// put indexes p under the ID of its Data (the caller holds the lock)
+func (ix *idIndex[T]) put(p *PointOf[T]) {
+	ix.byID[ix.id(p.Data)] = p
+}
+
+// dropLocked removes p from the index (the caller holds the lock).
+// It is a no-op on a nil index or if the ID belongs to another point.
+func (ix *idIndex[T]) dropLocked(p *PointOf[T]) {
+	if ix == nil {
+		return
+	}
+	if id := ix.id(p.Data); ix.byID[id] == p {
+		delete(ix.byID, id)
+	}
+}
+
+// moveLocked points the ID of 'from' to 'to' (the caller holds the lock)
+func (ix *idIndex[T]) moveLocked(from, to *PointOf[T]) {
+	if ix == nil {
+		return
+	}
+	if cur, _ := ix.get(from.Data); cur == from {
+		delete(ix.byID, ix.id(from.Data))
+		ix.put(to)
+	}
+}
+
+// remapped returns a copy of the index pointing to the remapped points
+// (used by Clone). Points missing from remap are left out.
+func (ix *idIndex[T]) remapped(remap map[*PointOf[T]]*PointOf[T]) *idIndex[T] {
+	if ix == nil {
+		return nil
+	}
+	cp := &idIndex[T]{byID: make(map[T]*PointOf[T], len(ix.byID)), key: ix.key}
+	for id, p := range ix.byID {
+		if np, ok := remap[p]; ok {
+			cp.byID[id] = np
+		}
+	}
+	return cp
+}
+
+// GetByID returns the point whose Data is id (with WithIDKey: has the ID of id).
+// It always returns false on a tree created without WithIDIndex.
+func (qt *QuadTreeOf[T]) GetByID(id T) (*PointOf[T], bool) {
+	if qt.ids == nil {
+		return nil, false
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	return qt.ids.get(id)
+}
+
+// RemoveByID removes the point whose Data is id, without knowing its coordinates.
+// It returns false if there is no such point (or no ID index).
+func (qt *QuadTreeOf[T]) RemoveByID(id T) bool {
+	if qt.ids == nil {
+		return false
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	p, ok := qt.ids.get(id)
+	if !ok {
+		return false
+	}
+	removed := qt.remove(p, false)
+	qt.ids.dropLocked(p)
+
+	if removed != nil {
+		// Keep the label and expiry indexes in sync with the tree
+		qt.labels.drop(removed)
+		qt.expiry.drop(removed)
+		qt.observeRemove(1)
+	}
+	return removed != nil
+}
+
+// MoveByID moves the point whose Data is id to (newX, newY), like Update.
+// It returns the new point, or nil if there is no such point (or no ID index)
+// or the new position is outside the boundary (the point is then left where it was).
+func (qt *QuadTreeOf[T]) MoveByID(id T, newX, newY float64) *PointOf[T] {
+	if qt.ids == nil {
+		return nil
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	p, ok := qt.ids.get(id)
+	if !ok {
+		return nil
+	}
+	return qt.updateLocked(p, newX, newY)
+}
+
+// ReplaceByID replaces the Data of the point with the same ID as data
+// (see WithIDKey), leaving it where it is. Like Update, the stored point is
+// not modified in place: it is replaced by a new point, which is returned.
+// It returns nil if there is no such point (or no ID index).
+func (qt *QuadTreeOf[T]) ReplaceByID(data T) *PointOf[T] {
+	if qt.ids == nil {
+		return nil
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	p, ok := qt.ids.get(data)
+	if !ok {
+		return nil
+	}
+	return qt.replaceLocked(p, p.X, p.Y, data)
+}
+
+// UpdateByID changes the point whose Data has the ID of id in one step:
+// update receives a copy of the stored point and returns the point it must
+// become (position and Data), e.g. to move a vehicle and set its heading
+// together. update runs under the ID index lock, so no other change by ID
+// can slip in between; it must not call back into the tree.
+// Like Update, the stored point is replaced by a new one, which is returned.
+// It returns nil, leaving the tree untouched, if there is no such point
+// (or no ID index), if the new position is outside the boundary, or if
+// update changed the ID.
+func (qt *QuadTreeOf[T]) UpdateByID(id T, update func(cur PointOf[T]) PointOf[T]) *PointOf[T] {
+	if qt.ids == nil {
+		return nil
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	p, ok := qt.ids.get(id)
+	if !ok {
+		return nil
+	}
+	next := update(*p)
+	if qt.ids.id(next.Data) != qt.ids.id(p.Data) {
+		return nil
+	}
+	return qt.replaceLocked(p, next.X, next.Y, next.Data)
+}
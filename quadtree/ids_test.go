@@ -0,0 +1,225 @@
+package quadtree // Tests for the ID index
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// TestQuadTreeIDIndex verifies GetByID, RemoveByID and MoveByID
+func TestQuadTreeIDIndex(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2, WithIDIndex())
+	for i := 0; i < 20; i++ {
+		qt.Insert(&PointOf[string]{X: float64(i), Y: float64(i), Data: fmt.Sprintf("driver-%d", i)})
+	}
+
+	// --- Test 1: lookup and unique IDs ---
+	p, ok := qt.GetByID("driver-7")
+	if !ok || p.X != 7 || p.Y != 7 {
+		t.Fatalf("GetByID(driver-7) = %+v, %v", p, ok)
+	}
+	if qt.Insert(&PointOf[string]{X: 50, Y: 50, Data: "driver-7"}) {
+		t.Errorf("A duplicate ID should be rejected")
+	}
+
+	// --- Test 2: move by ID ---
+	moved := qt.MoveByID("driver-7", -100, -40)
+	if moved == nil || moved.X != -100 || moved.Y != -40 {
+		t.Fatalf("MoveByID returned %+v", moved)
+	}
+	if p, _ := qt.GetByID("driver-7"); p != moved {
+		t.Errorf("The index should point to the moved point")
+	}
+	if qt.MoveByID("driver-7", 500, 0) != nil || qt.MoveByID("nobody", 1, 1) != nil {
+		t.Errorf("Invalid moves should fail")
+	}
+
+	// --- Test 3: remove by ID (and by point) ---
+	if !qt.RemoveByID("driver-7") || qt.RemoveByID("driver-7") {
+		t.Errorf("RemoveByID should succeed exactly once")
+	}
+	if _, ok := qt.GetByID("driver-7"); ok {
+		t.Errorf("driver-7 is still in the index")
+	}
+	p, _ = qt.GetByID("driver-3")
+	qt.Remove(p)
+	if _, ok := qt.GetByID("driver-3"); ok {
+		t.Errorf("Remove should drop the point from the index")
+	}
+	if qt.Count() != 18 || len(qt.ids.byID) != 18 {
+		t.Errorf("18 points expected, tree %d, index %d", qt.Count(), len(qt.ids.byID))
+	}
+
+	// --- Test 4: the clone has its own index ---
+	clone := qt.Clone()
+	cp, ok := clone.GetByID("driver-1")
+	if orig, _ := qt.GetByID("driver-1"); !ok || cp == orig {
+		t.Errorf("The clone index should point to the cloned points")
+	}
+
+	// --- Test 5: trees without the option have no index ---
+	plain := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 2)
+	plain.Insert(&PointOf[string]{X: 1, Y: 1, Data: "a"})
+	if _, ok := plain.GetByID("a"); ok || plain.RemoveByID("a") {
+		t.Errorf("A tree without WithIDIndex should not find points by ID")
+	}
+}
+
+// TestQuadTreeIDIndexConcurrent hammers Insert/MoveByID/RemoveByID from
+// many goroutines, then verifies that the index and the tree agree.
+// Run it with -race.
+func TestQuadTreeIDIndexConcurrent(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithIDIndex(), WithShards(1))
+
+	const workers = 8
+	const ops = 2000
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < ops; i++ {
+				// The workers share the same 50 IDs, so they collide on purpose
+				id := fmt.Sprintf("driver-%d", rng.Intn(50))
+				x, y := rng.Float64()*360-180, rng.Float64()*180-90
+				switch rng.Intn(3) {
+				case 0:
+					qt.Insert(&PointOf[string]{X: x, Y: y, Data: id})
+				case 1:
+					qt.MoveByID(id, x, y)
+				case 2:
+					qt.RemoveByID(id)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// --- Every indexed point is in the tree, at its coordinates ---
+	for id, p := range qt.ids.byID {
+		found := qt.Query(&Boundary{X: p.X, Y: p.Y, Width: 1e-9, Height: 1e-9})
+		inTree := false
+		for _, f := range found {
+			inTree = inTree || f == p
+		}
+		if !inTree {
+			t.Errorf("%s is in the index but not in the tree", id)
+		}
+	}
+	// --- Every point of the tree is indexed ---
+	all := qt.AllPoints()
+	if len(all) != len(qt.ids.byID) {
+		t.Errorf("Tree has %d points, index has %d", len(all), len(qt.ids.byID))
+	}
+	for _, p := range all {
+		if qt.ids.byID[p.Data] != p {
+			t.Errorf("%s is in the tree but not (or differently) in the index", p.Data)
+		}
+	}
+}
+
+// vehicle is a Data with an ID and an attribute that changes over time
+type vehicle struct {
+	ID    string
+	State string
+}
+
+// TestQuadTreeIDKey verifies that WithIDKey indexes a struct Data by its
+// ID field only, and that ReplaceByID changes the other fields in place
+func TestQuadTreeIDKey(t *testing.T) {
+	byID := func(v vehicle) vehicle { return vehicle{ID: v.ID} }
+	qt := NewQuadTreeOf[vehicle](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2, WithIDKey(byID))
+	for i := 0; i < 20; i++ {
+		qt.Insert(&PointOf[vehicle]{X: float64(i), Y: float64(i), Data: vehicle{ID: fmt.Sprintf("v%d", i), State: "available"}})
+	}
+
+	// --- Test 1: lookup by ID alone, whatever the other fields ---
+	p, ok := qt.GetByID(vehicle{ID: "v7"})
+	if !ok || p.X != 7 || p.Data.State != "available" {
+		t.Fatalf("GetByID(v7) = %+v, %v", p, ok)
+	}
+	if qt.Insert(&PointOf[vehicle]{X: 1, Y: 1, Data: vehicle{ID: "v7", State: "busy"}}) {
+		t.Error("Insert accepted a second point with the ID v7")
+	}
+
+	// --- Test 2: ReplaceByID changes the Data but not the position or identity ---
+	replaced := qt.ReplaceByID(vehicle{ID: "v7", State: "busy"})
+	if replaced == nil || replaced == p || replaced.X != 7 || replaced.Data.State != "busy" {
+		t.Fatalf("ReplaceByID(v7) = %+v", replaced)
+	}
+	if p.Data.State != "available" {
+		t.Error("ReplaceByID modified the old point in place")
+	}
+	if got, _ := qt.GetByID(vehicle{ID: "v7"}); got != replaced {
+		t.Errorf("GetByID after ReplaceByID: %p expected, got %p", replaced, got)
+	}
+	if found := qt.Query(&Boundary{X: 7, Y: 7, Width: 0.5, Height: 0.5}); len(found) != 1 || found[0] != replaced {
+		t.Errorf("Query after ReplaceByID: only the new point expected, got %v", found)
+	}
+	if qt.ReplaceByID(vehicle{ID: "missing"}) != nil {
+		t.Error("ReplaceByID of an unknown ID must return nil")
+	}
+
+	// --- Test 3: MoveByID keeps the Data, RemoveByID needs only the ID ---
+	if moved := qt.MoveByID(vehicle{ID: "v7"}, 50, 50); moved == nil || moved.Data.State != "busy" {
+		t.Fatalf("MoveByID(v7) = %+v", moved)
+	}
+	if !qt.RemoveByID(vehicle{ID: "v7"}) || qt.Count() != 19 {
+		t.Errorf("RemoveByID(v7): 19 points expected, got %d", qt.Count())
+	}
+	if _, ok := qt.GetByID(vehicle{ID: "v7"}); ok {
+		t.Error("v7 is still indexed after RemoveByID")
+	}
+
+	// --- Test 4: Clone keeps the key ---
+	clone := qt.Clone()
+	if _, ok := clone.GetByID(vehicle{ID: "v3"}); !ok {
+		t.Error("Clone lost the ID key: v3 not found by ID")
+	}
+}
+
+// TestQuadTreeUpdateByID verifies that UpdateByID moves a point and
+// changes its Data in one step, and refuses to change its ID
+func TestQuadTreeUpdateByID(t *testing.T) {
+	byID := func(v vehicle) vehicle { return vehicle{ID: v.ID} }
+	qt := NewQuadTreeOf[vehicle](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2, WithIDKey(byID))
+	for i := 0; i < 20; i++ {
+		qt.Insert(&PointOf[vehicle]{X: float64(i), Y: float64(i), Data: vehicle{ID: fmt.Sprintf("v%d", i), State: "available"}})
+	}
+
+	// --- Test 1: position and Data change together ---
+	updated := qt.UpdateByID(vehicle{ID: "v3"}, func(cur PointOf[vehicle]) PointOf[vehicle] {
+		cur.X, cur.Y = cur.X+10, cur.Y+10
+		cur.Data.State = "busy"
+		return cur
+	})
+	if updated == nil || updated.X != 13 || updated.Y != 13 || updated.Data.State != "busy" {
+		t.Fatalf("UpdateByID(v3) = %+v", updated)
+	}
+	if got, _ := qt.GetByID(vehicle{ID: "v3"}); got != updated {
+		t.Errorf("GetByID after UpdateByID: %p expected, got %p", updated, got)
+	}
+	if found := qt.Query(&Boundary{X: 3, Y: 3, Width: 0.5, Height: 0.5}); len(found) != 0 {
+		t.Errorf("The old position still holds %v", found)
+	}
+
+	// --- Test 2: changing the ID, leaving the boundary or an unknown ID change nothing ---
+	rename := func(cur PointOf[vehicle]) PointOf[vehicle] {
+		cur.Data.ID = "other"
+		return cur
+	}
+	outside := func(cur PointOf[vehicle]) PointOf[vehicle] {
+		cur.X = 500
+		return cur
+	}
+	if qt.UpdateByID(vehicle{ID: "v4"}, rename) != nil || qt.UpdateByID(vehicle{ID: "v4"}, outside) != nil ||
+		qt.UpdateByID(vehicle{ID: "missing"}, rename) != nil {
+		t.Error("UpdateByID must refuse a new ID, a position outside and an unknown ID")
+	}
+	if p, ok := qt.GetByID(vehicle{ID: "v4"}); !ok || p.X != 4 || qt.Count() != 20 {
+		t.Errorf("v4 must be left untouched at 4, got %+v (%d points)", p, qt.Count())
+	}
+}
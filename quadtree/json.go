@@ -0,0 +1,243 @@
+package quadtree // JSON persistence for the QuadTree
+
+import (
+	"encoding/json" // Import JSON encoding package
+	"errors"        // Import errors package
+	"fmt"           // Import formatting package (for errors)
+)
+
+// pointRecord is the on-disk representation of a single point
+// (shared by the JSON and the binary snapshot formats)
+type pointRecord[T comparable] struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Data T       `json:"data"`
+}
+
+// nodeRecord is the on-disk representation of a node and its subtree.
+// A leaf stores its points, a parent stores its four children
+// in the order North-West, North-East, South-West, South-East.
+// The capacity, the maximum depth and the shard levels are only written on the root.
+type nodeRecord[T comparable] struct {
+	Boundary Boundary         `json:"boundary"`
+	Capacity int              `json:"capacity,omitempty"`
+	MaxDepth int              `json:"max_depth,omitempty"`
+	Shards   int              `json:"shards,omitempty"`
+	Points   []pointRecord[T] `json:"points,omitempty"`
+	Children []*nodeRecord[T] `json:"children,omitempty"`
+}
+
+// MarshalJSON serializes the whole tree (boundary, capacity, points and children) to JSON.
+// Only JSON-serializable Data survives the round-trip: for a QuadTree[any],
+// strings come back as strings, but numbers come back as float64
+// and structs as map[string]interface{}.
+// Like SaveSnapshot, it serializes one consistent state of the tree.
+func (qt *QuadTreeOf[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(qt.rootRecord())
+}
+
+// rootRecord converts the whole tree to its on-disk representation, with
+// every writer stopped (see lockWhole): the record is one consistent state.
+// The TTLs are not saved, so the points already expired are left out:
+// they would come back without a TTL, forever.
+func (qt *QuadTreeOf[T]) rootRecord() *nodeRecord[T] {
+	keep := qt.expiry.live(nil)
+	qt.lockWhole()
+	defer qt.unlockWhole()
+	root := qt.recordNode(keep)
+	root.MaxDepth = qt.maxDepth
+	root.Shards = qt.shardLevels()
+	return root
+}
+
+// toRecord converts this node (and its subtree) to its on-disk
+// representation, with only the points accepted by keep (nil: all of them)
+func (qt *QuadTreeOf[T]) toRecord(keep func(*PointOf[T]) bool) *nodeRecord[T] {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+	return qt.recordNode(keep)
+}
+
+// recordNode is toRecord for a node the caller already holds locked
+func (qt *QuadTreeOf[T]) recordNode(keep func(*PointOf[T]) bool) *nodeRecord[T] {
+	node := &nodeRecord[T]{Boundary: qt.boundary}
+	// Read under the lock: SetCapacity may change it
+	if qt.depth == 0 {
+		node.Capacity = qt.capacity
+	}
+
+	// If this is a "leaf" node, store its points
+	if qt.northWest == nil {
+		node.Points = make([]pointRecord[T], 0, len(qt.points))
+		for _, p := range qt.points {
+			if keep != nil && !keep(p) {
+				continue
+			}
+			node.Points = append(node.Points, pointRecord[T]{X: p.X, Y: p.Y, Data: p.Data})
+		}
+		return node
+	}
+
+	// If this is a "parent" node, store the four children
+	node.Children = []*nodeRecord[T]{
+		qt.northWest.toRecord(keep),
+		qt.northEast.toRecord(keep),
+		qt.southWest.toRecord(keep),
+		qt.southEast.toRecord(keep),
+	}
+	return node
+}
+
+// UnmarshalJSON rebuilds the tree from the JSON produced by MarshalJSON,
+// replacing the current content of qt.
+// Malformed input is rejected with a descriptive error and leaves qt untouched.
+func (qt *QuadTreeOf[T]) UnmarshalJSON(data []byte) error {
+	var root nodeRecord[T]
+	if err := json.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("quadtree: invalid JSON: %w", err)
+	}
+	return qt.loadRecord(&root)
+}
+
+// loadRecord replaces the content of qt with the tree described by root.
+// Malformed input is rejected with a descriptive error and leaves qt untouched.
+func (qt *QuadTreeOf[T]) loadRecord(root *nodeRecord[T]) error {
+	// --- Validation of the root parameters ---
+	if root.Capacity < 1 {
+		return fmt.Errorf("quadtree: invalid capacity %d, must be at least 1", root.Capacity)
+	}
+	// The same rule as NewQuadTree: finite center and edges, positive size
+	if err := root.Boundary.Validate(); err != nil {
+		return fmt.Errorf("quadtree: invalid root boundary %+v: %w", root.Boundary, err)
+	}
+
+	// Build the new tree on the side, so a failure never leaves qt half-loaded
+	// Snapshots written before MaxDepth existed use the default
+	maxDepth := root.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	// The lock mode is not persisted: keep the one of qt
+	// (a zero QuadTreeOf, from LoadQuadTreeOf, has none yet: only the
+	// constructors close the root's edges)
+	opts := []Option{WithMaxDepth(maxDepth), WithShards(root.Shards)}
+	if qt.singleLock {
+		opts = append(opts, WithSingleLock())
+	} else if qt.closedEast {
+		opts = append(opts, WithNodeLocks())
+	}
+	// The pool is not persisted either: keep the one of qt
+	opts = append(opts, withPool(qt.pool))
+	fresh := NewQuadTreeOf[T](root.Boundary, root.Capacity, opts...)
+	if err := fresh.fromRecord(root, "root"); err != nil {
+		return err
+	}
+
+	// Swap the new content in under the Write Lock
+	// (the ID index lock, if any, comes first: see idIndex)
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	// Let the inserts already past the root finish before dropping the old nodes
+	qt.drain()
+	qt.boundary = fresh.boundary
+	qt.setEdges(fresh.minX, fresh.maxX, fresh.minY, fresh.maxY)
+	qt.capacity = fresh.capacity
+	qt.points = fresh.points
+	qt.xy = fresh.xy
+	qt.size.Store(fresh.size.Load())
+	qt.fixed = fresh.fixed
+	qt.depth = fresh.depth
+	qt.maxDepth = fresh.maxDepth
+	qt.northWest = fresh.northWest
+	qt.northEast = fresh.northEast
+	qt.southWest = fresh.southWest
+	qt.southEast = fresh.southEast
+	qt.publishChildren()
+	qt.closedEast = fresh.closedEast
+	qt.closedNorth = fresh.closedNorth
+	qt.singleLock = fresh.singleLock
+	// Labels and TTLs are not persisted: they belonged to the old points
+	qt.labels.reset()
+	qt.expiry.reset()
+
+	// The ID index is rebuilt from the loaded points
+	// (with duplicate IDs in the input, the last one wins)
+	if qt.ids != nil {
+		qt.ids.byID = make(map[T]*PointOf[T], fresh.Count())
+		fresh.ForEach(func(p *PointOf[T]) bool {
+			qt.ids.put(p)
+			return true
+		})
+	}
+
+	return nil
+}
+
+// fromRecord fills this (empty) node with the content of 'node'.
+// 'path' describes the position of the node for error messages (e.g. "root.NE.SW").
+func (qt *QuadTreeOf[T]) fromRecord(node *nodeRecord[T], path string) error {
+	if node == nil {
+		return fmt.Errorf("quadtree: %s: missing node", path)
+	}
+	// Every node must cover exactly the area its parent assigned to it
+	if node.Boundary != qt.boundary {
+		return fmt.Errorf("quadtree: %s: boundary %+v does not match the expected %+v", path, node.Boundary, qt.boundary)
+	}
+
+	switch len(node.Children) {
+	case 0:
+		// A "leaf" node: insert its points.
+		// Insert also subdivides if the snapshot holds more points than
+		// the capacity, so the resulting tree is always a valid QuadTree.
+		for i, p := range node.Points {
+			if !qt.Insert(&PointOf[T]{X: p.X, Y: p.Y, Data: p.Data}) {
+				return fmt.Errorf("quadtree: %s: point %d (%v, %v) is outside the node boundary", path, i, p.X, p.Y)
+			}
+		}
+		return nil
+
+	case 4:
+		// A "parent" node: it can't hold points of its own
+		if len(node.Points) != 0 {
+			return fmt.Errorf("quadtree: %s: a node with children can't hold points", path)
+		}
+		// Shard nodes are already split, all the others are still leaves
+		if qt.northWest == nil {
+			qt.subdivide()
+			qt.publishChildren()
+		}
+		children := []*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast}
+		names := []string{"NW", "NE", "SW", "SE"}
+		for i, child := range children {
+			if err := child.fromRecord(node.Children[i], path+"."+names[i]); err != nil {
+				return err
+			}
+			// Re-derive the size of this subtree from its children
+			qt.size.Add(child.size.Load())
+		}
+		return nil
+
+	default:
+		return errors.New("quadtree: " + path + ": a node must have either 0 or 4 children")
+	}
+}
+
+// LoadQuadTree rebuilds a (non-generic) QuadTree from the JSON produced by MarshalJSON
+func LoadQuadTree(data []byte) (*QuadTree, error) {
+	return LoadQuadTreeOf[any](data)
+}
+
+// LoadQuadTreeOf rebuilds a QuadTree storing Data of type T
+// from the JSON produced by MarshalJSON
+func LoadQuadTreeOf[T comparable](data []byte) (*QuadTreeOf[T], error) {
+	qt := &QuadTreeOf[T]{}
+	if err := qt.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return qt, nil
+}
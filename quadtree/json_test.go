@@ -0,0 +1,107 @@
+package quadtree // Tests for the JSON persistence
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+)
+
+// TestQuadTreeJSONRoundTrip verifies that a tree saved with MarshalJSON
+// and reloaded with LoadQuadTree answers queries exactly like the original.
+func TestQuadTreeJSONRoundTrip(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+
+	qt.Insert(&Point{X: -50, Y: 50, Data: "p1 (NW)"})
+	qt.Insert(&Point{X: 50, Y: 50, Data: "p2 (NE)"})
+	qt.Insert(&Point{X: -50, Y: -50, Data: "p3 (SW)"}) // Forces subdivision
+	qt.Insert(&Point{X: 50, Y: -50, Data: "p4 (SE)"})
+	qt.Insert(&Point{X: 180, Y: 90, Data: "p5 (corner)"})
+
+	data, err := qt.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	loaded, err := LoadQuadTree(data)
+	if err != nil {
+		t.Fatalf("LoadQuadTree failed: %v", err)
+	}
+
+	// --- Test 1: Boundary and capacity are preserved ---
+	if loaded.boundary != qt.boundary || loaded.capacity != qt.capacity {
+		t.Errorf("Parameters not preserved: expected %+v/%d, got %+v/%d",
+			qt.boundary, qt.capacity, loaded.boundary, loaded.capacity)
+	}
+
+	// --- Test 2: Every query returns the same points ---
+	areas := []*Boundary{
+		{X: 50, Y: 50, Width: 50, Height: 50},
+		{X: 0, Y: -50, Width: 100, Height: 50},
+		{X: 180, Y: 90, Width: 1, Height: 1},
+		{X: 0, Y: 0, Width: 180, Height: 90},
+	}
+	for _, area := range areas {
+		before := dataOf(qt.Query(area))
+		after := dataOf(loaded.Query(area))
+		if len(before) != len(after) {
+			t.Fatalf("Query(%+v): %d points before, %d after", *area, len(before), len(after))
+		}
+		for i := range before {
+			if before[i] != after[i] {
+				t.Errorf("Query(%+v): %q expected, got %q", *area, before[i], after[i])
+			}
+		}
+	}
+
+	// --- Test 3: The structure itself is preserved ---
+	if loaded.Fingerprint() != qt.Fingerprint() {
+		t.Error("Fingerprint differs after the round-trip")
+	}
+
+	// --- Test 4: json.Unmarshal works on a zero-value tree ---
+	var decoded QuadTree
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if decoded.Count() != qt.Count() {
+		t.Errorf("json.Unmarshal: %d points expected, got %d", qt.Count(), decoded.Count())
+	}
+}
+
+// TestQuadTreeJSONMalformed verifies that malformed input is
+// rejected with an error instead of panicking.
+func TestQuadTreeJSONMalformed(t *testing.T) {
+	cases := map[string]string{
+		"not json":         `{not json`,
+		"zero capacity":    `{"boundary":{"X":0,"Y":0,"Width":10,"Height":10},"capacity":0}`,
+		"empty boundary":   `{"boundary":{"X":0,"Y":0,"Width":0,"Height":10},"capacity":4}`,
+		"edges overflow":   `{"boundary":{"X":1e308,"Y":0,"Width":1e308,"Height":10},"capacity":4}`,
+		"point outside":    `{"boundary":{"X":0,"Y":0,"Width":10,"Height":10},"capacity":4,"points":[{"x":50,"y":0,"data":"p"}]}`,
+		"two children":     `{"boundary":{"X":0,"Y":0,"Width":10,"Height":10},"capacity":4,"children":[{},{}]}`,
+		"null child":       `{"boundary":{"X":0,"Y":0,"Width":10,"Height":10},"capacity":4,"children":[null,null,null,null]}`,
+		"wrong child area": `{"boundary":{"X":0,"Y":0,"Width":10,"Height":10},"capacity":4,"children":[{"boundary":{"X":1,"Y":1,"Width":1,"Height":1}},{},{},{}]}`,
+	}
+
+	for name, input := range cases {
+		// Start from a populated tree: a failed load must leave it untouched
+		qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 4)
+		qt.Insert(&Point{X: 1, Y: 1, Data: "keep"})
+
+		if err := qt.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("%s: UnmarshalJSON should fail", name)
+		}
+		if qt.Count() != 1 {
+			t.Errorf("%s: the tree was modified by a failed UnmarshalJSON", name)
+		}
+	}
+}
+
+// dataOf returns the sorted string Data of the given points
+func dataOf(points []*Point) []string {
+	out := make([]string, 0, len(points))
+	for _, p := range points {
+		out = append(out, p.Data.(string))
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,176 @@
+package quadtree // Label tagging and inverted index for the QuadTree
+
+import (
+	"sync" // Import concurrency package (Mutex)
+)
+
+// labelIndex keeps the labels attached to the points of a tree.
+// It lives on the root and is kept in sync by Insert/Remove.
+// It has its own lock, independent from the node locks.
+type labelIndex[T comparable] struct {
+	mu sync.RWMutex
+	// Inverted index: label -> set of points carrying it
+	byLabel map[string]map[*PointOf[T]]struct{}
+	// Forward index: point -> its labels (needed to clean up on Remove)
+	byPoint map[*PointOf[T]][]string
+}
+
+// set replaces the labels of p
+func (li *labelIndex[T]) set(p *PointOf[T], labels []string) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	// Maps are created lazily: trees without labels pay nothing
+	if li.byLabel == nil {
+		li.byLabel = map[string]map[*PointOf[T]]struct{}{}
+		li.byPoint = map[*PointOf[T]][]string{}
+	}
+
+	li.dropLocked(p)
+	if len(labels) == 0 {
+		return
+	}
+
+	// Keep our own copy, the caller may reuse its slice
+	li.byPoint[p] = append([]string(nil), labels...)
+	for _, label := range labels {
+		set, ok := li.byLabel[label]
+		if !ok {
+			set = map[*PointOf[T]]struct{}{}
+			li.byLabel[label] = set
+		}
+		set[p] = struct{}{}
+	}
+}
+
+// drop removes every label of p from the index
+func (li *labelIndex[T]) drop(p *PointOf[T]) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	li.dropLocked(p)
+}
+
+// dropLocked is drop for callers already holding the Write Lock
+func (li *labelIndex[T]) dropLocked(p *PointOf[T]) {
+	for _, label := range li.byPoint[p] {
+		set := li.byLabel[label]
+		delete(set, p)
+		// Don't keep empty sets around
+		if len(set) == 0 {
+			delete(li.byLabel, label)
+		}
+	}
+	delete(li.byPoint, p)
+}
+
+// move hands the labels of 'from' over to 'to' (used by Update)
+func (li *labelIndex[T]) move(from, to *PointOf[T]) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	labels, ok := li.byPoint[from]
+	if !ok {
+		return
+	}
+	li.dropLocked(from)
+	li.byPoint[to] = labels
+	for _, label := range labels {
+		set, ok := li.byLabel[label]
+		if !ok {
+			set = map[*PointOf[T]]struct{}{}
+			li.byLabel[label] = set
+		}
+		set[to] = struct{}{}
+	}
+}
+
+// reset empties the index
+func (li *labelIndex[T]) reset() {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	li.byLabel = nil
+	li.byPoint = nil
+}
+
+// matches checks the label filters for p (the caller holds the Read Lock).
+// p must carry at least one of anyOf (if not empty) and all of allOf.
+func (li *labelIndex[T]) matches(p *PointOf[T], anyOf, allOf []string) bool {
+	for _, label := range allOf {
+		if _, ok := li.byLabel[label][p]; !ok {
+			return false
+		}
+	}
+	if len(anyOf) == 0 {
+		return true
+	}
+	for _, label := range anyOf {
+		if _, ok := li.byLabel[label][p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertWithLabels inserts p and attaches the given labels to it.
+// The labels follow the point: they are dropped when the point is removed,
+// so a driver that moves (Remove + Insert) must be inserted with its labels again.
+func (qt *QuadTreeOf[T]) InsertWithLabels(p *PointOf[T], labels ...string) bool {
+	if !qt.Insert(p) {
+		return false
+	}
+	qt.labels.set(p, labels)
+	return true
+}
+
+// SetLabels replaces the labels of a point already stored in the tree
+// (p must be the same pointer that was inserted)
+func (qt *QuadTreeOf[T]) SetLabels(p *PointOf[T], labels ...string) {
+	qt.labels.set(p, labels)
+}
+
+// SetLabelsByID replaces the labels of the point whose Data is id (see GetByID).
+// Unlike SetLabels it can't hit a stale pointer: the ID index lock keeps
+// the point from being moved or removed meanwhile. It returns false if
+// there is no such point (or no ID index).
+func (qt *QuadTreeOf[T]) SetLabelsByID(id T, labels ...string) bool {
+	if qt.ids == nil {
+		return false
+	}
+	qt.ids.mu.Lock()
+	defer qt.ids.mu.Unlock()
+
+	p, ok := qt.ids.get(id)
+	if !ok {
+		return false
+	}
+	qt.labels.set(p, labels)
+	return true
+}
+
+// Labels returns the labels attached to p (nil if it has none)
+func (qt *QuadTreeOf[T]) Labels(p *PointOf[T]) []string {
+	qt.labels.mu.RLock()
+	defer qt.labels.mu.RUnlock()
+	return append([]string(nil), qt.labels.byPoint[p]...)
+}
+
+// QueryWithLabels finds the points within rangeRect that carry
+// at least one of the anyOf labels and all of the allOf labels.
+// An empty anyOf or allOf means "no constraint" for that filter,
+// e.g. QueryWithLabels(area, nil, []string{"available", "premium"}).
+func (qt *QuadTreeOf[T]) QueryWithLabels(rangeRect *Boundary, anyOf, allOf []string) []*PointOf[T] {
+	// Spatial filter first...
+	candidates := qt.Query(rangeRect)
+
+	// ...then the label filter, using the inverted index
+	qt.labels.mu.RLock()
+	defer qt.labels.mu.RUnlock()
+
+	found := candidates[:0]
+	for _, p := range candidates {
+		if qt.labels.matches(p, anyOf, allOf) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
@@ -0,0 +1,86 @@
+package quadtree // Tests for the label tagging
+
+import "testing"
+
+// TestQuadTreeQueryWithLabels verifies the anyOf/allOf semantics
+// combined with the spatial filter.
+func TestQuadTreeQueryWithLabels(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	qt.InsertWithLabels(&Point{X: 10, Y: 10, Data: "a"}, "available", "premium")
+	qt.InsertWithLabels(&Point{X: 20, Y: 20, Data: "b"}, "available")
+	qt.InsertWithLabels(&Point{X: 30, Y: 30, Data: "c"}, "busy", "premium")
+	qt.InsertWithLabels(&Point{X: 40, Y: 40, Data: "d"})                           // No labels
+	qt.InsertWithLabels(&Point{X: -50, Y: -50, Data: "e"}, "available", "premium") // Outside the area
+
+	area := &Boundary{X: 25, Y: 25, Width: 25, Height: 25}
+
+	cases := []struct {
+		name     string
+		anyOf    []string
+		allOf    []string
+		expected []string
+	}{
+		{"no filter", nil, nil, []string{"a", "b", "c", "d"}},
+		{"anyOf available", []string{"available"}, nil, []string{"a", "b"}},
+		{"anyOf available|busy", []string{"available", "busy"}, nil, []string{"a", "b", "c"}},
+		{"allOf available+premium", nil, []string{"available", "premium"}, []string{"a"}},
+		{"anyOf busy|available + allOf premium", []string{"busy", "available"}, []string{"premium"}, []string{"a", "c"}},
+		{"unknown label", []string{"offline"}, nil, nil},
+	}
+
+	for _, tc := range cases {
+		found := dataOf(qt.QueryWithLabels(area, tc.anyOf, tc.allOf))
+		if len(found) != len(tc.expected) {
+			t.Errorf("%s: %v expected, got %v", tc.name, tc.expected, found)
+			continue
+		}
+		for i := range found {
+			if found[i] != tc.expected[i] {
+				t.Errorf("%s: %v expected, got %v", tc.name, tc.expected, found)
+				break
+			}
+		}
+	}
+
+	// --- The index follows Remove ---
+	if !qt.Remove(&Point{X: 10, Y: 10, Data: "a"}) {
+		t.Fatal("Remove of point a failed")
+	}
+	if found := qt.QueryWithLabels(area, nil, []string{"premium"}); len(found) != 1 || found[0].Data != "c" {
+		t.Errorf("After Remove: only c expected to be premium, got %v", dataOf(found))
+	}
+	if _, ok := qt.labels.byLabel["available"]; !ok || len(qt.labels.byPoint) != 3 {
+		t.Errorf("After Remove: label index not in sync (%d labelled points)", len(qt.labels.byPoint))
+	}
+}
+
+// TestQuadTreeSetLabelsByID verifies that labels set by ID follow the point
+// through its moves
+func TestQuadTreeSetLabelsByID(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2, WithIDIndex())
+	qt.Insert(&PointOf[string]{X: 10, Y: 10, Data: "a"})
+
+	// --- Test 1: set, then replaced ---
+	if !qt.SetLabelsByID("a", "available") || !qt.SetLabelsByID("a", "busy") {
+		t.Fatal("SetLabelsByID of a registered ID failed")
+	}
+	p, _ := qt.GetByID("a")
+	if labels := qt.Labels(p); len(labels) != 1 || labels[0] != "busy" {
+		t.Errorf("[busy] expected, got %v", labels)
+	}
+
+	// --- Test 2: the labels follow MoveByID ---
+	moved := qt.MoveByID("a", 50, 50)
+	if labels := qt.Labels(moved); len(labels) != 1 || labels[0] != "busy" {
+		t.Errorf("After the move: [busy] expected, got %v", labels)
+	}
+
+	// --- Test 3: unknown ID, or a tree without ID index ---
+	if qt.SetLabelsByID("nobody", "busy") {
+		t.Error("SetLabelsByID of an unknown ID must fail")
+	}
+	if NewQuadTreeOf[string](Boundary{Width: 1, Height: 1}, 2).SetLabelsByID("a", "busy") {
+		t.Error("SetLabelsByID without an ID index must fail")
+	}
+}
@@ -0,0 +1,46 @@
+package quadtree // Leaf storage of the QuadTree
+
+// A leaf keeps its points twice: 'points' holds the pointers handed out by
+// the queries, 'xy' holds their coordinates side by side in one contiguous
+// array (X of points[i] at xy[2*i], Y at xy[2*i+1]).
+// The scans of the queries test the coordinates in 'xy' and only follow
+// the pointer of a point that matched, so rejecting the points outside
+// the area never touches the scattered Point structs.
+// The tree never changes a stored point (Update inserts a new one),
+// so 'xy' can't go stale. Remove still matches a point by its value
+// (X, Y, Data), not by pointer: see remove.
+
+// appendPoint adds p to this leaf
+func (qt *QuadTreeOf[T]) appendPoint(p *PointOf[T]) {
+	qt.points = append(qt.points, p)
+	qt.xy = append(qt.xy, p.X, p.Y)
+}
+
+// deletePoint removes points[i] from this leaf in O(1) ("swap and pop":
+// the last point takes its place, so the order is not kept)
+func (qt *QuadTreeOf[T]) deletePoint(i int) {
+	last := len(qt.points) - 1
+	qt.points[i] = qt.points[last]
+	qt.xy[2*i], qt.xy[2*i+1] = qt.xy[2*last], qt.xy[2*last+1]
+	// Drop the duplicated pointer, so the point can be freed
+	qt.points[last] = nil
+	qt.points = qt.points[:last]
+	qt.xy = qt.xy[:2*last]
+}
+
+// resetPoints empties this leaf, keeping the backing arrays
+func (qt *QuadTreeOf[T]) resetPoints() {
+	// Drop the pointers so the points can be freed
+	clear(qt.points)
+	qt.points = qt.points[:0]
+	qt.xy = qt.xy[:0]
+}
+
+// setPoints replaces the content of this leaf with 'points'
+func (qt *QuadTreeOf[T]) setPoints(points []*PointOf[T]) {
+	qt.points = append(make([]*PointOf[T], 0, max(len(points), qt.capacity)), points...)
+	qt.xy = make([]float64, 0, 2*cap(qt.points))
+	for _, p := range points {
+		qt.xy = append(qt.xy, p.X, p.Y)
+	}
+}
@@ -0,0 +1,88 @@
+package quadtree // Tests for the leaf storage
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkLeafCoords fails if the 'xy' array of a leaf of qt doesn't match its points
+func checkLeafCoords[T comparable](t *testing.T, name string, qt *QuadTreeOf[T]) {
+	t.Helper()
+	qt.forEachNode(func(node *QuadTreeOf[T]) {
+		if node.northWest != nil {
+			return
+		}
+		if len(node.xy) != 2*len(node.points) {
+			t.Fatalf("%s: leaf at depth %d has %d coordinates for %d points", name, node.depth, len(node.xy), len(node.points))
+		}
+		for i, p := range node.points {
+			if node.xy[2*i] != p.X || node.xy[2*i+1] != p.Y {
+				t.Fatalf("%s: leaf at depth %d: coordinates of point %d out of sync", name, node.depth, i)
+			}
+		}
+	})
+}
+
+// forEachNode calls fn on every node of the subtree (test helper, no locks)
+func (qt *QuadTreeOf[T]) forEachNode(fn func(*QuadTreeOf[T])) {
+	fn(qt)
+	if qt.northWest != nil {
+		for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+			child.forEachNode(fn)
+		}
+	}
+}
+
+// TestQuadTreeLeafCoords runs every operation that changes the leaves
+// and checks that the coordinates stay in sync with the points
+func TestQuadTreeLeafCoords(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTreeOf[int](world, 4, WithIDIndex(), WithPooling())
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*PointOf[int], 0, 2000)
+	for i := 0; i < 2000; i++ {
+		p := &PointOf[int]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i}
+		points = append(points, p)
+		qt.Insert(p)
+	}
+	checkLeafCoords(t, "Insert", qt)
+
+	// --- Remove and Update ---
+	for i := 0; i < 500; i++ {
+		qt.Remove(points[i])
+	}
+	for i := 500; i < 1000; i++ {
+		qt.Update(points[i], points[i].X/2, points[i].Y/2)
+	}
+	checkLeafCoords(t, "Remove/Update", qt)
+
+	// --- RemoveInArea, Clone, Rebuild ---
+	qt.RemoveInArea(&Boundary{X: 0, Y: 0, Width: 45, Height: 20})
+	checkLeafCoords(t, "RemoveInArea", qt)
+	checkLeafCoords(t, "Clone", qt.Clone())
+	qt.Rebuild()
+	checkLeafCoords(t, "Rebuild", qt)
+
+	// --- BuildQuadTree and JSON ---
+	built := BuildQuadTree[int](world, 4, points)
+	checkLeafCoords(t, "BuildQuadTree", built)
+	data, err := built.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+	loaded, err := LoadQuadTreeOf[int](data)
+	if err != nil {
+		t.Fatalf("LoadQuadTreeOf: %v", err)
+	}
+	checkLeafCoords(t, "LoadQuadTreeOf", loaded)
+
+	// --- Clear, then reuse of the pooled nodes ---
+	qt.Clear()
+	for _, p := range points[:300] {
+		qt.Insert(&PointOf[int]{X: p.X, Y: p.Y, Data: p.Data})
+	}
+	checkLeafCoords(t, "Clear+Insert", qt)
+	if found := qt.Query(&world); len(found) != 300 {
+		t.Errorf("Query after Clear: 300 points expected, got %d", len(found))
+	}
+}
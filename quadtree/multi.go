@@ -0,0 +1,78 @@
+package quadtree // Several range queries in a single traversal
+
+import "time" // Import time package (the share of each rect in the search time)
+
+// QueryMulti runs a Query for each of the rects in a single walk of the
+// tree: result i holds the points of rects[i], in the same order Query
+// would return them. At each node only the rects that still intersect it
+// are tested, so close rects (e.g. the tiles of a map viewport) share the
+// descent instead of repeating it. A nil rect finds nothing.
+// Since the walk is done once, all the results come from the same
+// view of every node.
+func (qt *QuadTreeOf[T]) QueryMulti(rects []*Boundary) [][]*PointOf[T] {
+	began := qt.searchStart()
+	found := make([][]*PointOf[T], len(rects))
+	active := make([]int, 0, len(rects))
+	for i, r := range rects {
+		found[i] = []*PointOf[T]{}
+		if r != nil {
+			active = append(active, i)
+		}
+	}
+	if len(active) > 0 {
+		// Expired points (see InsertWithTTL) are left out
+		qt.queryMultiRecursive(rects, active, qt.expiry.live(nil), found)
+	}
+	// One search per rect, as if each had its own Query
+	// (taking its share of the time)
+	if qt.observer != nil && len(found) > 0 {
+		took := time.Since(began) / time.Duration(len(found))
+		for _, f := range found {
+			qt.observer.Searched(len(f), took)
+		}
+	}
+	return found
+}
+
+// queryMultiRecursive is queryRecursive for several rects: active holds
+// the indexes of the rects that intersect the parent node, and keep (if
+// not nil) the points to return
+func (qt *QuadTreeOf[T]) queryMultiRecursive(rects []*Boundary, active []int, keep func(*PointOf[T]) bool, found [][]*PointOf[T]) {
+	qt.rlock()
+	defer qt.runlock()
+
+	// Keep only the rects that overlap this node
+	// (on the stack for the usual handful of rects)
+	var buf [16]int
+	here := buf[:0]
+	for _, i := range active {
+		if qt.intersects(rects[i]) {
+			here = append(here, i)
+		}
+	}
+	if len(here) == 0 {
+		return
+	}
+
+	// Leaf: each point is read once and tested against the remaining rects
+	if qt.northWest == nil {
+		for j, p := range qt.points {
+			if keep != nil && !keep(p) {
+				continue
+			}
+			x, y := qt.xy[2*j], qt.xy[2*j+1]
+			for _, i := range here {
+				if rects[i].ContainsXY(x, y) {
+					found[i] = append(found[i], p)
+				}
+			}
+		}
+		return
+	}
+
+	// Same order as queryRecursive, so each result matches Query
+	qt.northWest.queryMultiRecursive(rects, here, keep, found)
+	qt.northEast.queryMultiRecursive(rects, here, keep, found)
+	qt.southWest.queryMultiRecursive(rects, here, keep, found)
+	qt.southEast.queryMultiRecursive(rects, here, keep, found)
+}
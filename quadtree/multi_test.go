@@ -0,0 +1,64 @@
+package quadtree // Tests for the multi-rect query
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreeQueryMulti verifies that QueryMulti returns, for every rect,
+// exactly what a separate Query returns, in the same order
+func TestQuadTreeQueryMulti(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 2000; i++ {
+		qt.Insert(&Point{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: i})
+	}
+
+	// --- Test 1: neighbouring tiles, an overlapping one, one outside the tree, a nil ---
+	rects := []*Boundary{
+		{X: 5, Y: 5, Width: 5, Height: 5},
+		{X: 15, Y: 5, Width: 5, Height: 5},
+		{X: 10, Y: 5, Width: 8, Height: 2},
+		{X: 500, Y: 500, Width: 1, Height: 1},
+		nil,
+		{X: -60, Y: 40, Width: 30, Height: 30},
+	}
+	results := qt.QueryMulti(rects)
+	if len(results) != len(rects) {
+		t.Fatalf("%d results expected, got %d", len(rects), len(results))
+	}
+	for i, r := range rects {
+		var expected []*Point
+		if r != nil {
+			expected = qt.Query(r)
+		}
+		if len(results[i]) != len(expected) {
+			t.Errorf("Rect %d: %d points expected, got %d", i, len(expected), len(results[i]))
+			continue
+		}
+		for j := range expected {
+			if results[i][j] != expected[j] {
+				t.Errorf("Rect %d: point %d differs from Query", i, j)
+				break
+			}
+		}
+	}
+
+	// --- Test 2: more rects than the stack buffer ---
+	many := make([]*Boundary, 20)
+	for i := range many {
+		many[i] = &Boundary{X: float64(i*10 - 95), Y: 0, Width: 5, Height: 100}
+	}
+	total := 0
+	for _, found := range qt.QueryMulti(many) {
+		total += len(found)
+	}
+	if total != 2000 {
+		t.Errorf("20 strips covering the tree: 2000 points expected, got %d", total)
+	}
+
+	// --- Test 3: no rects ---
+	if results := qt.QueryMulti(nil); len(results) != 0 {
+		t.Errorf("No rects: no results expected, got %d", len(results))
+	}
+}
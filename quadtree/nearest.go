@@ -0,0 +1,212 @@
+package quadtree // Nearest-neighbor search on the QuadTree
+
+import (
+	"math" // Import math package (Inf, Max, Sqrt)
+)
+
+// NearestNeighbor returns the point closest to center (Euclidean distance
+// in degree space, or the tree's DistanceFunc), or nil if the tree is empty.
+// It is a branch-and-bound search: children are visited closest-first and
+// a whole subtree is skipped when its boundary is farther than the best match.
+func (qt *QuadTreeOf[T]) NearestNeighbor(center *PointOf[T]) *PointOf[T] {
+	best, _ := qt.nearest(center.X, center.Y, qt.distance)
+	return best
+}
+
+// Nearest returns the point closest to target, its distance (Euclidean,
+// in degree space) and true. On an empty tree it returns nil, 0 and false.
+// It is the same branch-and-bound search as NearestNeighbor.
+// With a DistanceFunc (WithDistance), the distance is the one it returns.
+func (qt *QuadTreeOf[T]) Nearest(target *PointOf[T]) (*PointOf[T], float64, bool) {
+	return qt.NearestFunc(target, nil)
+}
+
+// NearestFunc is Nearest measuring the distances with fn (nil: the tree's
+// DistanceFunc). The distance returned is the one fn returns.
+func (qt *QuadTreeOf[T]) NearestFunc(target *PointOf[T], fn DistanceFunc) (*PointOf[T], float64, bool) {
+	fn = qt.distanceFunc(fn)
+	best, dist := qt.nearest(target.X, target.Y, fn)
+	if best == nil {
+		return nil, 0, false
+	}
+	// The default distance is squared: return the real one
+	if fn == nil {
+		dist = math.Sqrt(dist)
+	}
+	return best, dist, true
+}
+
+// NearestFilter is NearestFunc skipping the points for which keep returns
+// false, e.g. the closest driver that is available. A nil keep keeps every
+// point. keep is called under the Read Lock of the leaf: it must not
+// change the tree.
+func (qt *QuadTreeOf[T]) NearestFilter(target *PointOf[T], fn DistanceFunc, keep func(*PointOf[T]) bool) (*PointOf[T], float64, bool) {
+	fn = qt.distanceFunc(fn)
+	best, dist := qt.nearestWhere(target.X, target.Y, fn, keep)
+	if best == nil {
+		return nil, 0, false
+	}
+	if fn == nil {
+		dist = math.Sqrt(dist)
+	}
+	return best, dist, true
+}
+
+// nearest returns the point closest to (x, y) and its distance (squared
+// without fn), or nil and +Inf if the tree is empty
+func (qt *QuadTreeOf[T]) nearest(x, y float64, fn DistanceFunc) (*PointOf[T], float64) {
+	return qt.nearestWhere(x, y, fn, nil)
+}
+
+// nearestWhere is nearest among the points kept by keep (nil: all of them).
+// Expired points (see InsertWithTTL) are skipped.
+func (qt *QuadTreeOf[T]) nearestWhere(x, y float64, fn DistanceFunc, keep func(*PointOf[T]) bool) (*PointOf[T], float64) {
+	began := qt.searchStart()
+	keep = qt.expiry.live(keep)
+	var best *PointOf[T]
+	bestDist := math.Inf(1)
+	qt.nearestRecursive(x, y, fn, keep, &best, &bestDist)
+	found := 0
+	if best != nil {
+		found = 1
+	}
+	qt.observeSearch(began, found)
+	return best, bestDist
+}
+
+// nearestRecursive is the internal helper that performs the branch-and-bound search
+func (qt *QuadTreeOf[T]) nearestRecursive(x, y float64, fn DistanceFunc, keep func(*PointOf[T]) bool, best **PointOf[T], bestDist *float64) {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// --- The Bound ---
+	// If even the closest spot of this node is farther than
+	// the best match found so far, nothing in here can beat it
+	if qt.minDist(x, y, fn) >= *bestDist {
+		return
+	}
+
+	// If this is a "leaf" node, check every point in its list
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			if d := qt.pointDist(i, x, y, fn); d < *bestDist && (keep == nil || keep(p)) {
+				*best = p
+				*bestDist = d
+			}
+		}
+		return
+	}
+
+	// If this is a "parent" node, visit the children closest-first:
+	// finding a good match early lets the bound prune the others
+	children := [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast}
+	dists := [4]float64{}
+	for i, child := range children {
+		dists[i] = child.minDist(x, y, fn)
+	}
+	// Insertion sort: there are only four children
+	for i := 1; i < 4; i++ {
+		for j := i; j > 0 && dists[j] < dists[j-1]; j-- {
+			dists[j], dists[j-1] = dists[j-1], dists[j]
+			children[j], children[j-1] = children[j-1], children[j]
+		}
+	}
+	for _, child := range children {
+		child.nearestRecursive(x, y, fn, keep, best, bestDist)
+	}
+}
+
+// neighbor is a candidate of the k-nearest search
+type neighbor[T comparable] struct {
+	p    *PointOf[T]
+	dist float64 // Squared, with the default distance
+}
+
+// QueryKNearest returns the k points closest to center (Euclidean distance
+// in degree space, or the tree's DistanceFunc), closest first. It returns
+// fewer points if the tree holds fewer than k, and none if k <= 0.
+// Like NearestNeighbor, it is a branch-and-bound search: once k candidates
+// are found, the subtrees farther than the k-th one are skipped.
+func (qt *QuadTreeOf[T]) QueryKNearest(center *PointOf[T], k int) []*PointOf[T] {
+	return qt.QueryKNearestFunc(center, k, nil)
+}
+
+// QueryKNearestFunc is QueryKNearest measuring the distances with fn
+// (nil: the tree's DistanceFunc)
+func (qt *QuadTreeOf[T]) QueryKNearestFunc(center *PointOf[T], k int, fn DistanceFunc) []*PointOf[T] {
+	began := qt.searchStart()
+	if k <= 0 {
+		return []*PointOf[T]{}
+	}
+
+	best := make([]neighbor[T], 0, k)
+	// Expired points (see InsertWithTTL) are skipped
+	qt.kNearestRecursive(center.X, center.Y, k, qt.distanceFunc(fn), qt.expiry.live(nil), &best)
+
+	found := make([]*PointOf[T], len(best))
+	for i, n := range best {
+		found[i] = n.p
+	}
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// kNearestRecursive is the internal helper of QueryKNearest.
+// 'best' holds the candidates found so far, sorted by distance (at most k),
+// among the points accepted by keep (nil: all of them).
+func (qt *QuadTreeOf[T]) kNearestRecursive(x, y float64, k int, fn DistanceFunc, keep func(*PointOf[T]) bool, best *[]neighbor[T]) {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// --- The Bound ---
+	// Once we have k candidates, a node farther than the k-th can't improve them
+	if len(*best) == k && qt.minDist(x, y, fn) >= (*best)[k-1].dist {
+		return
+	}
+
+	// If this is a "leaf" node, offer every point to the candidates
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			d := qt.pointDist(i, x, y, fn)
+			if len(*best) == k && d >= (*best)[k-1].dist || keep != nil && !keep(p) {
+				continue
+			}
+			// Insertion sort: k is small, and the list is already sorted
+			if len(*best) < k {
+				*best = append(*best, neighbor[T]{})
+			}
+			i := len(*best) - 1
+			for ; i > 0 && (*best)[i-1].dist > d; i-- {
+				(*best)[i] = (*best)[i-1]
+			}
+			(*best)[i] = neighbor[T]{p: p, dist: d}
+		}
+		return
+	}
+
+	// If this is a "parent" node, visit the children closest-first
+	children := [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast}
+	dists := [4]float64{}
+	for i, child := range children {
+		dists[i] = child.minDist(x, y, fn)
+	}
+	for i := 1; i < 4; i++ {
+		for j := i; j > 0 && dists[j] < dists[j-1]; j-- {
+			dists[j], dists[j-1] = dists[j-1], dists[j]
+			children[j], children[j-1] = children[j-1], children[j]
+		}
+	}
+	for _, child := range children {
+		child.kNearestRecursive(x, y, k, fn, keep, best)
+	}
+}
+
+// minDistSq returns the squared distance from (x, y) to the closest
+// spot of this node (0 if the coordinates are inside it)
+func (qt *QuadTreeOf[T]) minDistSq(x, y float64) float64 {
+	dx := math.Max(math.Max(qt.minX-x, 0), x-qt.maxX)
+	dy := math.Max(math.Max(qt.minY-y, 0), y-qt.maxY)
+	return dx*dx + dy*dy
+}
@@ -0,0 +1,175 @@
+package quadtree // Tests for the nearest-neighbor search
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// TestQuadTreeNearestNeighbor verifies NearestNeighbor
+// against a brute-force linear scan.
+func TestQuadTreeNearestNeighbor(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+
+	// --- Test 1: An empty tree has no nearest neighbor ---
+	if p := qt.NearestNeighbor(&Point{X: 0, Y: 0}); p != nil {
+		t.Fatalf("NearestNeighbor on empty tree: nil expected, got %v", p.Data)
+	}
+
+	// Populate the tree with random points
+	rng := rand.New(rand.NewSource(7))
+	points := make([]*Point, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		p := &Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: i}
+		points = append(points, p)
+		qt.Insert(p)
+	}
+
+	// --- Test 2: The result matches the linear scan ---
+	for i := 0; i < 100; i++ {
+		center := &Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90}
+
+		var expected *Point
+		bestDist := -1.0
+		for _, p := range points {
+			dx, dy := p.X-center.X, p.Y-center.Y
+			if d := dx*dx + dy*dy; bestDist < 0 || d < bestDist {
+				expected, bestDist = p, d
+			}
+		}
+
+		if got := qt.NearestNeighbor(center); got != expected {
+			t.Fatalf("NearestNeighbor(%v, %v): %v expected, got %v", center.X, center.Y, expected.Data, got.Data)
+		}
+	}
+}
+
+// TestQuadTreeNearest verifies the distance and the bool of Nearest
+func TestQuadTreeNearest(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+
+	// --- Test 1: An empty tree reports false ---
+	if p, d, ok := qt.Nearest(&Point{X: 0, Y: 0}); ok || p != nil || d != 0 {
+		t.Fatalf("Nearest on empty tree: nil, 0, false expected, got %v, %v, %v", p, d, ok)
+	}
+
+	// --- Test 2: The closest point and its distance ---
+	qt.Insert(&Point{X: 3, Y: 4, Data: "a"})
+	qt.Insert(&Point{X: -10, Y: -10, Data: "b"})
+	qt.Insert(&Point{X: 50, Y: 50, Data: "c"})
+	p, d, ok := qt.Nearest(&Point{X: 0, Y: 0})
+	if !ok || p.Data != "a" || d != 5 {
+		t.Errorf("Nearest(0, 0): a at distance 5 expected, got %v at %v (%v)", p.Data, d, ok)
+	}
+}
+
+// TestQuadTreeQueryKNearest verifies QueryKNearest
+// against a brute-force sort of every point.
+func TestQuadTreeQueryKNearest(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+	points := make([]*Point, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		p := randomWorldPoint(rng, i)
+		qt.Insert(p)
+		points = append(points, p)
+	}
+
+	distSq := func(p, c *Point) float64 {
+		return (p.X-c.X)*(p.X-c.X) + (p.Y-c.Y)*(p.Y-c.Y)
+	}
+
+	// --- Test 1: same distances as a brute-force sort ---
+	for i := 0; i < 50; i++ {
+		center := randomWorldPoint(rng, -1)
+		sorted := append([]*Point(nil), points...)
+		sort.Slice(sorted, func(a, b int) bool { return distSq(sorted[a], center) < distSq(sorted[b], center) })
+
+		found := qt.QueryKNearest(center, 10)
+		if len(found) != 10 {
+			t.Fatalf("QueryKNearest: 10 points expected, %d found", len(found))
+		}
+		for j, p := range found {
+			if distSq(p, center) != distSq(sorted[j], center) {
+				t.Fatalf("Center %v: neighbor %d at distance² %v, expected %v",
+					*center, j, distSq(p, center), distSq(sorted[j], center))
+			}
+		}
+	}
+
+	// --- Test 2: k larger than the tree, and k <= 0 ---
+	small := NewQuadTree(Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 2)
+	small.Insert(&Point{X: 1, Y: 1})
+	small.Insert(&Point{X: 5, Y: 5})
+	if found := small.QueryKNearest(&Point{}, 5); len(found) != 2 || found[0].X != 1 {
+		t.Errorf("k > points: the 2 points, closest first, expected, got %d", len(found))
+	}
+	if found := small.QueryKNearest(&Point{}, 0); found == nil || len(found) != 0 {
+		t.Errorf("k = 0: empty slice expected, got %v", found)
+	}
+}
+
+// TestQuadTreeNearestAntimeridian verifies that the great-circle searches
+// find the closest point across the antimeridian, and that they agree
+// with a brute-force scan anywhere on the globe
+func TestQuadTreeNearestAntimeridian(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	qt.Insert(&PointOf[string]{X: 179.95, Y: 0, Data: "east"})
+	for i := 0; i < 20; i++ { // Forces the subdivisions
+		qt.Insert(&PointOf[string]{X: -170, Y: float64(i) / 10, Data: "filler"})
+	}
+	target := &PointOf[string]{X: -179.95, Y: 0}
+
+	// --- Test 1: The point 11 km away beats the ones 1106 km away ---
+	p, d, ok := qt.NearestFunc(target, HaversineDistance)
+	if !ok || p.Data != "east" || d > 12 {
+		t.Errorf("NearestFunc across the antimeridian: east at ~11 km expected, got %v at %.0f km", p.Data, d)
+	}
+	if found := qt.QueryKNearestFunc(target, 1, HaversineDistance); len(found) != 1 || found[0].Data != "east" {
+		t.Errorf("QueryKNearestFunc across the antimeridian: [east] expected, got %v", found)
+	}
+
+	// --- Test 2: Same distance as a brute-force scan, anywhere ---
+	world := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(3))
+	points := make([]*PointOf[int], 2000)
+	for i := range points {
+		points[i] = &PointOf[int]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i}
+		world.Insert(points[i])
+	}
+	for n := 0; n < 500; n++ {
+		x, y := rng.Float64()*360-180, rng.Float64()*180-90
+		expected := HaversineDistance(x, y, points[0].X, points[0].Y)
+		for _, p := range points[1:] {
+			expected = min(expected, HaversineDistance(x, y, p.X, p.Y))
+		}
+		if _, d, _ := world.NearestFunc(&PointOf[int]{X: x, Y: y}, HaversineDistance); d != expected {
+			t.Fatalf("NearestFunc(%v, %v): %v km expected, got %v km", x, y, expected, d)
+		}
+	}
+}
+
+// TestQuadTreeNearestFilter verifies that NearestFilter skips the points
+// that are not kept, however close they are
+func TestQuadTreeNearestFilter(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	for i := 0; i < 100; i++ {
+		qt.Insert(&PointOf[int]{X: float64(i), Y: 0, Data: i})
+	}
+	target := &PointOf[int]{X: 0, Y: 0}
+	odd := func(p *PointOf[int]) bool { return p.Data%2 == 1 }
+
+	// --- Test 1: the closest kept point, not the closest one ---
+	if p, d, ok := qt.NearestFilter(target, nil, odd); !ok || p.Data != 1 || d != 1 {
+		t.Errorf("NearestFilter(odd): 1 at distance 1 expected, got %v at %v (%v)", p, d, ok)
+	}
+
+	// --- Test 2: nil keeps everything, nothing kept reports false ---
+	if p, _, ok := qt.NearestFilter(target, nil, nil); !ok || p.Data != 0 {
+		t.Errorf("NearestFilter(nil): 0 expected, got %v (%v)", p, ok)
+	}
+	none := func(*PointOf[int]) bool { return false }
+	if p, _, ok := qt.NearestFilter(target, HaversineDistance, none); ok || p != nil {
+		t.Errorf("NearestFilter(none): nil, false expected, got %v, %v", p, ok)
+	}
+}
@@ -0,0 +1,71 @@
+package quadtree // Hooks to watch what a tree does, e.g. for metrics
+
+import "time" // Import time package (the duration of the searches)
+
+// Observer is told about the changes and the searches of a tree created
+// WithObserver, e.g. to count them in Prometheus. Its methods are called
+// once the operation is done, from the goroutine that ran it, possibly
+// under the ID index lock: they must be fast, safe for concurrent use,
+// and must not call back into the tree.
+type Observer interface {
+	// Inserted is called with the number of points added
+	// (Insert and the functions built on it, BatchInsert)
+	Inserted(n int)
+	// Removed is called with the number of points taken out
+	// (Remove, RemoveByID, RemoveInArea, RemoveExpired, Clear)
+	Removed(n int)
+	// Moved is called for every point replaced by a new one
+	// (Update, MoveByID, UpdateByID, ReplaceByID)
+	Moved()
+	// Searched is called once per search returning points, with the
+	// number of points it found and how long it took (the counts are
+	// not searches)
+	Searched(found int, took time.Duration)
+}
+
+// WithObserver makes the tree report its operations to o (a nil o
+// reports nothing). The copies of the tree (Clone, Snapshot) and the
+// trees built on the side by Rebuild and SetCapacity don't report:
+// only what happens to the points of this tree does.
+func WithObserver(o Observer) Option {
+	return func(opts *options) {
+		opts.observer = o
+	}
+}
+
+// observeInsert reports n points added
+func (qt *QuadTreeOf[T]) observeInsert(n int) {
+	if qt.observer != nil && n > 0 {
+		qt.observer.Inserted(n)
+	}
+}
+
+// observeRemove reports n points taken out
+func (qt *QuadTreeOf[T]) observeRemove(n int) {
+	if qt.observer != nil && n > 0 {
+		qt.observer.Removed(n)
+	}
+}
+
+// observeMove reports a point replaced by a new one
+func (qt *QuadTreeOf[T]) observeMove() {
+	if qt.observer != nil {
+		qt.observer.Moved()
+	}
+}
+
+// searchStart returns the start time of a search, to be passed to
+// observeSearch (the zero time without an observer: nobody needs it)
+func (qt *QuadTreeOf[T]) searchStart() time.Time {
+	if qt.observer == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// observeSearch reports a search started at began that found n points
+func (qt *QuadTreeOf[T]) observeSearch(began time.Time, n int) {
+	if qt.observer != nil {
+		qt.observer.Searched(n, time.Since(began))
+	}
+}
@@ -0,0 +1,91 @@
+package quadtree // Tests for the operation observer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingObserver adds up what it is told
+type countingObserver struct {
+	mu                       sync.Mutex
+	inserted, removed, moved int
+	searches, found          int
+	took                     time.Duration
+}
+
+func (o *countingObserver) Inserted(n int) { o.mu.Lock(); o.inserted += n; o.mu.Unlock() }
+func (o *countingObserver) Removed(n int)  { o.mu.Lock(); o.removed += n; o.mu.Unlock() }
+func (o *countingObserver) Moved()         { o.mu.Lock(); o.moved++; o.mu.Unlock() }
+func (o *countingObserver) Searched(n int, took time.Duration) {
+	o.mu.Lock()
+	o.searches++
+	o.found += n
+	o.took += took
+	o.mu.Unlock()
+}
+
+// TestQuadTreeObserver verifies that the changes and the searches are
+// reported once each, whichever function makes them
+func TestQuadTreeObserver(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	obs := &countingObserver{}
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2,
+		WithIDIndex(), WithClock(clock.Now), WithObserver(obs))
+
+	// --- Test 1: inserts ---
+	for i := 0; i < 10; i++ {
+		qt.Insert(&PointOf[int]{X: float64(i), Y: float64(i), Data: i})
+	}
+	qt.Insert(&PointOf[int]{X: 500, Y: 0, Data: 99}) // Outside: not inserted
+	qt.BatchInsert([]*PointOf[int]{{X: -1, Y: -1, Data: 10}, {X: -2, Y: -2, Data: 11}})
+	qt.InsertWithTTL(&PointOf[int]{X: -3, Y: -3, Data: 12}, time.Minute)
+	if obs.inserted != 13 {
+		t.Errorf("13 inserts expected, got %d", obs.inserted)
+	}
+
+	// --- Test 2: moves ---
+	p, _ := qt.GetByID(0)
+	qt.Update(p, 50, 50)
+	qt.MoveByID(1, 51, 51)
+	qt.UpdateByID(2, func(cur PointOf[int]) PointOf[int] { cur.X = 52; return cur })
+	qt.MoveByID(42, 0, 0) // Unknown: nothing moved
+	if obs.moved != 3 {
+		t.Errorf("3 moves expected, got %d", obs.moved)
+	}
+
+	// --- Test 3: searches, once per call ---
+	area := &Boundary{X: 0, Y: 0, Width: 100, Height: 100}
+	qt.Query(area)
+	qt.QueryWrapped(&Boundary{X: 100, Y: 0, Width: 20, Height: 100})
+	qt.QueryLimit(area, 3)
+	qt.Nearest(&PointOf[int]{X: 0, Y: 0})
+	qt.QueryKNearest(&PointOf[int]{X: 0, Y: 0}, 2)
+	qt.QueryCircle(0, 0, 5)
+	qt.CountInRange(area) // A count, not a search
+	if obs.searches != 6 {
+		t.Errorf("6 searches expected, got %d", obs.searches)
+	}
+	if want := 13 + 0 + 3 + 1 + 2; obs.found < want {
+		t.Errorf("At least %d points found expected, got %d", want, obs.found)
+	}
+	if obs.took <= 0 {
+		t.Errorf("The searches must report how long they took, got %s", obs.took)
+	}
+	// QueryMulti reports one search per rect
+	qt.QueryMulti([]*Boundary{area, area, nil})
+	if obs.searches != 9 {
+		t.Errorf("9 searches expected after QueryMulti, got %d", obs.searches)
+	}
+
+	// --- Test 4: removes ---
+	qt.Remove(p) // Already moved: nothing removed
+	qt.RemoveByID(3)
+	qt.RemoveInArea(&Boundary{X: 4.5, Y: 4.5, Width: 1, Height: 1})
+	clock.Advance(time.Minute)
+	qt.RemoveExpired()
+	qt.Clear()
+	if obs.removed != 13 {
+		t.Errorf("13 removes expected, got %d", obs.removed)
+	}
+}
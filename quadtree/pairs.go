@@ -0,0 +1,54 @@
+package quadtree // Pairs of points close to each other (proximity pairs)
+
+// QueryPairs returns every pair of points inside rect that are within
+// maxDist of each other (at exactly maxDist included), each pair once:
+// [a, b] is never followed by [b, a]. Distances are measured like
+// QueryCircle: flat Euclidean in degree space, or the tree's DistanceFunc
+// (with maxDist in its units).
+//
+// Instead of comparing every point with every other (n² distances), each
+// point is only compared with the candidates of a circle search of radius
+// maxDist around it, which skips the nodes farther than that.
+// The output itself is not capped: when k points of rect all lie within
+// maxDist of each other, there are k*(k-1)/2 pairs, so a tight cluster of
+// 10k points gives 50 million of them. Keep rect and maxDist small
+// where the data can be that dense.
+//
+// Each search takes its own Read Locks: with concurrent writers the result
+// is not a snapshot of a single instant, like several Query calls.
+func (qt *QuadTreeOf[T]) QueryPairs(rect *Boundary, maxDist float64) [][2]*PointOf[T] {
+	pairs := [][2]*PointOf[T]{}
+	// A negative distance can't pair anything
+	if maxDist < 0 {
+		return pairs
+	}
+
+	points := qt.Query(rect)
+	// The position of each point in 'points': a pair is only kept from its
+	// first point, and candidates outside rect have none
+	index := make(map[*PointOf[T]]int, len(points))
+	for i, p := range points {
+		index[p] = i
+	}
+
+	// Same distance and radius as QueryCircleFunc
+	fn := qt.distance
+	radius := maxDist
+	if fn == nil {
+		radius *= radius
+	}
+
+	// One candidate buffer, reused for every point
+	var candidates []*PointOf[T]
+	for i, p := range points {
+		candidates = candidates[:0]
+		// (the expired candidates are not in index: Query left them out)
+		qt.queryCircleRecursive(p.X, p.Y, radius, fn, nil, &candidates)
+		for _, q := range candidates {
+			if j, ok := index[q]; ok && j > i {
+				pairs = append(pairs, [2]*PointOf[T]{p, q})
+			}
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,63 @@
+package quadtree // Tests for the proximity pairs
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestQueryPairs verifies the pairs on known points, then against
+// the naive comparison of every pair on random ones
+func TestQueryPairs(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	a := &Point{X: 10, Y: 10, Data: "a"}
+	b := &Point{X: 13, Y: 14, Data: "b"} // 5 from a
+	c := &Point{X: 30, Y: 10, Data: "c"} // Far from everyone
+	d := &Point{X: 10, Y: 6, Data: "d"}  // 4 from a, just outside the area
+	for _, p := range []*Point{a, b, c, d} {
+		qt.Insert(p)
+	}
+	area := &Boundary{X: 20, Y: 20, Width: 20, Height: 13} // y from 7
+
+	// --- Test 1: one pair, at exactly maxDist ---
+	pairs := qt.QueryPairs(area, 5)
+	if len(pairs) != 1 || pairs[0][0] == pairs[0][1] ||
+		!(pairs[0][0] == a && pairs[0][1] == b || pairs[0][0] == b && pairs[0][1] == a) {
+		t.Errorf("The pair (a, b) expected, got %v", pairs)
+	}
+
+	// --- Test 2: nothing closer than the smallest distance, or negative ---
+	if pairs := qt.QueryPairs(area, 4.9); len(pairs) != 0 {
+		t.Errorf("No pair expected below 5, got %v", pairs)
+	}
+	if pairs := qt.QueryPairs(area, -1); len(pairs) != 0 {
+		t.Errorf("No pair expected for a negative distance, got %v", pairs)
+	}
+
+	// --- Test 3: random points, same pairs as comparing every pair ---
+	big := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 3000; i++ {
+		big.Insert(&Point{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i})
+	}
+	area = &Boundary{X: 10, Y: 20, Width: 60, Height: 40}
+	inside := big.Query(area)
+	expected := 0
+	for i := range inside {
+		for j := i + 1; j < len(inside); j++ {
+			dx, dy := inside[i].X-inside[j].X, inside[i].Y-inside[j].Y
+			if dx*dx+dy*dy <= 3*3 {
+				expected++
+			}
+		}
+	}
+	seen := map[[2]*Point]bool{}
+	for _, pair := range big.QueryPairs(area, 3) {
+		if seen[pair] || seen[[2]*Point{pair[1], pair[0]}] {
+			t.Fatalf("Pair %v returned twice", pair)
+		}
+		seen[pair] = true
+	}
+	if len(seen) != expected || expected == 0 {
+		t.Errorf("%d pairs expected, got %d", expected, len(seen))
+	}
+}
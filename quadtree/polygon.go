@@ -0,0 +1,64 @@
+package quadtree // Polygon queries on the QuadTree
+
+// QueryPolygon finds the points inside the polygon described by 'vertices'
+// (in order, the last vertex connects back to the first).
+// The polygon's bounding box is used as a coarse filter with the standard
+// rectangular search, then a ray-casting point-in-polygon test is applied
+// to every candidate. Non-convex (concave) polygons are supported.
+// Fewer than 3 vertices don't describe an area: an empty slice is returned.
+//
+// Points exactly on an edge follow the same half-open rule as Boundary.Contains:
+// they are inside on the West/South edges of the polygon and outside on its
+// East/North edges (for an axis-aligned square, exactly [min, max)), so two
+// polygons sharing an edge never both claim a point lying on it.
+func (qt *QuadTreeOf[T]) QueryPolygon(vertices []PointOf[T]) []*PointOf[T] {
+	began := qt.searchStart()
+	found := []*PointOf[T]{}
+	if len(vertices) < 3 {
+		return found
+	}
+
+	// --- Coarse filter: the bounding box of the polygon ---
+	minX, minY := vertices[0].X, vertices[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range vertices[1:] {
+		minX, maxX = min(minX, v.X), max(maxX, v.X)
+		minY, maxY = min(minY, v.Y), max(maxY, v.Y)
+	}
+	box := fromMinMax(minX, minY, maxX, maxY)
+
+	candidates := []*PointOf[T]{}
+	qt.queryRecursive(&box, nil, &candidates)
+
+	// --- Fine filter: ray casting ---
+	for _, p := range candidates {
+		if pointInPolygon(p.X, p.Y, vertices) {
+			found = append(found, p)
+		}
+	}
+	qt.observeSearch(began, len(found))
+
+	return found
+}
+
+// pointInPolygon checks if (x, y) is inside the polygon using the
+// ray-casting algorithm: a horizontal ray from the point towards +X
+// crosses the polygon's edges an odd number of times if, and only if,
+// the point is inside.
+func pointInPolygon[T comparable](x, y float64, vertices []PointOf[T]) bool {
+	inside := false
+	j := len(vertices) - 1
+	for i := range vertices {
+		a, b := &vertices[i], &vertices[j]
+		// Does the edge (a, b) straddle the ray's horizontal line?
+		if (a.Y > y) != (b.Y > y) {
+			// X coordinate where the edge crosses that line
+			crossX := a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if x < crossX {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside
+}
@@ -0,0 +1,81 @@
+package quadtree // Tests for the polygon queries
+
+import "testing"
+
+// TestQuadTreeQueryPolygon verifies the point-in-polygon filter
+// with a triangular search area.
+func TestQuadTreeQueryPolygon(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// Right triangle with vertices (0,0), (40,0), (0,40)
+	triangle := []Point{{X: 0, Y: 0}, {X: 40, Y: 0}, {X: 0, Y: 40}}
+
+	qt.Insert(&Point{X: 10, Y: 10, Data: "inside"})
+	qt.Insert(&Point{X: 5, Y: 30, Data: "inside, near the hypotenuse"})
+	qt.Insert(&Point{X: 30, Y: 30, Data: "bounding box only"}) // Beyond the hypotenuse
+	qt.Insert(&Point{X: 35, Y: 20, Data: "bounding box only, too"})
+	qt.Insert(&Point{X: -10, Y: 10, Data: "outside"})
+
+	found := qt.QueryPolygon(triangle)
+
+	// --- Test 1: Only the 2 points inside the triangle ---
+	if len(found) != 2 {
+		t.Fatalf("QueryPolygon: 2 points expected, %d found", len(found))
+	}
+	for _, p := range found {
+		if p.X+p.Y >= 40 || p.X < 0 {
+			t.Errorf("Point %v is outside the triangle but was returned", p.Data)
+		}
+	}
+
+	// --- Test 2: Fewer than 3 vertices find nothing ---
+	if found := qt.QueryPolygon(triangle[:2]); found == nil || len(found) != 0 {
+		t.Errorf("QueryPolygon with 2 vertices: empty slice expected, got %v", found)
+	}
+}
+
+// TestQuadTreeQueryPolygonConcave uses an L-shaped polygon: the points in
+// its notch are inside the bounding box but must be excluded.
+// It also pins down the behavior of points exactly on the edges.
+func TestQuadTreeQueryPolygonConcave(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// L shape: the square (0,0)-(40,40) without its North-East quarter
+	lShape := []PointOf[string]{
+		{X: 0, Y: 0}, {X: 40, Y: 0}, {X: 40, Y: 20},
+		{X: 20, Y: 20}, {X: 20, Y: 40}, {X: 0, Y: 40},
+	}
+
+	points := map[string]bool{ // Data -> expected inside
+		"foot":            true,  // (30, 10), in the horizontal bar
+		"leg":             true,  // (10, 30), in the vertical bar
+		"corner":          true,  // (10, 10), where the bars meet
+		"notch":           false, // (30, 30), the missing quarter
+		"notch edge":      false, // (30, 20), on the inner edge, North of the foot
+		"West edge":       true,  // (0, 10), the West edge is inclusive
+		"South edge":      true,  // (10, 0), the South edge is inclusive
+		"East edge":       false, // (40, 10), the East edge is exclusive
+		"North edge":      false, // (10, 40), the North edge is exclusive
+		"outside the box": false, // (50, 50)
+	}
+	coords := map[string][2]float64{
+		"foot": {30, 10}, "leg": {10, 30}, "corner": {10, 10},
+		"notch": {30, 30}, "notch edge": {30, 20},
+		"West edge": {0, 10}, "South edge": {10, 0},
+		"East edge": {40, 10}, "North edge": {10, 40},
+		"outside the box": {50, 50},
+	}
+	for data, xy := range coords {
+		qt.Insert(&PointOf[string]{X: xy[0], Y: xy[1], Data: data})
+	}
+
+	found := map[string]bool{}
+	for _, p := range qt.QueryPolygon(lShape) {
+		found[p.Data] = true
+	}
+	for data, inside := range points {
+		if found[data] != inside {
+			t.Errorf("%s: inside=%v expected, got %v", data, inside, found[data])
+		}
+	}
+}
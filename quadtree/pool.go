@@ -0,0 +1,103 @@
+package quadtree // Recycling of nodes and points (WithPooling)
+
+import (
+	"sync" // Import concurrency package (Pool)
+)
+
+// treePool recycles the nodes and the points of a tree created WithPooling.
+// Every node of the tree points to the same treePool (nil without pooling).
+type treePool[T comparable] struct {
+	nodes  sync.Pool // Empty *QuadTreeOf[T], from collapsed subtrees
+	points sync.Pool // Zeroed *PointOf[T], from ReleasePoint
+}
+
+// WithPooling makes the tree recycle its memory instead of leaving it to the
+// garbage collector: the nodes of collapsed subtrees are reused by the next
+// subdivisions, and Update takes its new points from the pool filled by
+// ReleasePoint. It helps workloads that move many points all the time,
+// like the simulator. See BenchmarkSimulateMove.
+func WithPooling() Option {
+	return func(o *options) {
+		o.pooling = true
+	}
+}
+
+// withPool makes the new tree recycle through tp, the pool of the tree it
+// replaces (Rebuild, UnmarshalJSON). A nil tp means no pooling.
+func withPool[T comparable](tp *treePool[T]) Option {
+	return func(o *options) {
+		o.pooling = tp != nil
+		o.pool = tp
+	}
+}
+
+// NewPoint returns a point at (x, y) holding data. With WithPooling it reuses
+// a point given back with ReleasePoint, otherwise it is a plain allocation.
+func (qt *QuadTreeOf[T]) NewPoint(x, y float64, data T) *PointOf[T] {
+	return qt.pool.point(x, y, data)
+}
+
+// ReleasePoint gives p back to the pool of a tree created WithPooling
+// (without pooling it does nothing). p is zeroed, so its Data can't leak
+// into the next point handed out.
+// Only release a point that is no longer in the tree (e.g. the old point
+// after an Update) and that nobody else still references.
+func (qt *QuadTreeOf[T]) ReleasePoint(p *PointOf[T]) {
+	if qt.pool == nil || p == nil {
+		return
+	}
+	*p = PointOf[T]{}
+	qt.pool.points.Put(p)
+}
+
+// point returns a point from the pool, or a new one (also on a nil pool)
+func (tp *treePool[T]) point(x, y float64, data T) *PointOf[T] {
+	if tp != nil {
+		if p, _ := tp.points.Get().(*PointOf[T]); p != nil {
+			p.X, p.Y, p.Data = x, y, data
+			return p
+		}
+	}
+	return &PointOf[T]{X: x, Y: y, Data: data}
+}
+
+// node returns an empty node from the pool, or nil (also on a nil pool)
+func (tp *treePool[T]) node() *QuadTreeOf[T] {
+	if tp == nil {
+		return nil
+	}
+	n, _ := tp.nodes.Get().(*QuadTreeOf[T])
+	return n
+}
+
+// dropChildren turns this node back into a leaf. With pooling, the nodes
+// of the dropped subtrees go back to the pool.
+// The caller holds the Write Lock of this node and has seen it empty (or
+// drained it): nobody can be inside the children (see handover.go).
+func (qt *QuadTreeOf[T]) dropChildren() {
+	if qt.pool != nil && qt.northWest != nil {
+		for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+			child.recycle()
+		}
+	}
+	qt.northWest, qt.northEast, qt.southWest, qt.southEast = nil, nil, nil, nil
+	qt.publishChildren()
+}
+
+// recycle resets this node and its subtree and puts them in the pool.
+// The points themselves are not recycled: callers may still hold them.
+func (qt *QuadTreeOf[T]) recycle() {
+	if qt.northWest != nil {
+		for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+			child.recycle()
+		}
+	}
+
+	// Keep the backing array of the points, but drop the pointers
+	// so no stale point (or Data) survives in the pooled node
+	qt.resetPoints()
+	points, xy := qt.points, qt.xy
+	pool := qt.pool
+	*qt = QuadTreeOf[T]{points: points, xy: xy}
+	pool.nodes.Put(qt)
+}
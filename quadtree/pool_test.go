@@ -0,0 +1,91 @@
+package quadtree // Tests for the node and point recycling
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreePooling checks that a tree created WithPooling behaves like
+// a plain one while it recycles its nodes and points
+func TestQuadTreePooling(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+
+	// --- Test 1: a released point is zeroed ---
+	qt := NewQuadTreeOf[string](world, 4, WithPooling(), WithIDIndex())
+	p := qt.NewPoint(1, 2, "driver-1")
+	if p.X != 1 || p.Y != 2 || p.Data != "driver-1" {
+		t.Fatalf("NewPoint: (1, 2, driver-1) expected, got %v", *p)
+	}
+	qt.ReleasePoint(p)
+	if *p != (PointOf[string]{}) {
+		t.Errorf("ReleasePoint must zero the point, got %v", *p)
+	}
+	// Whatever the pool hands out next carries the new values only
+	if q := qt.NewPoint(3, 4, "driver-2"); q.X != 3 || q.Y != 4 || q.Data != "driver-2" {
+		t.Errorf("NewPoint after a release: (3, 4, driver-2) expected, got %v", *q)
+	}
+
+	// --- Test 2: moves with released points keep the tree consistent ---
+	rng := rand.New(rand.NewSource(1))
+	drivers := make([]*PointOf[string], 500)
+	for i := range drivers {
+		drivers[i] = qt.NewPoint(rng.Float64()*360-180, rng.Float64()*180-90, string(rune('a'+i%26))+string(rune(i)))
+		qt.Insert(drivers[i])
+	}
+	for round := 0; round < 20; round++ {
+		for i, old := range drivers {
+			moved := qt.Update(old, min(max(old.X+rng.Float64()-0.5, -180), 180), min(max(old.Y+rng.Float64()-0.5, -90), 90))
+			if moved == nil {
+				t.Fatalf("Update of %q failed", old.Data)
+			}
+			drivers[i] = moved
+			qt.ReleasePoint(old)
+		}
+	}
+	for _, d := range drivers {
+		if got, ok := qt.GetByID(d.Data); !ok || got != d {
+			t.Fatalf("GetByID(%q) must return the current point", d.Data)
+		}
+	}
+	if qt.Count() != len(drivers) || len(qt.AllPoints()) != len(drivers) {
+		t.Errorf("%d points expected, Count %d, AllPoints %d", len(drivers), qt.Count(), len(qt.AllPoints()))
+	}
+
+	// --- Test 3: recycled nodes carry no stale points ---
+	qt.RemoveInArea(&world) // Collapses everything into the pool
+	for i := 0; i < 200; i++ {
+		qt.Insert(qt.NewPoint(float64(i%20)-10, float64(i/20)-5, "n"+string(rune(i))))
+	}
+	var check func(node *QuadTreeOf[string])
+	check = func(node *QuadTreeOf[string]) {
+		if node.northWest == nil {
+			if int64(len(node.points)) != node.size.Load() {
+				t.Fatalf("Leaf at depth %d: %d points for a size of %d", node.depth, len(node.points), node.size.Load())
+			}
+			for _, p := range node.points {
+				if !node.contains(p) {
+					t.Fatalf("Leaf at depth %d holds a point outside its boundary", node.depth)
+				}
+			}
+			return
+		}
+		for _, child := range []*QuadTreeOf[string]{node.northWest, node.northEast, node.southWest, node.southEast} {
+			if child.pool != qt.pool || child.depth != node.depth+1 {
+				t.Fatalf("Child of depth %d: wrong pool or depth", node.depth)
+			}
+			check(child)
+		}
+	}
+	check(qt)
+	if found := qt.Query(&Boundary{X: 0, Y: 0, Width: 10, Height: 5}); len(found) != 200 {
+		t.Errorf("Query after the recycling: 200 points expected, got %d", len(found))
+	}
+
+	// --- Test 4: without pooling ReleasePoint does nothing ---
+	plain := NewQuadTree(world, 4)
+	kept := &Point{X: 1, Y: 1, Data: "kept"}
+	plain.ReleasePoint(kept)
+	if kept.Data != "kept" {
+		t.Errorf("ReleasePoint without pooling must not touch the point")
+	}
+}
@@ -1,15 +1,25 @@
 package quadtree // Declares that this file belongs to the "quadtree" package
 
 import (
-	"sync" //Import concurrency package (Mutex)
+	"errors"      // Import errors package (the InsertE errors)
+	"math"        // Import math package (IsNaN, IsInf)
+	"sync"        //Import concurrency package (Mutex)
+	"sync/atomic" // Import atomic package (lock-free counters)
+	"time"        // Import time package (the clock of the TTLs)
 )
 
-type Point struct { // Points represents a single point in 2D space with associated data
-	X    float64     // Longitude
-	Y    float64     // Latitude
-	Data interface{} //Generic Data (e.g ID Driver)
+// PointOf represents a single point in 2D space with associated data of type T.
+// T must be comparable because Remove matches points by (X, Y, Data).
+type PointOf[T comparable] struct {
+	X    float64 // Longitude
+	Y    float64 // Latitude
+	Data T       //Generic Data (e.g ID Driver)
 }
 
+// Point is the non-generic point, kept for backward compatibility
+// Its Data can hold any (comparable) value
+type Point = PointOf[any]
+
 type Boundary struct { // Boundary defines a rectangular area using a center and "halves"
 	X      float64 //Center X (Longitude)
 	Y      float64 //Center Y (Latitude)
@@ -17,58 +27,354 @@ type Boundary struct { // Boundary defines a rectangular area using a center and
 	Height float64 // Half the Height (from Y on board)
 }
 
-// QuadTree is the primary data structure
+// QuadTreeOf is the primary data structure, generic over the Data type T
 // Contains a pointer to a Mutex to handle concurrency
-type QuadTree struct {
-	boundary Boundary // The area that this node covers
-	capacity int      // Max number of points before splitting
-	points   []*Point // Slice of pointers to points in this node
+type QuadTreeOf[T comparable] struct {
+	boundary Boundary      // The area that this node covers
+	capacity int           // Max number of points before splitting
+	points   []*PointOf[T] // Slice of pointers to points in this node
+	xy       []float64     // Coordinates of 'points', side by side (see leaf.go)
+	size     atomic.Int64  // Number of points in this whole subtree
+	depth    int           // Depth of this node (0 for the root)
+	maxDepth int           // Depth at which nodes stop subdividing
+
+	// The exact edges of this node. They are computed once from the parent's
+	// edges and center, not from the boundary: X-Width/2+Width/2 is not always X
+	// in floating point, so the edges rebuilt from each child's own boundary
+	// may leave a gap (or an overlap) between siblings. With the shared values
+	// the four children cover their parent exactly, and no point falls through.
+	minX, maxX float64
+	minY, maxY float64
 
 	// Pointer to the 4 children (initially nil)
-	northWest *QuadTree
-	northEast *QuadTree
-	southWest *QuadTree
-	southEast *QuadTree
+	northWest *QuadTreeOf[T]
+	northEast *QuadTreeOf[T]
+	southWest *QuadTreeOf[T]
+	southEast *QuadTreeOf[T]
+
+	// The same 4 children, published for the queries that read them
+	// without any lock (see readfree.go). Set once they are complete.
+	children atomic.Pointer[[4]*QuadTreeOf[T]]
+
+	// The root treats its own East/North edges as inclusive, so points
+	// lying exactly on the world's maximum edge are not rejected.
+	// Children along those edges inherit the flag, all the others
+	// keep the semi-open [min, max) convention.
+	closedEast  bool
+	closedNorth bool
+
+	// A fixed node is one of the pre-split "shard" levels at the top of the
+	// tree: its children are created once and never change, so writers only
+	// need its Read Lock to pass through it (see WithShards)
+	fixed bool
+
+	// Inverted index of the point labels (only used on the root)
+	labels labelIndex[T]
+
+	// Expiry of the points inserted with a TTL (only used on the root)
+	expiry expiryIndex[T]
+
+	// Data -> point index (only on a root created WithIDIndex, nil otherwise)
+	ids *idIndex[T]
+
+	// Distance of the distance-based searches (only on the root, nil
+	// for SquaredEuclidean, see WithDistance)
+	distance DistanceFunc
+
+	// In single-lock mode (the default without shards, or WithSingleLock)
+	// the root's lock guards the whole tree:
+	// the root has singleLock set, all the other nodes have noLock set
+	// and never touch their own lock
+	singleLock bool
+	noLock     bool
+
+	// Node and point recycling (only with WithPooling, nil otherwise)
+	pool *treePool[T]
+
+	// Told about the changes and searches (only on the root, see WithObserver)
+	observer Observer
 
 	//Mutex to make the structure thread-safe
 	//RWMutex is optimal: it allows multiple readings or a single writing
 	mu sync.RWMutex
 }
 
-// NewQuadTree is the constructor for a QuadTree
-func NewQuadTree(boundary Boundary, capacity int) *QuadTree {
+// QuadTree is the non-generic tree, kept for backward compatibility
+// It stores Points whose Data can hold any (comparable) value
+type QuadTree = QuadTreeOf[any]
+
+// DefaultMaxDepth is the default limit on subdivision.
+// On a world map, 20 levels are roughly sub-meter precision.
+const DefaultMaxDepth = 20
+
+// options holds the optional settings of a QuadTree
+type options struct {
+	maxDepth    int
+	shardLevels int
+	idIndex     bool
+	idKey       any // The func(T) T of WithIDKey
+	singleLock  bool
+	nodeLocks   bool
+	distance    DistanceFunc
+	clock       func() time.Time
+	pooling     bool
+	pool        any // An existing *treePool[T] to share (see withPool)
+	observer    Observer
+}
+
+// Option is a functional option for NewQuadTree / NewQuadTreeOf
+type Option func(*options)
+
+// WithMaxDepth caps subdivision at depth n (the root has depth 0).
+// Leaves at the maximum depth accept any number of points beyond the capacity,
+// so many coincident points can't cause endless subdivision.
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		// A negative depth makes no sense: never subdivide
+		if n < 0 {
+			n = 0
+		}
+		o.maxDepth = n
+	}
+}
+
+// WithShards pre-splits the root into 4^levels fixed top-level cells ("shards"),
+// e.g. levels=2 gives 16 shards. Every shard is a subtree with its own lock,
+// and inserts/removes only take a Read Lock on the levels above their leaf
+// (see handover.go), so writers in different shards no longer contend on
+// the root's Write Lock.
+// The shard levels are never collapsed and count towards the maximum depth.
+func WithShards(levels int) Option {
+	return func(o *options) {
+		if levels < 0 {
+			levels = 0
+		}
+		o.shardLevels = levels
+	}
+}
+
+// WithSingleLock guards the whole tree with the root's RWMutex only,
+// instead of one RWMutex per node. A deep Query then takes a single Read
+// Lock instead of dozens, which is cheaper on read-heavy loads, but every
+// writer locks the whole tree (shard levels no longer help writers).
+// Trees without shards use the single lock unless WithNodeLocks is given.
+// See BenchmarkLockMode.
+func WithSingleLock() Option {
+	return func(o *options) {
+		o.singleLock = true
+	}
+}
+
+// WithNodeLocks gives every node its own RWMutex even without shards.
+// Inserts use hand-over-hand locking: they hold a node's lock only until
+// they hold the next one's, so a writer deep in one branch never blocks
+// writers heading elsewhere, and only the leaf is locked for writing.
+// It pays off with many concurrent writers; WithSingleLock wins when
+// the tree is mostly read. See BenchmarkHandOverHand.
+func WithNodeLocks() Option {
+	return func(o *options) {
+		o.nodeLocks = true
+	}
+}
+
+// NewQuadTree is the constructor for a (non-generic) QuadTree
+func NewQuadTree(boundary Boundary, capacity int, opts ...Option) *QuadTree {
+	return NewQuadTreeOf[any](boundary, capacity, opts...)
+}
+
+// NewQuadTreeOf is the constructor for a QuadTree storing Data of type T
+func NewQuadTreeOf[T comparable](boundary Boundary, capacity int, opts ...Option) *QuadTreeOf[T] {
 
 	// Ensure the capacity is at least 1 to avoid logical errors
 	if capacity < 1 {
 		capacity = 1
 	}
 
+	// Apply the options on top of the defaults
+	o := options{maxDepth: DefaultMaxDepth}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	shardLevels := min(o.shardLevels, o.maxDepth)
+
+	// Without shards, every Insert still enters through the root: unless
+	// asked for, one lock per node would mostly add cost, not concurrency
+	if shardLevels == 0 && !o.nodeLocks {
+		o.singleLock = true
+	}
+
 	// Initialize the QuadTree struct
-	qt := &QuadTree{
+	qt := &QuadTreeOf[T]{
 		boundary: boundary,
 		capacity: capacity,
+		maxDepth: o.maxDepth,
+		minX:     boundary.X - boundary.Width,
+		maxX:     boundary.X + boundary.Width,
+		minY:     boundary.Y - boundary.Height,
+		maxY:     boundary.Y + boundary.Height,
 		// Initialize the 'points' slice with a length of 0,
 		// but with a pre-allocated capacity for efficiency.
-		points: make([]*Point, 0, capacity),
+		points: make([]*PointOf[T], 0, capacity),
+		xy:     make([]float64, 0, 2*capacity),
+		// A new tree is a root: its maximum edges are inclusive
+		closedEast:  true,
+		closedNorth: true,
+		singleLock:  o.singleLock,
+		distance:    o.distance,
+		observer:    o.observer,
 	}
 
+	if o.idIndex {
+		qt.ids = newIDIndex[T](&o)
+	}
+	qt.expiry.now = o.clock
+	if o.pooling {
+		qt.pool, _ = o.pool.(*treePool[T])
+		if qt.pool == nil {
+			qt.pool = &treePool[T]{}
+		}
+	}
+
+	// Pre-split the shard levels (never deeper than the maximum depth)
+	qt.presplit(shardLevels)
+
 	return qt
 }
 
+// presplit subdivides this node 'levels' times and marks
+// the resulting internal nodes as fixed
+func (qt *QuadTreeOf[T]) presplit(levels int) {
+	if levels <= 0 {
+		return
+	}
+	qt.subdivide()
+	qt.publishChildren()
+	qt.fixed = true
+	for _, child := range []*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+		child.presplit(levels - 1)
+	}
+}
+
+// lockForWrite acquires the lock needed to modify this node's subtree;
+// unlockForWrite releases it.
+// Fixed (shard) nodes never change their own children and only hold
+// atomic counters, so a Read Lock is enough: writers heading to
+// different shards don't block each other.
+// (A pair of methods, not a returned unlock func: a closure would
+// be allocated at every node of every Insert and Remove.)
+func (qt *QuadTreeOf[T]) lockForWrite() {
+	// Below the root of a single-lock tree there is nothing to lock
+	if qt.noLock {
+		return
+	}
+	// The single lock is the only lock: writers always need it exclusively
+	if qt.fixed && !qt.singleLock {
+		qt.mu.RLock()
+		return
+	}
+	qt.mu.Lock()
+}
+
+// unlockForWrite releases the lock taken by lockForWrite
+func (qt *QuadTreeOf[T]) unlockForWrite() {
+	if qt.noLock {
+		return
+	}
+	if qt.fixed && !qt.singleLock {
+		qt.mu.RUnlock()
+		return
+	}
+	qt.mu.Unlock()
+}
+
+// rlock takes the Read Lock of this node (a no-op below the root of a
+// single-lock tree, where the root's lock already guards everything)
+func (qt *QuadTreeOf[T]) rlock() {
+	if !qt.noLock {
+		qt.mu.RLock()
+	}
+}
+
+// runlock releases the Read Lock taken by rlock
+func (qt *QuadTreeOf[T]) runlock() {
+	if !qt.noLock {
+		qt.mu.RUnlock()
+	}
+}
+
+// Area returns the area covered by the boundary (full width * full height)
+func (b *Boundary) Area() float64 {
+	return (b.Width * 2) * (b.Height * 2)
+}
+
+// Center returns the center of the boundary
+func (b *Boundary) Center() (x, y float64) {
+	return b.X, b.Y
+}
+
+// MinX returns the West edge of the boundary (X - Width)
+func (b *Boundary) MinX() float64 {
+	return b.X - b.Width
+}
+
+// MaxX returns the East edge of the boundary (X + Width)
+func (b *Boundary) MaxX() float64 {
+	return b.X + b.Width
+}
+
+// MinY returns the South edge of the boundary (Y - Height)
+func (b *Boundary) MinY() float64 {
+	return b.Y - b.Height
+}
+
+// MaxY returns the North edge of the boundary (Y + Height)
+func (b *Boundary) MaxY() float64 {
+	return b.Y + b.Height
+}
+
 // Contains checks if a point is within the boundary of this node
 func (b *Boundary) Contains(p *Point) bool {
+	return b.ContainsXY(p.X, p.Y)
+}
+
+// ContainsXY checks if the coordinates (x, y) are within the boundary.
+// It works for points of any Data type.
+func (b *Boundary) ContainsXY(x, y float64) bool {
 	// The logic uses a "semi-open" interval [min, max)
 	// This means the 'min' boundary (West, South) is inclusive (>=)
 	// and the 'max' boundary (East, North) is exclusive (<).
 	// This prevents double-counting points that lie exactly on a shared border.
-	return p.X >= (b.X-b.Width) && // West boundary (inclusive)
-		p.X < (b.X+b.Width) && // East boundary (exclusive)
-		p.Y >= (b.Y-b.Height) && // South boundary (inclusive)
-		p.Y < (b.Y+b.Height) // North boundary (exclusive)
+	return x >= (b.X-b.Width) && // West boundary (inclusive)
+		x < (b.X+b.Width) && // East boundary (exclusive)
+		y >= (b.Y-b.Height) && // South boundary (inclusive)
+		y < (b.Y+b.Height) // North boundary (exclusive)
+}
+
+// contains checks if a point belongs to this node.
+// It follows Boundary.Contains, but a node lying on the world's
+// East/North edge also accepts points exactly on that edge.
+func (qt *QuadTreeOf[T]) contains(p *PointOf[T]) bool {
+	// West and South boundaries are always inclusive.
+	// Written as !(>=) so that NaN, which fails every comparison, is outside.
+	if !(p.X >= qt.minX) || !(p.Y >= qt.minY) {
+		return false
+	}
+
+	// East boundary: exclusive, unless this node sits on the world's East edge
+	if p.X > qt.maxX || (p.X == qt.maxX && !qt.closedEast) {
+		return false
+	}
+
+	// North boundary: exclusive, unless this node sits on the world's North edge
+	if p.Y > qt.maxY || (p.Y == qt.maxY && !qt.closedNorth) {
+		return false
+	}
+
+	return true
 }
 
 // subdivide creates four child quadrants for this node
-func (qt *QuadTree) subdivide() {
+func (qt *QuadTreeOf[T]) subdivide() {
 	// Calculate the dimensions for the new children
 	childWidth := qt.boundary.Width / 2
 	childHeight := qt.boundary.Height / 2
@@ -77,58 +383,179 @@ func (qt *QuadTree) subdivide() {
 
 	// Create the boundary for the North-West child and initialize it
 	nwBoundary := Boundary{X: centerX - childWidth, Y: centerY + childHeight, Width: childWidth, Height: childHeight}
-	qt.northWest = NewQuadTree(nwBoundary, qt.capacity)
+	qt.northWest = qt.newChild(nwBoundary)
 
 	// Create the boundary for the North-East child and initialize it
 	neBoundary := Boundary{X: centerX + childWidth, Y: centerY + childHeight, Width: childWidth, Height: childHeight}
-	qt.northEast = NewQuadTree(neBoundary, qt.capacity)
+	qt.northEast = qt.newChild(neBoundary)
 
 	// Create the boundary for the South-West child and initialize it
 	swBoundary := Boundary{X: centerX - childWidth, Y: centerY - childHeight, Width: childWidth, Height: childHeight}
-	qt.southWest = NewQuadTree(swBoundary, qt.capacity)
+	qt.southWest = qt.newChild(swBoundary)
 
 	// Create the boundary for the South-East child and initialize it
 	seBoundary := Boundary{X: centerX + childWidth, Y: centerY - childHeight, Width: childWidth, Height: childHeight}
-	qt.southEast = NewQuadTree(seBoundary, qt.capacity)
+	qt.southEast = qt.newChild(seBoundary)
+
+	// Only the children touching this node's closed edges keep them closed:
+	// the East children inherit the East edge, the North children the North edge
+	qt.northWest.closedEast, qt.northWest.closedNorth = false, qt.closedNorth
+	qt.northEast.closedEast, qt.northEast.closedNorth = qt.closedEast, qt.closedNorth
+	qt.southWest.closedEast, qt.southWest.closedNorth = false, false
+	qt.southEast.closedEast, qt.southEast.closedNorth = qt.closedEast, false
+
+	// The children share this node's edges and center exactly (see minX)
+	qt.northWest.setEdges(qt.minX, centerX, centerY, qt.maxY)
+	qt.northEast.setEdges(centerX, qt.maxX, centerY, qt.maxY)
+	qt.southWest.setEdges(qt.minX, centerX, qt.minY, centerY)
+	qt.southEast.setEdges(centerX, qt.maxX, qt.minY, centerY)
+}
+
+// setEdges sets the exact edges of this node
+func (qt *QuadTreeOf[T]) setEdges(minX, maxX, minY, maxY float64) {
+	qt.minX, qt.maxX = minX, maxX
+	qt.minY, qt.maxY = minY, maxY
+}
+
+// newChild creates an empty child node covering 'boundary', one level deeper
+func (qt *QuadTreeOf[T]) newChild(boundary Boundary) *QuadTreeOf[T] {
+	// With pooling, reuse a node of a collapsed subtree
+	child := qt.pool.node()
+	if child == nil {
+		child = &QuadTreeOf[T]{
+			points: make([]*PointOf[T], 0, qt.capacity),
+			xy:     make([]float64, 0, 2*qt.capacity),
+		}
+	}
+	child.boundary = boundary
+	child.capacity = qt.capacity
+	child.depth = qt.depth + 1
+	child.maxDepth = qt.maxDepth
+	// In a single-lock tree only the root locks
+	child.noLock = qt.noLock || qt.singleLock
+	child.pool = qt.pool
+	return child
+}
+
+// The reasons why InsertE rejects a point
+var (
+	// ErrNonFinite: a coordinate of the point is NaN or ±Inf
+	ErrNonFinite = errors.New("quadtree: point coordinates must be finite")
+	// ErrOutOfBounds: the point is outside the tree's boundary
+	ErrOutOfBounds = errors.New("quadtree: point outside the tree boundary")
+	// ErrDuplicateID: a tree created WithIDIndex already holds this Data
+	ErrDuplicateID = errors.New("quadtree: a point with the same Data already exists")
+	// ErrNotPlaced: the point is inside the boundary, but no leaf took it.
+	// It should never happen: it means a bug in the tree.
+	ErrNotPlaced = errors.New("quadtree: point inside the boundary but not placed in any leaf")
+)
+
+// Insert adds a point to the QuadTree.
+// It returns false if the point was rejected: see InsertE for the reason.
+func (qt *QuadTreeOf[T]) Insert(p *PointOf[T]) bool {
+	return qt.InsertE(p) == nil
+}
+
+// InsertE adds a point to the QuadTree, like Insert, but tells why
+// a point was rejected: ErrNonFinite, ErrOutOfBounds, ErrDuplicateID or ErrNotPlaced.
+func (qt *QuadTreeOf[T]) InsertE(p *PointOf[T]) error {
+	// A NaN or infinite coordinate is a broken input, not just a far away point
+	if !isFinite(p.X) || !isFinite(p.Y) {
+		return ErrNonFinite
+	}
+
+	// With an ID index, the index and the tree are updated together
+	// under the index lock, so they never disagree
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+
+		// IDs are unique: a second point with the same Data is rejected
+		if _, ok := qt.ids.get(p.Data); ok {
+			return ErrDuplicateID
+		}
+		if !qt.insert(p) {
+			return qt.insertError(p)
+		}
+		qt.ids.put(p)
+		qt.observeInsert(1)
+		return nil
+	}
+	if !qt.insert(p) {
+		return qt.insertError(p)
+	}
+	qt.observeInsert(1)
+	return nil
+}
+
+// isFinite reports whether v is neither NaN nor ±Inf
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
 }
 
-// Insert adds a point to the QuadTree
-func (qt *QuadTree) Insert(p *Point) bool {
+// insertError explains why insert rejected p
+func (qt *QuadTreeOf[T]) insertError(p *PointOf[T]) error {
+	qt.rlock()
+	defer qt.runlock()
+	if !qt.contains(p) {
+		return ErrOutOfBounds
+	}
+	return ErrNotPlaced
+}
+
+// insert is the internal insertion (no ID index involved)
+func (qt *QuadTreeOf[T]) insert(p *PointOf[T]) bool {
+	// With one lock per node, writers pass each other on the way down
+	if qt.nodeLocks() {
+		return qt.insertHandOverHand(p)
+	}
+	return qt.insertRecursive(p)
+}
+
+// insertRecursive is the recursive insertion, holding the lock of every
+// node on the way down until the point is stored
+func (qt *QuadTreeOf[T]) insertRecursive(p *PointOf[T]) bool {
 
 	// Acquire a Write Lock because we are modifying the tree
-	qt.mu.Lock()
+	// (only a Read Lock on the fixed shard levels, see lockForWrite)
+	qt.lockForWrite()
 	// 'defer' ensures the lock is released when the function exits
-	defer qt.mu.Unlock()
+	defer qt.unlockForWrite()
 
 	// If the point is not within this node's boundary, reject it
-	if !qt.boundary.Contains(p) {
+	if !qt.contains(p) {
 		return false
 	}
 
 	// If this node is already subdivided (it's a "parent" node)...
 	if qt.northWest != nil {
 		// ...try to insert the point into one of its children recursively
-		if qt.northWest.Insert(p) {
-			return true
-		}
-		if qt.northEast.Insert(p) {
+		// (the || stops at the first child that accepts it)
+		if qt.northWest.insertRecursive(p) || qt.northEast.insertRecursive(p) ||
+			qt.southWest.insertRecursive(p) || qt.southEast.insertRecursive(p) {
+			// One more point in this subtree
+			qt.size.Add(1)
 			return true
 		}
-		if qt.southWest.Insert(p) {
-			return true
-		}
-		if qt.southEast.Insert(p) {
-			return true
-		}
-		// If it fails to insert in all children (e.g., boundary issue), return failure
+		// Unreachable: the children cover this node exactly (see minX)
 		return false
 	}
 
 	// If this is a "leaf" node (not subdivided), add the point to its list
-	qt.points = append(qt.points, p)
+	qt.addToLeaf(p)
+	// If we reached here, the point was successfully added to this leaf node
+	return true
+}
+
+// addToLeaf stores p in this leaf and subdivides it if it is now full.
+// The caller holds the Write Lock of this node.
+func (qt *QuadTreeOf[T]) addToLeaf(p *PointOf[T]) {
+	qt.appendPoint(p)
+	qt.size.Add(1)
 
-	// Check if this node is now "full" and needs to be subdivided
-	if len(qt.points) > qt.capacity {
+	// Check if this node is now "full" and needs to be subdivided.
+	// At the maximum depth we never subdivide: the leaf just keeps growing.
+	if len(qt.points) > qt.capacity && qt.depth < qt.maxDepth {
 		// Create the four children
 		qt.subdivide()
 
@@ -137,27 +564,25 @@ func (qt *QuadTree) Insert(p *Point) bool {
 		// from this parent node down into the new children.
 		oldPoints := qt.points
 		// Clear the parent's point list
-		qt.points = make([]*Point, 0, qt.capacity)
+		qt.points = make([]*PointOf[T], 0, qt.capacity)
+		qt.xy = qt.xy[:0]
 
-		// Loop over the old points and insert them into the children
+		// Loop over the old points and insert them into the children.
+		// The children cover this node exactly (see minX), so one of
+		// them always accepts a point this node accepted: none is lost.
+		// Nobody else can reach the new children while we hold this
+		// node's Write Lock, so their own locks are never contended.
 		for _, pt := range oldPoints {
 			// This recursive call will find the correct child
-			if qt.northWest.Insert(pt) {
-				continue
-			}
-			if qt.northEast.Insert(pt) {
-				continue
-			}
-			if qt.southWest.Insert(pt) {
-				continue
-			}
-			if qt.southEast.Insert(pt) {
-				continue
-			}
+			// (the || stops at the first child that accepts it)
+			_ = qt.northWest.insertRecursive(pt) || qt.northEast.insertRecursive(pt) ||
+				qt.southWest.insertRecursive(pt) || qt.southEast.insertRecursive(pt)
 		}
+
+		// Only now the lock-free queries may go down to the children:
+		// before, some points were in neither this node nor them
+		qt.publishChildren()
 	}
-	// If we reached here, the point was successfully added to this leaf node
-	return true
 }
 
 // Intersects checks if this boundary overlaps with another boundary
@@ -202,111 +627,786 @@ func (b *Boundary) Intersects(other *Boundary) bool {
 }
 
 // Query is the public function to find points within a specific area
-func (qt *QuadTree) Query(rangeRect *Boundary) []*Point {
+func (qt *QuadTreeOf[T]) Query(rangeRect *Boundary) []*PointOf[T] {
+	began := qt.searchStart()
 	// Create an empty slice to store the results
-	found := []*Point{}
+	found := []*PointOf[T]{}
 
 	// Call the recursive helper function to populate the 'found' slice
-	qt.queryRecursive(rangeRect, &found)
+	qt.queryRecursive(rangeRect, nil, &found)
+	qt.observeSearch(began, len(found))
 
 	// Return the populated slice
 	return found
 
 }
 
-// queryRecursive is the internal helper that performs the recursive search
-func (qt *QuadTree) queryRecursive(rangeRect *Boundary, found *[]*Point) {
+// QueryInto is like Query, but appends the points to buf and returns the
+// extended slice, so a caller can reuse (or pool) its buffers instead of
+// allocating a new slice for every query. Pass buf[:0] to start over.
+// The result may alias buf's backing array: don't use buf again while
+// you still need the result.
+func (qt *QuadTreeOf[T]) QueryInto(rangeRect *Boundary, buf []*PointOf[T]) []*PointOf[T] {
+	began := qt.searchStart()
+	start := len(buf)
+	qt.queryRecursive(rangeRect, nil, &buf)
+	qt.observeSearch(began, len(buf)-start)
+	return buf
+}
+
+// QueryFilter is like Query, but only returns the points accepted by keep.
+// The predicate is applied at the leaf level, so rejected points are never
+// appended to the result slice. A nil keep behaves exactly like Query.
+// keep runs under the tree's Read Locks: it must not modify the tree.
+func (qt *QuadTreeOf[T]) QueryFilter(rangeRect *Boundary, keep func(*PointOf[T]) bool) []*PointOf[T] {
+	began := qt.searchStart()
+	found := []*PointOf[T]{}
+	qt.queryRecursive(rangeRect, keep, &found)
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// ForEachInRange calls fn for every point within rangeRect, without
+// building a result slice. The traversal stops as soon as fn returns false.
+// As with ForEach, Read Locks are held while fn runs: fn must not modify
+// the tree (Insert/Remove/Update from inside fn would deadlock).
+// Collect what you need and modify the tree after ForEachInRange returns.
+func (qt *QuadTreeOf[T]) ForEachInRange(rangeRect *Boundary, fn func(p *PointOf[T]) bool) {
+	began := qt.searchStart()
+	visited := 0
+	qt.visitRange(rangeRect, func(p *PointOf[T]) bool {
+		visited++
+		return fn(p)
+	})
+	qt.observeSearch(began, visited)
+}
+
+// QueryLimit is like Query, but stops as soon as 'max' points have been
+// collected, without visiting the remaining subtrees.
+// Which points are returned depends on the tree layout.
+// A max <= 0 means unlimited (same as Query).
+func (qt *QuadTreeOf[T]) QueryLimit(rangeRect *Boundary, max int) []*PointOf[T] {
+	began := qt.searchStart()
+	if max <= 0 {
+		return qt.Query(rangeRect)
+	}
+
+	found := make([]*PointOf[T], 0, max)
+	qt.visitRange(rangeRect, func(p *PointOf[T]) bool {
+		found = append(found, p)
+		// Keep going only while there is room for more
+		return len(found) < max
+	})
+	qt.observeSearch(began, len(found))
+	return found
+}
+
+// visitRange calls fn for every point within rangeRect and stops as soon
+// as fn returns false. It returns false if the traversal was stopped.
+// Expired points (see InsertWithTTL) are skipped.
+func (qt *QuadTreeOf[T]) visitRange(rangeRect *Boundary, fn func(*PointOf[T]) bool) bool {
+	return qt.visitRecursive(rangeRect, qt.expiry.live(nil), fn)
+}
+
+// visitRecursive is the internal helper of visitRange: fn is only
+// called for the points accepted by keep (nil: all of them)
+func (qt *QuadTreeOf[T]) visitRecursive(rangeRect *Boundary, keep, fn func(*PointOf[T]) bool) bool {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// Prune the branches outside the area
+	if !qt.intersects(rangeRect) {
+		return true
+	}
+
+	// If this is a "leaf" node, visit the points inside the area
+	// (testing the contiguous coordinates, see leaf.go)
+	if qt.northWest == nil {
+		for i, p := range qt.points {
+			if rangeRect.ContainsXY(qt.xy[2*i], qt.xy[2*i+1]) && (keep == nil || keep(p)) && !fn(p) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// If this is a "parent" node, visit the four children
+	// (the && stops at the first child that was asked to stop)
+	return qt.northWest.visitRecursive(rangeRect, keep, fn) &&
+		qt.northEast.visitRecursive(rangeRect, keep, fn) &&
+		qt.southWest.visitRecursive(rangeRect, keep, fn) &&
+		qt.southEast.visitRecursive(rangeRect, keep, fn)
+}
+
+// queryRecursive is the internal helper that performs the search.
+// If keep is not nil, only the points it accepts are appended to 'found'.
+// Expired points (see InsertWithTTL) are left out.
+func (qt *QuadTreeOf[T]) queryRecursive(rangeRect *Boundary, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
+	keep = qt.expiry.live(keep)
+	// With one lock per node, only the leaves are locked (see readfree.go)
+	if qt.readFree() {
+		qt.queryReadFree(rangeRect, keep, found)
+	} else {
+		qt.queryNested(rangeRect, keep, found)
+	}
+}
+
+// queryNested is the recursive search holding the Read Lock of every node
+// on the way down
+func (qt *QuadTreeOf[T]) queryNested(rangeRect *Boundary, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
 	// Acquire a Read Lock (RLock).
 	// This allows *multiple* queries to run at the same time,
 	// but blocks if an Insert() is writing.
-	qt.mu.RLock()
+	qt.rlock()
 	// Release the Read Lock when the function exits
-	defer qt.mu.RUnlock()
+	defer qt.runlock()
 
 	// --- The Core Optimization ---
 	// If the query area (rangeRect) doesn't even overlap
 	// with this node's boundary, stop searching.
 	// This "prunes" entire branches of the tree.
-	if !qt.boundary.Intersects(rangeRect) {
+	if !qt.intersects(rangeRect) {
 		return
 	}
 
 	// If this is a "leaf" node (it has points, no children)...
 	if qt.northWest == nil {
 		// ...check every point in this node's list
-		for _, p := range qt.points {
-			// If the point is inside the query area...
-			if rangeRect.Contains(p) {
-				// ...add it to the results
-				*found = append(*found, p)
-			}
-		}
+		qt.scanLeaf(rangeRect, keep, found)
 		// We are a leaf, so we are done
 		return
 	}
 
 	// If this is a "parent" node (it has children)...
-	// ...recursively call queryRecursive on all four children.
+	// ...recursively call queryNested on all four children.
 	// They will each run the 'Intersects' check (step 1).
-	qt.northWest.queryRecursive(rangeRect, found)
-	qt.northEast.queryRecursive(rangeRect, found)
-	qt.southWest.queryRecursive(rangeRect, found)
-	qt.southEast.queryRecursive(rangeRect, found)
+	qt.northWest.queryNested(rangeRect, keep, found)
+	qt.northEast.queryNested(rangeRect, keep, found)
+	qt.southWest.queryNested(rangeRect, keep, found)
+	qt.southEast.queryNested(rangeRect, keep, found)
+}
+
+// scanLeaf appends the points of this leaf within rangeRect (and accepted
+// by keep, if not nil) to 'found'. The caller holds this node's Read Lock.
+func (qt *QuadTreeOf[T]) scanLeaf(rangeRect *Boundary, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
+	// The coordinates come from the contiguous 'xy' (see leaf.go):
+	// the Point itself is only touched if it matches.
+	for i, p := range qt.points {
+		// If the point is inside the query area (and accepted by keep)...
+		if rangeRect.ContainsXY(qt.xy[2*i], qt.xy[2*i+1]) && (keep == nil || keep(p)) {
+			// ...add it to the results
+			*found = append(*found, p)
+		}
+	}
+}
+
+// Remove finds and removes a specific point from the tree.
+// The point is matched by value (X, Y and Data): if several stored
+// points are equal, p itself goes when it is one of them.
+func (qt *QuadTreeOf[T]) Remove(p *PointOf[T]) bool {
+	return qt.removePoint(p, false)
 }
 
-// Remove finds and removes a specific point from the tree
-func (qt *QuadTree) Remove(p *Point) bool {
+// removePoint is Remove, matching only p itself with exact
+func (qt *QuadTreeOf[T]) removePoint(p *PointOf[T], exact bool) bool {
+	// With an ID index, hold its lock while changing the tree
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+
+	removed := qt.remove(p, exact)
+	if removed == nil {
+		return false
+	}
+	qt.ids.dropLocked(removed)
+
+	// Keep the label and expiry indexes in sync with the tree
+	qt.labels.drop(removed)
+	qt.expiry.drop(removed)
+	qt.observeRemove(1)
+	return true
+}
+
+// remove is the internal helper that performs the removal.
+// It returns the stored point that was removed, or nil if none matched.
+// With exact, only p itself matches, not another point equal to it.
+func (qt *QuadTreeOf[T]) remove(p *PointOf[T], exact bool) *PointOf[T] {
+	// With one lock per node, only the leaf is locked for writing
+	if qt.nodeLocks() {
+		removed, emptied := qt.removeShared(p, exact)
+		if emptied {
+			qt.collapse(p.X, p.Y)
+		}
+		return removed
+	}
+	return qt.removeRecursive(p, exact)
+}
+
+// removeRecursive is the recursive removal, holding the lock of every
+// node on the way down
+func (qt *QuadTreeOf[T]) removeRecursive(p *PointOf[T], exact bool) *PointOf[T] {
 
 	// Acquire a Write Lock (we are modifying the tree)
-	qt.mu.Lock()
-	defer qt.mu.Unlock()
+	qt.lockForWrite()
+	defer qt.unlockForWrite()
 
 	// If the point can't exist in this boundary, return failure
-	if !qt.boundary.Contains(p) {
-		return false
+	if !qt.contains(p) {
+		return nil
 	}
 
 	// If this is a "parent" node (it has children)...
 	if qt.northWest != nil {
-		// ...recursively call Remove on the correct child
-		if qt.northWest.Remove(p) {
-			return true
-		}
-		if qt.northEast.Remove(p) {
-			return true
-		}
-		if qt.southWest.Remove(p) {
-			return true
-		}
-		if qt.southEast.Remove(p) {
-			return true
+		// ...recursively call remove on the correct child
+		for _, child := range []*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+			if removed := child.removeRecursive(p, exact); removed != nil {
+				// One less point in this subtree
+				// If the whole subtree is now empty, drop the children and
+				// become a leaf again (recovers memory after many removes).
+				// Our children already did the same, so this collapses
+				// recursively up the tree. The fixed shard levels are never
+				// collapsed: we only hold a Read Lock on them.
+				if qt.size.Add(-1) == 0 && !qt.fixed {
+					qt.dropChildren()
+				}
+				return removed
+			}
 		}
 		// Point not found in any child
-		return false
+		return nil
 	}
 
-	// If this is a "leaf" node...
-	// ...find the exact index of the point in our list
+	// If this is a "leaf" node, remove it from the list
+	return qt.removeFromLeaf(p, exact)
+}
+
+// removeFromLeaf removes p from this leaf's list and returns the stored
+// point, or nil if none matched (with exact, only p itself matches).
+// The caller holds the Write Lock of this node.
+func (qt *QuadTreeOf[T]) removeFromLeaf(p *PointOf[T], exact bool) *PointOf[T] {
+	// Find the exact index of the point in our list
 	foundIndex := -1
 	for i, pt := range qt.points {
-		// We must check for an *exact* match (X, Y, and Data)
-		if pt.Data == p.Data && pt.X == p.X && pt.Y == p.Y {
-			foundIndex = i
-			break
+		if exact {
+			if pt == p {
+				foundIndex = i
+				break
+			}
+			continue
+		}
+		// We must check for an *exact* match (X, Y, and Data).
+		// The coordinates come first: they are in the contiguous 'xy'.
+		if qt.xy[2*i] == p.X && qt.xy[2*i+1] == p.Y && pt.Data == p.Data {
+			// Among equal points, prefer p itself (when the caller
+			// holds a stored point, that exact one goes)
+			if foundIndex == -1 || pt == p {
+				foundIndex = i
+			}
+			if pt == p {
+				break
+			}
 		}
 	}
 
 	// If the point was not found in our list
 	if foundIndex == -1 {
-		return false
+		return nil
 	}
+	removed := qt.points[foundIndex]
 
 	// --- O(1) Slice Removal ---
-	// "Swap and Pop" trick:
-	// 1. Overwrite the element-to-remove with the *last* element in the slice
-	qt.points[foundIndex] = qt.points[len(qt.points)-1]
-	// 2. Reslice the slice to be one element shorter, dropping the (now duplicated) last element
-	qt.points = qt.points[:len(qt.points)-1]
+	// "Swap and Pop" trick: the last point takes the place of the removed one
+	qt.deletePoint(foundIndex)
+	qt.size.Add(-1)
+
+	return removed
+}
+
+// Update moves the point 'old' to (newX, newY).
+// The stored point is never modified in place (callers may still be reading it):
+// it is replaced by a new point with the same Data, which is returned.
+// Labels follow the point. It returns nil, leaving the tree untouched,
+// if 'old' is not in the tree or the new position is outside the boundary.
+// Note: a concurrent query may briefly see neither the old nor the new point.
+func (qt *QuadTreeOf[T]) Update(old *PointOf[T], newX, newY float64) *PointOf[T] {
+	// With an ID index, hold its lock while changing the tree
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+	return qt.updateLocked(old, newX, newY)
+}
+
+// updateLocked is Update for callers already holding the ID index lock (if any)
+func (qt *QuadTreeOf[T]) updateLocked(old *PointOf[T], newX, newY float64) *PointOf[T] {
+	return qt.replaceLocked(old, newX, newY, old.Data)
+}
+
+// replaceLocked replaces 'old' with a new point at (newX, newY) carrying data
+// (the caller holds the ID index lock, if any)
+func (qt *QuadTreeOf[T]) replaceLocked(old *PointOf[T], newX, newY float64, data T) *PointOf[T] {
+	// With pooling, the new point may be a recycled one
+	moved := qt.pool.point(newX, newY, data)
+
+	// Check the destination first, so a bad position never removes anything
+	qt.rlock()
+	inside := qt.contains(moved)
+	qt.runlock()
+	if !inside {
+		qt.ReleasePoint(moved)
+		return nil
+	}
+
+	removed := qt.remove(old, false)
+	if removed == nil {
+		qt.ReleasePoint(moved)
+		return nil
+	}
+	qt.insert(moved)
+
+	// Keep the label and ID indexes in sync with the tree
+	qt.labels.move(removed, moved)
+	qt.expiry.move(removed, moved)
+	qt.ids.moveLocked(removed, moved)
+	qt.observeMove()
+	return moved
+}
+
+// RangeDelete removes every point contained within the given area
+// and returns how many points were actually removed.
+// It is RemoveInArea under another name, so it does not first collect
+// the points with queryRecursive and then delete them leaf by leaf with
+// swap-and-pop: between the two passes the Read Locks are released, and
+// the points inserted or moved into the area meanwhile would be missed.
+// RemoveInArea finds and removes the points in a single traversal,
+// holding the Write Lock of each leaf while it filters it in place
+// (as cheap as swap-and-pop, and the remaining points keep their order).
+func (qt *QuadTreeOf[T]) RangeDelete(area *Boundary) int {
+	return qt.RemoveInArea(area)
+}
+
+// RemoveInArea removes every point contained within rangeRect in a single
+// traversal, and returns how many points were removed. The subtrees left
+// empty are collapsed back into leaves, like Remove does.
+// It is much faster than a Query followed by one Remove per point.
+func (qt *QuadTreeOf[T]) RemoveInArea(rangeRect *Boundary) int {
+	// With an ID index, hold its lock while changing the tree
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+
+	removed := []*PointOf[T]{}
+	qt.removeInAreaRecursive(rangeRect, &removed)
+
+	// Keep the label and ID indexes in sync with the tree
+	for _, p := range removed {
+		qt.labels.drop(p)
+		qt.expiry.drop(p)
+		qt.ids.dropLocked(p)
+	}
+	qt.observeRemove(len(removed))
+	return len(removed)
+}
+
+// removeInAreaRecursive is the internal helper of RemoveInArea.
+// It appends the removed points to 'removed'.
+func (qt *QuadTreeOf[T]) removeInAreaRecursive(rangeRect *Boundary, removed *[]*PointOf[T]) {
+	// Acquire a Write Lock (we are modifying the tree)
+	qt.lockForWrite()
+	defer qt.unlockForWrite()
+
+	// Prune the branches outside the area
+	if !qt.intersects(rangeRect) {
+		return
+	}
+
+	before := len(*removed)
+
+	// If this is a "leaf" node, keep only the points outside the area
+	if qt.northWest == nil {
+		// Filter in place: 'kept' and 'keptXY' reuse the same backing arrays
+		kept, keptXY := qt.points[:0], qt.xy[:0]
+		for i, p := range qt.points {
+			x, y := qt.xy[2*i], qt.xy[2*i+1]
+			if rangeRect.ContainsXY(x, y) {
+				*removed = append(*removed, p)
+			} else {
+				kept = append(kept, p)
+				keptXY = append(keptXY, x, y)
+			}
+		}
+		// Drop the leftover pointers at the end, so the points can be freed
+		clear(qt.points[len(kept):])
+		qt.points, qt.xy = kept, keptXY
+		qt.size.Add(-int64(len(*removed) - before))
+		return
+	}
+
+	// If this is a "parent" node, clean up the four children
+	qt.northWest.removeInAreaRecursive(rangeRect, removed)
+	qt.northEast.removeInAreaRecursive(rangeRect, removed)
+	qt.southWest.removeInAreaRecursive(rangeRect, removed)
+	qt.southEast.removeInAreaRecursive(rangeRect, removed)
+
+	// Collapse this subtree if it is now empty (never the fixed shard levels)
+	if qt.size.Add(-int64(len(*removed)-before)) == 0 && !qt.fixed {
+		qt.dropChildren()
+	}
+}
+
+// Clear removes every point and collapses the tree back to a single empty
+// root (the fixed shard levels are kept), reusing the same *QuadTree so
+// existing references stay valid. Boundary, capacity and options are kept.
+func (qt *QuadTreeOf[T]) Clear() {
+	// Hold the root Write Lock: every operation enters through the root,
+	// so once the ones already inside are done (drain) nobody is inside
+	// the tree while we take it apart
+	// (the ID index lock, if any, comes first: see idIndex)
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+		clear(qt.ids.byID)
+	}
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	qt.drain()
+
+	removed := int(qt.size.Load())
+	qt.clearRecursive()
+
+	// The labels and TTLs belonged to the removed points
+	qt.labels.reset()
+	qt.expiry.reset()
+	qt.observeRemove(removed)
+}
+
+// clearRecursive empties this subtree (the caller holds the root Write Lock)
+func (qt *QuadTreeOf[T]) clearRecursive() {
+	// Keep the backing arrays, but drop the pointers so the points can be freed
+	qt.resetPoints()
+	qt.size.Store(0)
+
+	// The fixed shard levels keep their children, emptied
+	if qt.fixed {
+		for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+			child.clearRecursive()
+		}
+		return
+	}
+	qt.dropChildren()
+}
+
+// Count returns the total number of points stored in the tree
+// without allocating a result slice
+func (qt *QuadTreeOf[T]) Count() int {
+	// Every node keeps the size of its subtree up to date,
+	// so the root already knows the total (atomic: no lock needed)
+	return int(qt.size.Load())
+}
+
+// TotalPoints is Count as an int64: it reads the root's atomic counter,
+// which every successful Insert/Remove increments/decrements, without a lock
+func (qt *QuadTreeOf[T]) TotalPoints() int64 {
+	return qt.size.Load()
+}
+
+// AllPoints returns every point stored in the tree (a full traversal)
+func (qt *QuadTreeOf[T]) AllPoints() []*PointOf[T] {
+	all := make([]*PointOf[T], 0, qt.Count())
+	qt.ForEach(func(p *PointOf[T]) bool {
+		all = append(all, p)
+		return true
+	})
+	return all
+}
+
+// CountInRange returns the number of points within a specific area
+// without materializing them in a slice. A subtree lying entirely inside
+// the area is counted from its stored size, without visiting it.
+// Expired points (see InsertWithTTL) are not counted: while the tree
+// holds TTLs, every point is looked at. See BenchmarkCountInRange.
+func (qt *QuadTreeOf[T]) CountInRange(rangeRect *Boundary) int {
+	return qt.countRecursive(rangeRect, qt.expiry.live(nil))
+}
+
+// PointCount returns the number of points within area, like len(Query(area)),
+// but without allocating: the points are counted where they are stored
+// (e.g. for the density tiles of a dashboard). It counts the same points
+// as CountInRange, expired ones excluded.
+func (qt *QuadTreeOf[T]) PointCount(area *Boundary) int {
+	return qt.countRecursive(area, qt.expiry.live(nil))
+}
+
+// countRecursive is the internal helper of CountInRange: only the points
+// accepted by keep are counted (nil: all of them, using the stored sizes)
+func (qt *QuadTreeOf[T]) countRecursive(rangeRect *Boundary, keep func(*PointOf[T]) bool) int {
+	qt.rlock()
+	defer qt.runlock()
+
+	// Same pruning as queryRecursive: skip branches outside the area
+	if !qt.intersects(rangeRect) {
+		return 0
+	}
+
+	// --- Fast path ---
+	// If this whole node lies inside the area, every point
+	// of the subtree matches: no need to look at them
+	if keep == nil && qt.insideRect(rangeRect) {
+		return int(qt.size.Load())
+	}
+
+	// If this is a "leaf" node, count the points inside the area
+	if qt.northWest == nil {
+		count := 0
+		for i, p := range qt.points {
+			if rangeRect.ContainsXY(qt.xy[2*i], qt.xy[2*i+1]) && (keep == nil || keep(p)) {
+				count++
+			}
+		}
+		return count
+	}
+
+	// If this is a "parent" node, sum the counts of the four children
+	return qt.northWest.countRecursive(rangeRect, keep) +
+		qt.northEast.countRecursive(rangeRect, keep) +
+		qt.southWest.countRecursive(rangeRect, keep) +
+		qt.southEast.countRecursive(rangeRect, keep)
+}
+
+// intersects checks if this node can hold a point contained by rangeRect.
+// It follows Boundary.Intersects, but a node lying on the world's East/North
+// edge also holds the points exactly on that edge, so a rangeRect starting
+// exactly there must not prune it (Boundary.Intersects would).
+func (qt *QuadTreeOf[T]) intersects(rangeRect *Boundary) bool {
+	rMinX := rangeRect.X - rangeRect.Width
+	rMinY := rangeRect.Y - rangeRect.Height
+
+	// West and South: the area is exclusive on its max edges,
+	// so it must end strictly after this node's min edges
+	if qt.minX >= rangeRect.X+rangeRect.Width || qt.minY >= rangeRect.Y+rangeRect.Height {
+		return false
+	}
+
+	// East and North: the area must start before this node's max edges,
+	// or exactly on them when the node's own edge is closed
+	if qt.maxX < rMinX || (qt.maxX == rMinX && !qt.closedEast) {
+		return false
+	}
+	if qt.maxY < rMinY || (qt.maxY == rMinY && !qt.closedNorth) {
+		return false
+	}
+
+	return true
+}
+
+// insideRect checks if this whole node lies inside rangeRect,
+// i.e. every point this node can hold is also contained by rangeRect
+func (qt *QuadTreeOf[T]) insideRect(rangeRect *Boundary) bool {
+	rMaxX := rangeRect.X + rangeRect.Width
+	rMaxY := rangeRect.Y + rangeRect.Height
+
+	// West and South: the area must start at or before this node
+	if rangeRect.X-rangeRect.Width > qt.minX || rangeRect.Y-rangeRect.Height > qt.minY {
+		return false
+	}
+
+	// East and North: the area is exclusive on its max edges, so it must
+	// end strictly after this node when the node's own edge is closed
+	if qt.maxX > rMaxX || (qt.maxX == rMaxX && qt.closedEast) {
+		return false
+	}
+	if qt.maxY > rMaxY || (qt.maxY == rMaxY && qt.closedNorth) {
+		return false
+	}
 
 	return true
 }
+
+// ForEach calls fn for every point stored in the tree, without
+// building a result slice. The traversal stops as soon as fn returns false.
+// Read Locks are held during the traversal, so fn must not modify the tree
+// (Insert/Remove from inside fn would deadlock).
+func (qt *QuadTreeOf[T]) ForEach(fn func(p *PointOf[T]) bool) {
+	qt.forEachRecursive(fn)
+}
+
+// forEachRecursive is the internal helper that performs the traversal.
+// It returns false if fn asked to stop.
+func (qt *QuadTreeOf[T]) forEachRecursive(fn func(p *PointOf[T]) bool) bool {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// If this is a "leaf" node, visit every point in its list
+	if qt.northWest == nil {
+		for _, p := range qt.points {
+			if !fn(p) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// If this is a "parent" node, visit the four children
+	// (the && stops at the first child that was asked to stop)
+	return qt.northWest.forEachRecursive(fn) &&
+		qt.northEast.forEachRecursive(fn) &&
+		qt.southWest.forEachRecursive(fn) &&
+		qt.southEast.forEachRecursive(fn)
+}
+
+// Merge inserts a copy of every point of 'other' into this tree
+// and returns how many points were successfully inserted.
+// Points outside this tree's boundary are silently skipped.
+// The source keeps its Read Locks during the whole traversal, while this
+// tree takes its Write Lock once per insert: two trees must not be merged
+// into each other at the same time, and merging a tree into itself is a no-op.
+func (qt *QuadTreeOf[T]) Merge(other *QuadTreeOf[T]) int {
+	// Inserting while ForEach holds our own Read Lock would deadlock
+	if other == qt {
+		return 0
+	}
+
+	inserted := 0
+	other.ForEach(func(p *PointOf[T]) bool {
+		// Copy the point: the two trees must not share their storage
+		if qt.Insert(&PointOf[T]{X: p.X, Y: p.Y, Data: p.Data}) {
+			inserted++
+		}
+		return true
+	})
+
+	return inserted
+}
+
+// shardLevels returns the number of fixed (pre-split) levels of the tree
+func (qt *QuadTreeOf[T]) shardLevels() int {
+	levels := 0
+	for node := qt; node.fixed; node = node.northWest {
+		levels++
+	}
+	return levels
+}
+
+// Clone returns a fully independent deep copy of the tree: every node,
+// every points slice and every Point struct is copied, so later changes
+// to the original never affect the clone (and vice versa).
+// Labels, TTLs and the ID index are copied too, attached to the cloned points.
+// No writer can change the source during the copy (see lockWhole), so the
+// clone is one consistent state of it, whatever the lock mode.
+func (qt *QuadTreeOf[T]) Clone() *QuadTreeOf[T] {
+	// Only track old -> new points when there are labels or IDs to carry over
+	var remap map[*PointOf[T]]*PointOf[T]
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+	// The node locks come before the label and expiry locks
+	// (the searches take the expiry lock under a leaf lock, see expiryIndex.live)
+	qt.lockWhole()
+	defer qt.unlockWhole()
+	qt.labels.mu.RLock()
+	defer qt.labels.mu.RUnlock()
+	qt.expiry.mu.RLock()
+	defer qt.expiry.mu.RUnlock()
+	if len(qt.labels.byPoint) > 0 || len(qt.expiry.byPoint) > 0 || qt.ids != nil {
+		remap = make(map[*PointOf[T]]*PointOf[T], len(qt.labels.byPoint))
+	}
+
+	clone := qt.cloneNode(remap, nil)
+	clone.ids = qt.ids.remapped(remap)
+
+	for old, labels := range qt.labels.byPoint {
+		// A labelled point may have been removed while we were cloning
+		if p, ok := remap[old]; ok {
+			clone.labels.set(p, labels)
+		}
+	}
+
+	// The TTLs keep running out at the same moment, on the same clock
+	clone.expiry.now = qt.expiry.now
+	for old, e := range qt.expiry.byPoint {
+		if p, ok := remap[old]; ok {
+			if clone.expiry.byPoint == nil {
+				clone.expiry.byPoint = map[*PointOf[T]]expiry{}
+			}
+			clone.expiry.byPoint[p] = e
+			clone.expiry.count.Add(1)
+		}
+	}
+
+	return clone
+}
+
+// cloneRoot copies the whole tree, with every writer stopped (see
+// lockWhole): the copy is one state the tree really went through.
+// Only the points accepted by keep are copied (nil: all of them).
+func (qt *QuadTreeOf[T]) cloneRoot(remap map[*PointOf[T]]*PointOf[T], keep func(*PointOf[T]) bool) *QuadTreeOf[T] {
+	qt.lockWhole()
+	defer qt.unlockWhole()
+	return qt.cloneNode(remap, keep)
+}
+
+// cloneRecursive copies this node and its subtree, keeping only the
+// points accepted by keep (nil: all of them).
+// If remap is not nil, it records which copy belongs to which original point.
+func (qt *QuadTreeOf[T]) cloneRecursive(remap map[*PointOf[T]]*PointOf[T], keep func(*PointOf[T]) bool) *QuadTreeOf[T] {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+	return qt.cloneNode(remap, keep)
+}
+
+// cloneNode is cloneRecursive for a node the caller already holds locked
+func (qt *QuadTreeOf[T]) cloneNode(remap map[*PointOf[T]]*PointOf[T], keep func(*PointOf[T]) bool) *QuadTreeOf[T] {
+	clone := &QuadTreeOf[T]{
+		boundary:    qt.boundary,
+		capacity:    qt.capacity,
+		points:      make([]*PointOf[T], 0, max(len(qt.points), qt.capacity)),
+		xy:          make([]float64, 0, 2*max(len(qt.points), qt.capacity)),
+		depth:       qt.depth,
+		maxDepth:    qt.maxDepth,
+		minX:        qt.minX,
+		maxX:        qt.maxX,
+		minY:        qt.minY,
+		maxY:        qt.maxY,
+		closedEast:  qt.closedEast,
+		closedNorth: qt.closedNorth,
+		fixed:       qt.fixed,
+		singleLock:  qt.singleLock,
+		noLock:      qt.noLock,
+		distance:    qt.distance,
+		pool:        qt.pool,
+	}
+
+	// If this is a "leaf" node, copy every Point struct (not just the pointers)
+	if qt.northWest == nil {
+		for _, p := range qt.points {
+			if keep != nil && !keep(p) {
+				continue
+			}
+			cp := &PointOf[T]{X: p.X, Y: p.Y, Data: p.Data}
+			clone.appendPoint(cp)
+			if remap != nil {
+				remap[p] = cp
+			}
+		}
+		clone.size.Store(int64(len(clone.points)))
+		return clone
+	}
+
+	// If this is a "parent" node, clone the four children
+	clone.northWest = qt.northWest.cloneRecursive(remap, keep)
+	clone.northEast = qt.northEast.cloneRecursive(remap, keep)
+	clone.southWest = qt.southWest.cloneRecursive(remap, keep)
+	clone.southEast = qt.southEast.cloneRecursive(remap, keep)
+	clone.publishChildren()
+	// The size is counted from what was copied, not read from qt: with one
+	// lock per node it may have changed between this node and its children
+	clone.size.Store(clone.northWest.size.Load() + clone.northEast.size.Load() +
+		clone.southWest.size.Load() + clone.southEast.size.Load())
+	return clone
+}
@@ -1,6 +1,15 @@
 package quadtree // Declares that this file is part of the "quadtree" package
 
-import "testing" // Imports Go's standard testing framework
+import (
+	"errors"    // errors.Is for the InsertE errors
+	"fmt"       // Point names for the NaN test
+	"math"      // NaN and Inf for the NaN test
+	"math/rand" // Random points for the concurrent tests
+	"strings"   // Prefix matching for the filter tests
+	"sync"      // WaitGroup for the concurrent tests
+	"testing"   // Imports Go's standard testing framework
+	"time"      // TTLs for the count tests
+)
 
 // TestNewQuadTree (You have a typo here, it should be TestNewQuadTree)
 // This function tests the NewQuadTree "constructor".
@@ -157,3 +166,1235 @@ func TestQuadTreeQuery(t *testing.T) {
 		t.Fatalf("Query South: 2 points expected (p3, p4), found %d", len(foundSouth))
 	}
 }
+
+// TestQuadTreeRangeDelete verifies that every point inside an area
+// is removed, while points outside of it are left untouched.
+func TestQuadTreeRangeDelete(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	qt.Insert(&Point{X: -50, Y: 50, Data: "p1 (NW)"})
+	qt.Insert(&Point{X: 50, Y: 50, Data: "p2 (NE)"})
+	qt.Insert(&Point{X: -50, Y: -50, Data: "p3 (SW)"}) // Forces subdivision
+	qt.Insert(&Point{X: 50, Y: -50, Data: "p4 (SE)"})
+	qt.Insert(&Point{X: 60, Y: 60, Data: "p5 (NE, extra)"})
+
+	// --- Test 1: Delete the whole North-East quadrant ---
+	removed := qt.RangeDelete(&Boundary{X: 50, Y: 50, Width: 50, Height: 50})
+
+	// p2 and p5 must have been removed
+	if removed != 2 {
+		t.Fatalf("RangeDelete NE: 2 points expected to be removed, got %d", removed)
+	}
+
+	// The NE quadrant must now be empty...
+	if found := qt.Query(&Boundary{X: 50, Y: 50, Width: 50, Height: 50}); len(found) != 0 {
+		t.Errorf("NE query after RangeDelete: 0 points expected, %d found", len(found))
+	}
+	// ...while the other 3 points must still be there
+	if found := qt.Query(&Boundary{X: 0, Y: 0, Width: 100, Height: 100}); len(found) != 3 {
+		t.Errorf("Query All after RangeDelete: 3 points expected, %d found", len(found))
+	}
+
+	// --- Test 2: Delete an area that is already empty ---
+	if removed := qt.RangeDelete(&Boundary{X: 50, Y: 50, Width: 50, Height: 50}); removed != 0 {
+		t.Errorf("RangeDelete on an empty area: 0 points expected to be removed, got %d", removed)
+	}
+}
+
+// TestQuadTreeRangeDeleteNodeLocks runs RangeDelete on a tree with node
+// locks while other goroutines insert, and checks that every point of the
+// area is either counted as removed or still in the tree afterwards
+func TestQuadTreeRangeDeleteNodeLocks(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	area := &Boundary{X: 90, Y: 45, Width: 90, Height: 45} // The NE quarter
+	qt := NewQuadTree(world, 4, WithNodeLocks())
+
+	// 1000 points inside the area before the delete
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&Point{X: rng.Float64() * 179, Y: rng.Float64() * 89, Data: fmt.Sprint("before ", i)})
+	}
+
+	// --- Test 1: concurrent inserts, inside and outside the area ---
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w + 2)))
+			for i := 0; i < 500; i++ {
+				x, y := rng.Float64()*179, rng.Float64()*89
+				if i%2 == 1 {
+					x = -x // Outside the area
+				}
+				qt.Insert(&Point{X: x, Y: y, Data: fmt.Sprint(w, " ", i)})
+			}
+		}(w)
+	}
+	removed := 0
+	for i := 0; i < 5; i++ {
+		removed += qt.RangeDelete(area)
+	}
+	wg.Wait()
+
+	// --- Test 2: nothing lost, nothing removed twice ---
+	inside := 1000 + 4*250
+	left := len(qt.Query(area))
+	if removed < 1000 || removed+left != inside {
+		t.Errorf("%d points inside the area: removed %d + left %d expected to match", inside, removed, left)
+	}
+	if qt.Count() != 4*250+left {
+		t.Errorf("Count: %d expected (the points outside and those left), got %d", 4*250+left, qt.Count())
+	}
+}
+
+// TestQuadTreeCount verifies that Count and CountInRange
+// agree with the number of points returned by Query.
+func TestQuadTreeCount(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// --- Test 1: An empty tree has no points ---
+	if count := qt.Count(); count != 0 {
+		t.Fatalf("Count on empty tree: 0 expected, got %d", count)
+	}
+
+	qt.Insert(&Point{X: -50, Y: 50, Data: "p1 (NW)"})
+	qt.Insert(&Point{X: 50, Y: 50, Data: "p2 (NE)"})
+	qt.Insert(&Point{X: -50, Y: -50, Data: "p3 (SW)"}) // Forces subdivision
+	qt.Insert(&Point{X: 50, Y: -50, Data: "p4 (SE)"})
+	qt.Insert(&Point{X: 60, Y: 60, Data: "p5 (NE, extra)"})
+
+	// --- Test 2: Count must see all 5 points across the children ---
+	if count := qt.Count(); count != 5 {
+		t.Errorf("Count: 5 expected, got %d", count)
+	}
+
+	// --- Test 3: CountInRange must match the Query results ---
+	areas := []*Boundary{
+		{X: 50, Y: 50, Width: 50, Height: 50},  // NE quadrant
+		{X: 0, Y: 0, Width: 10, Height: 10},    // Empty center
+		{X: 0, Y: -50, Width: 100, Height: 50}, // South half
+		{X: 0, Y: 0, Width: 100, Height: 100},  // Whole map
+	}
+	for _, area := range areas {
+		expected := len(qt.Query(area))
+		if count := qt.CountInRange(area); count != expected {
+			t.Errorf("CountInRange(%+v): %d expected, got %d", *area, expected, count)
+		}
+	}
+}
+
+// TestQuadTreeCountInRangeAllocs verifies that CountInRange counts
+// the points of an area without building a result slice.
+func TestQuadTreeCountInRangeAllocs(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&Point{X: float64(i%100) - 50, Y: float64(i/10) - 50, Data: i})
+	}
+	area := &Boundary{X: 10, Y: 10, Width: 30, Height: 30}
+
+	// --- Test 1: The count must match the Query results ---
+	expected := len(qt.Query(area))
+	if count := qt.CountInRange(area); count != expected {
+		t.Fatalf("CountInRange: %d expected, got %d", expected, count)
+	}
+
+	// --- Test 2: Counting must not allocate, unlike Query ---
+	if allocs := testing.AllocsPerRun(100, func() { qt.CountInRange(area) }); allocs != 0 {
+		t.Errorf("CountInRange: 0 allocations expected, got %.0f", allocs)
+	}
+}
+
+// TestQuadTreePointCount verifies that PointCount agrees with Query,
+// including the expired points it leaves out, without allocating.
+func TestQuadTreePointCount(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4, WithClock(clock.Now))
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&Point{X: float64(i%100) - 50, Y: float64(i/10) - 50, Data: i})
+	}
+	areas := []*Boundary{
+		{X: 10, Y: 10, Width: 30, Height: 30},  // Partly inside some nodes
+		{X: 0, Y: 0, Width: 100, Height: 100},  // Whole map
+		{X: 80, Y: 80, Width: 10, Height: 10},  // Empty corner
+		{X: -25, Y: 0, Width: 25, Height: 100}, // A strip
+	}
+
+	// --- Test 1: The count must match the Query results ---
+	for _, area := range areas {
+		if expected, count := len(qt.Query(area)), qt.PointCount(area); count != expected {
+			t.Errorf("PointCount(%+v): %d expected, got %d", *area, expected, count)
+		}
+	}
+
+	// --- Test 2: Counting must not allocate ---
+	if allocs := testing.AllocsPerRun(100, func() { qt.PointCount(areas[0]) }); allocs != 0 {
+		t.Errorf("PointCount: 0 allocations expected, got %.0f", allocs)
+	}
+
+	// --- Test 3: Expired points are not counted ---
+	qt.InsertWithTTL(&Point{X: 10, Y: 10, Data: "short-lived"}, time.Minute)
+	before := qt.PointCount(areas[0])
+	clock.Advance(2 * time.Minute)
+	if count := qt.PointCount(areas[0]); count != before-1 || count != len(qt.Query(areas[0])) {
+		t.Errorf("PointCount after the TTL: %d expected, got %d", before-1, count)
+	}
+}
+
+// TestQuadTreeWorldEdges verifies that points lying exactly on the
+// world's edges are accepted by the root and can be queried back.
+func TestQuadTreeWorldEdges(t *testing.T) {
+	// Same world boundary used by the simulation
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+
+	corners := []*Point{
+		{X: 180, Y: 90, Data: "north-east corner"},   // Max edges (inclusive at the root)
+		{X: -180, Y: -90, Data: "south-west corner"}, // Min edges (always inclusive)
+		{X: 180, Y: -90, Data: "south-east corner"},
+		{X: -180, Y: 90, Data: "north-west corner"},
+	}
+
+	// --- Test 1: Every corner must be accepted ---
+	// With capacity 2 this also forces a subdivision, so the
+	// edge points must survive the redistribution into the children
+	for _, p := range corners {
+		if !qt.Insert(p) {
+			t.Errorf("Insert of %v at (%v, %v) was rejected", p.Data, p.X, p.Y)
+		}
+	}
+
+	// --- Test 2: Every corner must be found again ---
+	for _, p := range corners {
+		found := qt.Query(&Boundary{X: p.X, Y: p.Y, Width: 1, Height: 1})
+		if len(found) != 1 || found[0].Data != p.Data {
+			t.Errorf("Query around %v: 1 point expected, %d found", p.Data, len(found))
+		}
+	}
+
+	// --- Test 3: Points on the edge can also be removed ---
+	if !qt.Remove(corners[0]) {
+		t.Error("Remove of the north-east corner failed")
+	}
+
+	// --- Test 4: Points beyond the world are still rejected ---
+	if qt.Insert(&Point{X: 180.0001, Y: 0, Data: "outside"}) {
+		t.Error("Insert of a point beyond the East edge should fail")
+	}
+}
+
+// TestQuadTreeGeneric verifies that a typed tree (the driver case,
+// with string IDs) works end-to-end without any type assertion.
+func TestQuadTreeGeneric(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	p1 := &PointOf[string]{X: -50, Y: 50, Data: "driver-1"}
+	p2 := &PointOf[string]{X: 50, Y: 50, Data: "driver-2"}
+	p3 := &PointOf[string]{X: 60, Y: 60, Data: "driver-3"} // Forces subdivision
+
+	qt.Insert(p1)
+	qt.Insert(p2)
+	qt.Insert(p3)
+
+	// --- Test 1: Query returns typed points ---
+	found := qt.Query(&Boundary{X: 50, Y: 50, Width: 50, Height: 50})
+	if len(found) != 2 {
+		t.Fatalf("NE query: 2 points expected, %d found", len(found))
+	}
+	// p.Data is already a string: no p.Data.(string) needed
+	ids := map[string]bool{}
+	for _, p := range found {
+		ids[p.Data] = true
+	}
+	if !ids["driver-2"] || !ids["driver-3"] {
+		t.Errorf("NE query: driver-2 and driver-3 expected, got %v", ids)
+	}
+
+	// --- Test 2: Remove matches on the typed Data ---
+	if !qt.Remove(&PointOf[string]{X: 50, Y: 50, Data: "driver-2"}) {
+		t.Error("Remove of driver-2 failed")
+	}
+	if qt.Remove(&PointOf[string]{X: -50, Y: 50, Data: "driver-2"}) {
+		t.Error("Remove with the wrong Data should fail")
+	}
+	if count := qt.Count(); count != 2 {
+		t.Errorf("Count after Remove: 2 expected, got %d", count)
+	}
+}
+
+// TestQuadTreeMaxDepth verifies that many coincident points don't
+// cause endless subdivision: leaves at the maximum depth just grow.
+func TestQuadTreeMaxDepth(t *testing.T) {
+	cases := map[string]struct {
+		qt       *QuadTree
+		maxDepth int
+	}{
+		"default": {NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4), DefaultMaxDepth},
+		"custom":  {NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithMaxDepth(5)), 5},
+	}
+
+	for name, tc := range cases {
+		// Insert 1000 points at exactly the same coordinate
+		for i := 0; i < 1000; i++ {
+			if !tc.qt.Insert(&Point{X: 12.5, Y: 41.9, Data: i}) {
+				t.Fatalf("%s: Insert of coincident point %d was rejected", name, i)
+			}
+		}
+
+		// --- Test 1: No point was lost ---
+		if count := tc.qt.Count(); count != 1000 {
+			t.Errorf("%s: Count: 1000 expected, got %d", name, count)
+		}
+		// --- Test 2: The tree stopped subdividing at the maximum depth ---
+		if depth := maxDepthOf(tc.qt); depth != tc.maxDepth {
+			t.Errorf("%s: depth %d expected, got %d", name, tc.maxDepth, depth)
+		}
+		// --- Test 3: All the points can be found and removed ---
+		if found := tc.qt.Query(&Boundary{X: 12.5, Y: 41.9, Width: 1, Height: 1}); len(found) != 1000 {
+			t.Errorf("%s: Query: 1000 points expected, %d found", name, len(found))
+		}
+		if !tc.qt.Remove(&Point{X: 12.5, Y: 41.9, Data: 500}) {
+			t.Errorf("%s: Remove of a coincident point failed", name)
+		}
+	}
+}
+
+// maxDepthOf returns the depth of the deepest node in the tree
+func maxDepthOf[T comparable](qt *QuadTreeOf[T]) int {
+	if qt.northWest == nil {
+		return qt.depth
+	}
+	return max(maxDepthOf(qt.northWest), maxDepthOf(qt.northEast),
+		maxDepthOf(qt.southWest), maxDepthOf(qt.southEast))
+}
+
+// TestQuadTreeForEach verifies that ForEach visits every point
+// exactly once, and that it stops early when asked to.
+func TestQuadTreeForEach(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2)
+	for i := 0; i < 100; i++ {
+		qt.Insert(&Point{X: float64(i*3) - 150, Y: float64(i) - 50, Data: i})
+	}
+
+	// --- Test 1: Every point is visited exactly once ---
+	seen := map[interface{}]int{}
+	qt.ForEach(func(p *Point) bool {
+		seen[p.Data]++
+		return true
+	})
+	if len(seen) != 100 {
+		t.Errorf("ForEach: 100 distinct points expected, visited %d", len(seen))
+	}
+	for data, n := range seen {
+		if n != 1 {
+			t.Errorf("ForEach: point %v visited %d times", data, n)
+		}
+	}
+
+	// --- Test 2: Returning false stops the traversal ---
+	visited := 0
+	qt.ForEach(func(p *Point) bool {
+		visited++
+		return visited < 10
+	})
+	if visited != 10 {
+		t.Errorf("ForEach with early stop: 10 visits expected, got %d", visited)
+	}
+
+	// --- Test 3: After a random workload, exactly Count() points are visited ---
+	// (including the ones on the world's max edges, that a Query
+	// with the world boundary would miss)
+	rng := rand.New(rand.NewSource(1))
+	var live []*Point
+	for i := 0; i < 2000; i++ {
+		if len(live) > 0 && rng.Intn(3) == 0 {
+			j := rng.Intn(len(live))
+			qt.Remove(live[j])
+			live[j] = live[len(live)-1]
+			live = live[:len(live)-1]
+			continue
+		}
+		p := randomWorldPoint(rng, 1000+i)
+		if i%100 == 0 {
+			p.X, p.Y = 180, 90 // The world's North-East corner
+		}
+		qt.Insert(p)
+		live = append(live, p)
+	}
+	visited = 0
+	qt.ForEach(func(p *Point) bool {
+		visited++
+		return true
+	})
+	if visited != qt.Count() {
+		t.Errorf("ForEach after the workload: %d visits expected, got %d", qt.Count(), visited)
+	}
+}
+
+// TestQuadTreeMerge verifies that merging two shards produces a tree
+// answering queries for both of them.
+func TestQuadTreeMerge(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 100, Height: 100}
+
+	// Shard 1 owns the North-East, shard 2 the South-West
+	ne := NewQuadTree(world, 2)
+	ne.Insert(&Point{X: 50, Y: 50, Data: "ne-1"})
+	ne.Insert(&Point{X: 60, Y: 60, Data: "ne-2"})
+	ne.Insert(&Point{X: 70, Y: 70, Data: "ne-3"})
+
+	sw := NewQuadTree(world, 2)
+	sw.Insert(&Point{X: -50, Y: -50, Data: "sw-1"})
+	sw.Insert(&Point{X: -60, Y: -60, Data: "sw-2"})
+
+	// --- Test 1: Every point of the source is inserted ---
+	if inserted := ne.Merge(sw); inserted != 2 {
+		t.Fatalf("Merge: 2 points expected to be inserted, got %d", inserted)
+	}
+
+	// --- Test 2: The merged tree answers for both halves ---
+	if found := ne.Query(&Boundary{X: -50, Y: -50, Width: 50, Height: 50}); len(found) != 2 {
+		t.Errorf("SW query after Merge: 2 points expected, %d found", len(found))
+	}
+	if found := ne.Query(&Boundary{X: 50, Y: 50, Width: 50, Height: 50}); len(found) != 3 {
+		t.Errorf("NE query after Merge: 3 points expected, %d found", len(found))
+	}
+
+	// --- Test 3: The source is left untouched ---
+	if count := sw.Count(); count != 2 {
+		t.Errorf("Source after Merge: 2 points expected, got %d", count)
+	}
+
+	// --- Test 4: Out-of-boundary points are skipped ---
+	small := NewQuadTree(Boundary{X: 50, Y: 50, Width: 50, Height: 50}, 2)
+	if inserted := small.Merge(ne); inserted != 3 {
+		t.Errorf("Merge into a smaller tree: 3 points expected to be inserted, got %d", inserted)
+	}
+
+	// --- Test 5: Merging a tree into itself is a no-op ---
+	if inserted := ne.Merge(ne); inserted != 0 || ne.Count() != 5 {
+		t.Errorf("Self merge: nothing expected, got %d inserted and %d points", inserted, ne.Count())
+	}
+}
+
+// TestQuadTreeShards verifies that a sharded tree behaves exactly
+// like a single-root tree, including under concurrent writers.
+func TestQuadTreeShards(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 4, WithShards(2))
+
+	// --- Test 1: The root is pre-split into 16 fixed cells ---
+	if !qt.fixed || !qt.northWest.fixed || qt.northWest.northWest.fixed {
+		t.Fatal("WithShards(2) should create exactly 2 fixed levels")
+	}
+	if levels := qt.shardLevels(); levels != 2 {
+		t.Errorf("shardLevels: 2 expected, got %d", levels)
+	}
+
+	// --- Test 2: Concurrent inserts and removes in different shards ---
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				p := &Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: seed*1000 + int64(i)}
+				qt.Insert(p)
+				// Remove every other point again
+				if i%2 == 0 && !qt.Remove(p) {
+					t.Errorf("Remove of a just inserted point failed")
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+
+	// --- Test 3: Counts and queries agree ---
+	if count := qt.Count(); count != 8*250 {
+		t.Errorf("Count: %d expected, got %d", 8*250, count)
+	}
+	if found := qt.Query(&world); len(found) != qt.Count() {
+		t.Errorf("Query All: %d points expected, %d found", qt.Count(), len(found))
+	}
+
+	// --- Test 4: The shards survive a JSON round-trip ---
+	data, err := qt.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	loaded, err := LoadQuadTree(data)
+	if err != nil {
+		t.Fatalf("LoadQuadTree failed: %v", err)
+	}
+	if loaded.shardLevels() != 2 || loaded.Fingerprint() != qt.Fingerprint() {
+		t.Error("Sharded tree not preserved by the JSON round-trip")
+	}
+}
+
+// TestQuadTreeClone verifies that a clone shares no memory with the original.
+func TestQuadTreeClone(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	p1 := &Point{X: -50, Y: 50, Data: "p1 (NW)"}
+	qt.InsertWithLabels(p1, "premium")
+	qt.Insert(&Point{X: 50, Y: 50, Data: "p2 (NE)"})
+	qt.Insert(&Point{X: -50, Y: -50, Data: "p3 (SW)"}) // Forces subdivision
+
+	clone := qt.Clone()
+
+	// --- Test 1: Same structure and content ---
+	if clone.Fingerprint() != qt.Fingerprint() || clone.Count() != 3 {
+		t.Fatal("Clone differs from the original")
+	}
+
+	// --- Test 2: The Point structs are copies, not shared pointers ---
+	cp := clone.Query(&Boundary{X: -50, Y: 50, Width: 1, Height: 1})
+	if len(cp) != 1 || cp[0] == p1 {
+		t.Fatal("Clone shares Point pointers with the original")
+	}
+	// ...and the labels followed the copies
+	if labels := clone.Labels(cp[0]); len(labels) != 1 || labels[0] != "premium" {
+		t.Errorf("Clone labels: [premium] expected, got %v", labels)
+	}
+
+	// --- Test 3: Changes to the original don't affect the clone ---
+	qt.Insert(&Point{X: 50, Y: -50, Data: "p4 (SE)"})
+	qt.Remove(p1)
+	if clone.Count() != 3 {
+		t.Errorf("Clone Count after changing the original: 3 expected, got %d", clone.Count())
+	}
+	if len(clone.Query(&Boundary{X: -50, Y: 50, Width: 1, Height: 1})) != 1 {
+		t.Error("Point removed from the original disappeared from the clone")
+	}
+
+	// --- Test 4: Changes to the clone don't affect the original ---
+	clone.RangeDelete(&Boundary{X: 0, Y: 0, Width: 100, Height: 100})
+	if clone.Count() != 0 || qt.Count() != 3 {
+		t.Errorf("After emptying the clone: 0/3 expected, got %d/%d", clone.Count(), qt.Count())
+	}
+}
+
+// TestQuadTreeCloneRemoveAll removes every point from a clone one by
+// one and checks that the original keeps all of its points.
+func TestQuadTreeCloneRemoveAll(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 500; i++ {
+		qt.Insert(&Point{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: i})
+	}
+	clone := qt.Clone()
+
+	// --- Test 1: Every point of the clone can be removed from it ---
+	for _, p := range clone.AllPoints() {
+		if !clone.Remove(p) {
+			t.Fatalf("Remove(%v) from the clone failed", p.Data)
+		}
+	}
+	if clone.Count() != 0 {
+		t.Errorf("Clone Count after removing everything: 0 expected, got %d", clone.Count())
+	}
+
+	// --- Test 2: The original is untouched ---
+	if qt.Count() != 500 || len(qt.AllPoints()) != 500 {
+		t.Errorf("Original after emptying the clone: 500 expected, got %d/%d", qt.Count(), len(qt.AllPoints()))
+	}
+}
+
+// TestQuadTreeQueryFilter verifies that the predicate is applied
+// on top of the spatial filter, and that nil behaves like Query.
+func TestQuadTreeQueryFilter(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	qt.Insert(&PointOf[string]{X: 10, Y: 10, Data: "fleet-A-1"})
+	qt.Insert(&PointOf[string]{X: 20, Y: 20, Data: "fleet-B-1"})
+	qt.Insert(&PointOf[string]{X: 30, Y: 30, Data: "fleet-A-2"})
+	qt.Insert(&PointOf[string]{X: -50, Y: -50, Data: "fleet-A-3"}) // Outside the area
+
+	area := &Boundary{X: 25, Y: 25, Width: 25, Height: 25}
+	fleetA := func(p *PointOf[string]) bool { return strings.HasPrefix(p.Data, "fleet-A") }
+
+	// --- Test 1: Only fleet-A drivers inside the area ---
+	if found := qt.QueryFilter(area, fleetA); len(found) != 2 {
+		t.Errorf("QueryFilter fleet-A: 2 points expected, %d found", len(found))
+	}
+
+	// --- Test 2: A nil predicate is a plain Query ---
+	if found := qt.QueryFilter(area, nil); len(found) != len(qt.Query(area)) {
+		t.Errorf("QueryFilter nil: %d points expected, %d found", len(qt.Query(area)), len(found))
+	}
+}
+
+// TestQuadTreeQueryLimit verifies that QueryLimit never returns
+// more than max points, and that max <= 0 means unlimited.
+func TestQuadTreeQueryLimit(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	for i := 0; i < 50; i++ {
+		qt.Insert(&Point{X: float64(i) - 25, Y: float64(i%10) - 5, Data: i})
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 100, Height: 100}
+
+	cases := map[int]int{ // max -> expected length
+		20:  20,
+		1:   1,
+		50:  50,
+		100: 50, // Fewer points than the limit
+		0:   50, // Unlimited
+		-1:  50, // Unlimited
+	}
+	for max, expected := range cases {
+		found := qt.QueryLimit(area, max)
+		if len(found) != expected {
+			t.Errorf("QueryLimit(max=%d): %d points expected, %d found", max, expected, len(found))
+		}
+		for _, p := range found {
+			if !area.Contains(p) {
+				t.Errorf("QueryLimit(max=%d) returned a point outside the area", max)
+			}
+		}
+	}
+
+	// The wrapped variant honors the limit across the split boxes too
+	if found := qt.QueryWrappedLimit(&Boundary{X: 95, Y: 0, Width: 130, Height: 100}, 30); len(found) != 30 {
+		t.Errorf("QueryWrappedLimit: 30 points expected, %d found", len(found))
+	}
+}
+
+// TestQuadTreeUpdate verifies that Update moves a point (labels included)
+// and leaves the tree untouched when the move is not possible.
+func TestQuadTreeUpdate(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	for i := 0; i < 20; i++ {
+		qt.Insert(&PointOf[string]{X: float64(i), Y: float64(i), Data: "other"})
+	}
+	p := &PointOf[string]{X: -50, Y: -50, Data: "driver"}
+	qt.InsertWithLabels(p, "ev")
+
+	// --- Test 1: a valid move ---
+	moved := qt.Update(p, 60, 70)
+	if moved == nil || moved.X != 60 || moved.Y != 70 || moved.Data != "driver" {
+		t.Fatalf("Update: moved point %+v is wrong", moved)
+	}
+	if p.X != -50 || p.Y != -50 {
+		t.Errorf("Update modified the old point in place")
+	}
+	if len(qt.Query(&Boundary{X: -50, Y: -50, Width: 1, Height: 1})) != 0 {
+		t.Errorf("The old position is still in the tree")
+	}
+	if found := qt.Query(&Boundary{X: 60, Y: 70, Width: 1, Height: 1}); len(found) != 1 || found[0] != moved {
+		t.Errorf("The new position is not in the tree")
+	}
+	if labels := qt.Labels(moved); len(labels) != 1 || labels[0] != "ev" {
+		t.Errorf("Labels did not follow the point: %v", labels)
+	}
+	if qt.Count() != 21 {
+		t.Errorf("Count after Update: 21 expected, %d found", qt.Count())
+	}
+
+	// --- Test 2: moving outside the boundary fails and keeps the point ---
+	if qt.Update(moved, 500, 0) != nil {
+		t.Errorf("Update outside the boundary should fail")
+	}
+	if len(qt.Query(&Boundary{X: 60, Y: 70, Width: 1, Height: 1})) != 1 {
+		t.Errorf("A failed Update removed the point")
+	}
+
+	// --- Test 3: a point that is not in the tree ---
+	if qt.Update(p, 10, 10) != nil {
+		t.Errorf("Update of a missing point should fail")
+	}
+	if qt.Count() != 21 {
+		t.Errorf("Count after failed Updates: 21 expected, %d found", qt.Count())
+	}
+}
+
+// TestQuadTreeForEachInRange verifies the range visitor, its early stop,
+// and the "collect first, modify after" pattern required by its locking.
+func TestQuadTreeForEachInRange(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	for i := 0; i < 40; i++ {
+		qt.Insert(&Point{X: float64(i) - 20, Y: float64(i) - 20, Data: i})
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 10, Height: 10}
+
+	// --- Test 1: same points as Query ---
+	visited := 0
+	qt.ForEachInRange(area, func(p *Point) bool {
+		if !area.Contains(p) {
+			t.Errorf("Point (%v, %v) visited outside the area", p.X, p.Y)
+		}
+		visited++
+		return true
+	})
+	if expected := len(qt.Query(area)); visited != expected {
+		t.Errorf("ForEachInRange: %d points expected, %d visited", expected, visited)
+	}
+
+	// --- Test 2: early stop ---
+	visited = 0
+	qt.ForEachInRange(area, func(p *Point) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Errorf("Early stop: 3 points expected, %d visited", visited)
+	}
+
+	// --- Test 3: the callback must not modify the tree ---
+	// Collect inside the callback, remove after the traversal returns.
+	var toRemove []*Point
+	qt.ForEachInRange(area, func(p *Point) bool {
+		toRemove = append(toRemove, p)
+		return true
+	})
+	for _, p := range toRemove {
+		qt.Remove(p)
+	}
+	if n := len(qt.Query(area)); n != 0 {
+		t.Errorf("After removal: 0 points expected in the area, %d found", n)
+	}
+}
+
+// TestQuadTreeTotalPoints inserts and removes from many goroutines
+// and checks that the counter still matches a full traversal.
+// Run it with -race to check the counter is updated safely.
+func TestQuadTreeTotalPoints(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+
+	const workers = 8
+	const perWorker = 500
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				rng := rand.New(rand.NewSource(int64(w)))
+				var mine []*Point
+				for i := 0; i < perWorker; i++ {
+					p := randomWorldPoint(rng, w*perWorker+i)
+					if qt.Insert(p) {
+						mine = append(mine, p)
+					}
+					// Remove every third point again
+					if i%3 == 0 && len(mine) > 0 {
+						qt.Remove(mine[len(mine)-1])
+						mine = mine[:len(mine)-1]
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	})
+
+	if total, all := qt.TotalPoints(), len(qt.AllPoints()); total != int64(all) {
+		t.Errorf("TotalPoints() = %d, but AllPoints() has %d points", total, all)
+	}
+	if qt.TotalPoints() == 0 {
+		t.Errorf("The tree should not be empty")
+	}
+}
+
+// TestQuadTreeCollapse verifies that removing every point turns the
+// subdivided nodes back into leaves, except for the fixed shard levels
+func TestQuadTreeCollapse(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 100, Height: 100}
+
+	// --- Test 1: the root becomes a leaf again ---
+	qt := NewQuadTree(world, 4)
+	points := make([]*Point, 0, 20)
+	for i := 0; i < 20; i++ {
+		p := &Point{X: float64(i*9) - 90, Y: float64(i*7) - 70, Data: i}
+		qt.Insert(p)
+		points = append(points, p)
+	}
+	if qt.northWest == nil {
+		t.Fatalf("The root should have been subdivided")
+	}
+	for _, p := range points {
+		qt.Remove(p)
+	}
+	if qt.northWest != nil {
+		t.Errorf("After removing every point the root should be a leaf again")
+	}
+
+	// The collapsed tree keeps working
+	qt.Insert(&Point{X: 1, Y: 1, Data: "again"})
+	if qt.Count() != 1 || len(qt.Query(&world)) != 1 {
+		t.Errorf("Insert after a collapse failed")
+	}
+
+	// --- Test 2: only part of the tree collapses ---
+	qt = NewQuadTree(world, 1)
+	keep := &Point{X: -50, Y: -50, Data: "keep"}
+	qt.Insert(keep)
+	gone := []*Point{{X: 60, Y: 60, Data: 1}, {X: 70, Y: 70, Data: 2}, {X: 80, Y: 80, Data: 3}}
+	for _, p := range gone {
+		qt.Insert(p)
+	}
+	for _, p := range gone {
+		qt.Remove(p)
+	}
+	if qt.northWest == nil || qt.southEast.northWest != nil {
+		t.Errorf("Only the empty South-East subtree should collapse")
+	}
+
+	// --- Test 3: the fixed shard levels are never collapsed ---
+	qt = NewQuadTree(world, 4, WithShards(1))
+	for _, p := range points {
+		qt.Insert(p)
+	}
+	for _, p := range points {
+		qt.Remove(p)
+	}
+	if qt.northWest == nil {
+		t.Errorf("The sharded root must stay subdivided")
+	}
+	if qt.Count() != 0 {
+		t.Errorf("Sharded tree: 0 points expected, %d found", qt.Count())
+	}
+}
+
+// TestBoundaryAreaCenter verifies the Boundary helpers
+func TestBoundaryAreaCenter(t *testing.T) {
+	b := Boundary{X: 12.5, Y: 41.9, Width: 180, Height: 90}
+
+	if area := b.Area(); area != 360*180 {
+		t.Errorf("Area: %v expected, got %v", 360*180, area)
+	}
+	if x, y := b.Center(); x != 12.5 || y != 41.9 {
+		t.Errorf("Center: (12.5, 41.9) expected, got (%v, %v)", x, y)
+	}
+	if area := (&Boundary{X: 1, Y: 1}).Area(); area != 0 {
+		t.Errorf("A degenerate boundary has no area, got %v", area)
+	}
+}
+
+// TestQuadTreeEdgeQueries is a regression test for points lying exactly on
+// node edges: the pruning of the search must agree with the containment rules
+func TestQuadTreeEdgeQueries(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 1)
+
+	// A point exactly on the root's center line (X == 0): after the split
+	// it lives in the East children, whose West edge is inclusive
+	onCenter := &Point{X: 0, Y: 10, Data: "center"}
+	// A point exactly on the world's East edge (X == 180)
+	onEdge := &Point{X: 180, Y: 10, Data: "edge"}
+	for _, p := range []*Point{onCenter, onEdge, {X: -100, Y: -50}, {X: 100, Y: -50}} {
+		if !qt.Insert(p) {
+			t.Fatalf("Insert of (%v, %v) failed", p.X, p.Y)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		area     Boundary
+		expected *Point
+	}{
+		// The area starts exactly on the center line
+		{"starts on the center", Boundary{X: 5, Y: 10, Width: 5, Height: 5}, onCenter},
+		// The area ends just after the center line
+		{"ends after the center", Boundary{X: -5, Y: 10, Width: 5.001, Height: 5}, onCenter},
+		// The area starts exactly on the world's East edge
+		{"starts on the East edge", Boundary{X: 185, Y: 10, Width: 5, Height: 5}, onEdge},
+	}
+	for _, tc := range cases {
+		found := qt.Query(&tc.area)
+		if len(found) != 1 || found[0] != tc.expected {
+			t.Errorf("%s: %v expected, %d points found", tc.name, tc.expected.Data, len(found))
+		}
+		if n := qt.CountInRange(&tc.area); n != 1 {
+			t.Errorf("%s: CountInRange 1 expected, got %d", tc.name, n)
+		}
+	}
+
+	// The area ends exactly on the center line: [min, max) excludes it
+	if found := qt.Query(&Boundary{X: -5, Y: 10, Width: 5, Height: 5}); len(found) != 0 {
+		t.Errorf("An area ending on the center line should not include it, %d found", len(found))
+	}
+}
+
+// TestQuadTreeSingleLock runs a concurrent workload on a single-lock tree
+// (run it with -race) and checks that only the root has a lock in use
+func TestQuadTreeSingleLock(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 4, WithSingleLock(), WithShards(1))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		// A writer...
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 500; i++ {
+				p := randomWorldPoint(rng, w*1000+i)
+				qt.Insert(p)
+				if i%4 == 0 {
+					qt.Remove(p)
+				}
+			}
+		}(w)
+		// ...and a reader
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				qt.Query(&Boundary{X: 0, Y: 0, Width: 90, Height: 45})
+				qt.NearestNeighbor(&Point{X: 10, Y: 10})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if qt.Count() != 4*375 || len(qt.AllPoints()) != qt.Count() {
+		t.Errorf("%d points expected, Count %d, AllPoints %d", 4*375, qt.Count(), len(qt.AllPoints()))
+	}
+
+	// Every node but the root skips its own lock, clones included
+	var check func(name string, node *QuadTree, root bool)
+	check = func(name string, node *QuadTree, root bool) {
+		if node.noLock == root || node.singleLock != root {
+			t.Fatalf("%s: wrong lock mode at depth %d", name, node.depth)
+		}
+		if node.northWest != nil {
+			for _, child := range []*QuadTree{node.northWest, node.northEast, node.southWest, node.southEast} {
+				check(name, child, false)
+			}
+		}
+	}
+	check("tree", qt, true)
+	check("clone", qt.Clone(), true)
+	qt.Rebuild()
+	check("rebuilt", qt, true)
+
+	// Without shards, the single lock is the default
+	plain := NewQuadTree(world, 4)
+	for i := 0; i < 100; i++ {
+		plain.Insert(&Point{X: float64(i) - 50, Y: float64(i%50) - 25})
+	}
+	check("default", plain, true)
+
+	// With shards, every node has its own lock unless asked otherwise
+	sharded := NewQuadTree(world, 4, WithShards(1))
+	sharded.Insert(&Point{X: 1, Y: 1})
+	if sharded.singleLock || sharded.northWest.noLock || sharded.northWest.singleLock {
+		t.Errorf("sharded: one lock per node expected")
+	}
+}
+
+// TestQuadTreeNodeLocks verifies the hand-over-hand inserts of a tree with
+// one lock per node, mixed with removes, reads and rebuilds
+func TestQuadTreeNodeLocks(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 2, WithNodeLocks())
+
+	// --- Test 1: One lock per node, even without shards ---
+	if qt.singleLock || !qt.nodeLocks() {
+		t.Fatal("WithNodeLocks: one lock per node expected")
+	}
+
+	// --- Test 2: Concurrent writers, readers and rebuilds ---
+	var wg sync.WaitGroup
+	points := make([][]*Point, 8)
+	for w := range points {
+		wg.Add(2)
+		// A writer, keeping the points it leaves in the tree...
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 500; i++ {
+				p := randomWorldPoint(rng, w*1000+i)
+				if !qt.Insert(p) {
+					t.Errorf("Insert of a point inside the world failed")
+				}
+				// Remove every other point again
+				if i%2 == 0 {
+					if !qt.Remove(p) {
+						t.Errorf("Remove of a just inserted point failed")
+					}
+					continue
+				}
+				points[w] = append(points[w], p)
+			}
+		}(w)
+		// ...and a reader
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				qt.Query(&Boundary{X: 0, Y: 0, Width: 90, Height: 45})
+				qt.NearestNeighbor(&Point{X: 10, Y: 10})
+				if i%50 == 0 {
+					qt.Rebuild()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// --- Test 3: Counts and queries agree ---
+	if qt.Count() != 8*250 || len(qt.Query(&world)) != qt.Count() {
+		t.Errorf("%d points expected, Count %d, Query %d", 8*250, qt.Count(), len(qt.Query(&world)))
+	}
+
+	// --- Test 4: Removing everything collapses the tree to its root ---
+	for _, ps := range points {
+		for _, p := range ps {
+			if !qt.Remove(p) {
+				t.Fatalf("Remove of a stored point failed")
+			}
+		}
+	}
+	if qt.Count() != 0 || qt.northWest != nil {
+		t.Errorf("Empty tree: a single leaf expected, Count %d", qt.Count())
+	}
+}
+
+// TestQuadTreeClear verifies that Clear empties the tree in place
+// and that the tree keeps working afterwards
+func TestQuadTreeClear(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTreeOf[string](world, 4, WithIDIndex())
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		qt.InsertWithLabels(&PointOf[string]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: strings.Repeat("x", i+1)}, "old")
+	}
+	same := qt
+
+	qt.Clear()
+
+	// --- Test 1: empty, and back to a single root ---
+	if qt != same {
+		t.Fatal("Clear must keep the same tree")
+	}
+	if qt.Count() != 0 || qt.northWest != nil || len(qt.points) != 0 {
+		t.Errorf("After Clear: empty leaf root expected, Count %d", qt.Count())
+	}
+	if qt.boundary != world || qt.capacity != 4 {
+		t.Errorf("Clear must keep boundary and capacity")
+	}
+	if len(qt.AllPoints()) != 0 || len(qt.QueryWithLabels(&world, []string{"old"}, nil)) != 0 {
+		t.Errorf("Points or labels survived Clear")
+	}
+	if _, ok := qt.GetByID("x"); ok {
+		t.Errorf("The ID index survived Clear")
+	}
+
+	// --- Test 2: Insert and Query work normally ---
+	for i := 0; i < 10; i++ {
+		if !qt.Insert(&PointOf[string]{X: float64(i), Y: float64(i), Data: strings.Repeat("x", i+1)}) {
+			t.Fatalf("Insert after Clear failed")
+		}
+	}
+	if found := qt.Query(&Boundary{X: 5, Y: 5, Width: 5, Height: 5}); len(found) != 10 || qt.Count() != 10 {
+		t.Errorf("After Clear and 10 inserts: 10 points expected, Query %d, Count %d", len(found), qt.Count())
+	}
+
+	// --- Test 3: the shard levels are kept ---
+	sharded := NewQuadTree(world, 4, WithShards(2))
+	sharded.Insert(&Point{X: 1, Y: 1})
+	sharded.Clear()
+	if sharded.shardLevels() != 2 || sharded.Count() != 0 {
+		t.Errorf("Sharded Clear: 2 levels and 0 points expected, got %d and %d", sharded.shardLevels(), sharded.Count())
+	}
+}
+
+// TestQuadTreeRemoveInArea verifies that RemoveInArea deletes exactly the
+// points inside an area that straddles all four quadrants
+func TestQuadTreeRemoveInArea(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTreeOf[int](world, 4, WithIDIndex())
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		qt.InsertWithLabels(&PointOf[int]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i}, "car")
+	}
+	// Centered on the origin, so it touches NW, NE, SW and SE
+	area := &Boundary{X: 0, Y: 0, Width: 60, Height: 30}
+	inside := qt.Query(area)
+
+	// --- Test 1: the right points are removed ---
+	removed := qt.RemoveInArea(area)
+	if removed != len(inside) || removed == 0 {
+		t.Fatalf("RemoveInArea: %d points expected to be removed, got %d", len(inside), removed)
+	}
+	if found := qt.Query(area); len(found) != 0 {
+		t.Errorf("Query after RemoveInArea: 0 points expected, %d found", len(found))
+	}
+	if qt.Count() != 2000-removed || len(qt.AllPoints()) != 2000-removed {
+		t.Errorf("After RemoveInArea: %d points expected, Count %d", 2000-removed, qt.Count())
+	}
+
+	// --- Test 2: the labels and the ID index follow ---
+	if len(qt.QueryWithLabels(&world, []string{"car"}, nil)) != 2000-removed {
+		t.Errorf("The labels of the removed points are still indexed")
+	}
+	if _, ok := qt.GetByID(inside[0].Data); ok {
+		t.Errorf("The ID of a removed point is still indexed")
+	}
+
+	// --- Test 3: an emptied tree collapses back to a single leaf ---
+	qt.RemoveInArea(&world)
+	if qt.Count() != 0 || qt.northWest != nil {
+		t.Errorf("After removing everything: empty leaf root expected, Count %d", qt.Count())
+	}
+}
+
+// TestQuadTreeInsertE verifies the reasons InsertE gives for a rejection
+func TestQuadTreeInsertE(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2, WithIDIndex())
+
+	// --- Test 1: accepted points, edges included ---
+	for i, p := range []*PointOf[string]{{X: 1, Y: 1, Data: "a"}, {X: 180, Y: 90, Data: "corner"}, {X: -180, Y: -90, Data: "b"}} {
+		if err := qt.InsertE(p); err != nil {
+			t.Fatalf("InsertE #%d: no error expected, got %v", i, err)
+		}
+	}
+
+	// --- Test 2: outside the boundary ---
+	for _, p := range []*PointOf[string]{{X: 180.0001, Y: 0, Data: "east"}, {X: 0, Y: -91, Data: "south"}} {
+		if err := qt.InsertE(p); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("InsertE %v: ErrOutOfBounds expected, got %v", p.Data, err)
+		}
+	}
+
+	// --- Test 3: a duplicate ID ---
+	if err := qt.InsertE(&PointOf[string]{X: 5, Y: 5, Data: "a"}); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("InsertE of a duplicate ID: ErrDuplicateID expected, got %v", err)
+	}
+
+	// Nothing rejected ended up in the tree, and Insert agrees with InsertE
+	if qt.Count() != 3 {
+		t.Errorf("3 points expected, got %d", qt.Count())
+	}
+	if qt.Insert(&PointOf[string]{X: 200, Y: 0, Data: "far"}) {
+		t.Errorf("Insert must reject what InsertE rejects")
+	}
+}
+
+// TestQuadTreeInsertNonFinite verifies that NaN and ±Inf coordinates are
+// rejected with ErrNonFinite instead of being silently lost
+func TestQuadTreeInsertNonFinite(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2, WithIDIndex())
+	for i := 0; i < 5; i++ {
+		qt.Insert(&PointOf[string]{X: float64(i), Y: float64(i), Data: fmt.Sprint("p", i)})
+	}
+
+	// --- Test 1: NaN and ±Inf on either axis ---
+	nan, inf := math.NaN(), math.Inf(1)
+	for _, p := range []*PointOf[string]{
+		{X: nan, Y: 0, Data: "nan-x"}, {X: 0, Y: nan, Data: "nan-y"},
+		{X: inf, Y: 0, Data: "inf-x"}, {X: 0, Y: -inf, Data: "inf-y"},
+	} {
+		if err := qt.InsertE(p); !errors.Is(err, ErrNonFinite) {
+			t.Errorf("InsertE %s: ErrNonFinite expected, got %v", p.Data, err)
+		}
+	}
+	if qt.Count() != 5 {
+		t.Errorf("5 points expected, got %d", qt.Count())
+	}
+
+	// --- Test 2: moving a point to NaN fails and keeps it where it was ---
+	old, _ := qt.GetByID("p1")
+	if moved := qt.Update(old, nan, 1); moved != nil {
+		t.Errorf("Update to NaN must fail, got %v", moved)
+	}
+	if got, _ := qt.GetByID("p1"); got != old || len(qt.Query(&Boundary{X: 1, Y: 1, Width: 0.5, Height: 0.5})) != 1 {
+		t.Errorf("The point must stay at (1, 1) after a rejected Update")
+	}
+}
+
+// TestQuadTreeQueryInto verifies that QueryInto appends to the caller's buffer
+func TestQuadTreeQueryInto(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+	for i := 0; i < 10; i++ {
+		qt.Insert(&Point{X: float64(i), Y: float64(i), Data: i})
+	}
+	area := &Boundary{X: 2, Y: 2, Width: 2, Height: 2} // Points 0..3
+
+	// --- Test 1: same points as Query, appended after the existing ones ---
+	marker := &Point{Data: "marker"}
+	buf := make([]*Point, 0, 16)
+	buf = append(buf, marker)
+	found := qt.QueryInto(area, buf)
+	if len(found) != 5 || found[0] != marker {
+		t.Fatalf("QueryInto: the marker and 4 points expected, got %d", len(found))
+	}
+
+	// --- Test 2: a buffer with enough room is reused, not reallocated ---
+	again := qt.QueryInto(area, found[:0])
+	if len(again) != 4 || &again[0] != &buf[0] {
+		t.Errorf("QueryInto(buf[:0]): 4 points in the same backing array expected")
+	}
+
+	// --- Test 3: a nil buffer works like Query ---
+	if found := qt.QueryInto(area, nil); len(found) != len(qt.Query(area)) {
+		t.Errorf("QueryInto(nil): %d points expected, got %d", len(qt.Query(area)), len(found))
+	}
+}
+
+// TestQuadTreeChildEdges verifies that points lying exactly on the edges
+// between children are never lost, even when halving the boundary
+// rounds the children's edges (here: centers and sizes that are not
+// exact in binary floating point)
+func TestQuadTreeChildEdges(t *testing.T) {
+	boundaries := []Boundary{
+		{X: 0.1, Y: 0.7, Width: 0.3, Height: 1.1},
+		{X: -3.3, Y: 2.9, Width: 1.7, Height: 0.9},
+		{X: 12.345, Y: 45.678, Width: 0.123, Height: 0.0789},
+	}
+	for _, b := range boundaries {
+		qt := NewQuadTree(b, 1)
+
+		// Every edge of the first two levels of children,
+		// plus the closest float64 on both sides of it
+		var xs, ys []float64
+		for _, f := range []float64{-1, -0.5, 0, 0.5} {
+			x, y := b.X+f*b.Width, b.Y+f*b.Height
+			xs = append(xs, math.Nextafter(x, math.Inf(-1)), x, math.Nextafter(x, math.Inf(1)))
+			ys = append(ys, math.Nextafter(y, math.Inf(-1)), y, math.Nextafter(y, math.Inf(1)))
+		}
+
+		// --- Test 1: every point inside the boundary is inserted ---
+		n := 0
+		for _, x := range xs {
+			for _, y := range ys {
+				p := &Point{X: x, Y: y, Data: n}
+				if !b.Contains(p) {
+					continue // Just outside the West/South edge
+				}
+				if err := qt.InsertE(p); err != nil {
+					t.Fatalf("%+v: InsertE(%v, %v) = %v, want nil", b, x, y, err)
+				}
+				n++
+			}
+		}
+
+		// --- Test 2: the redistributions kept every point ---
+		if qt.Count() != n {
+			t.Errorf("%+v: Count() = %d, want %d", b, qt.Count(), n)
+		}
+		if all := qt.AllPoints(); len(all) != n {
+			t.Errorf("%+v: AllPoints() returned %d points, want %d", b, len(all), n)
+		}
+
+		// --- Test 3: each point is found by a query and can be removed ---
+		for _, p := range qt.AllPoints() {
+			area := &Boundary{X: p.X, Y: p.Y, Width: 1e-9, Height: 1e-9}
+			if found := qt.Query(area); !containsPoint(found, p) {
+				t.Errorf("%+v: Query around (%v, %v) missed the point", b, p.X, p.Y)
+			}
+			if !qt.Remove(p) {
+				t.Errorf("%+v: Remove(%v, %v) = false, want true", b, p.X, p.Y)
+			}
+		}
+		if qt.Count() != 0 {
+			t.Errorf("%+v: Count() after removing everything = %d, want 0", b, qt.Count())
+		}
+	}
+}
+
+// containsPoint reports whether p is one of the found points
+func containsPoint(found []*Point, p *Point) bool {
+	for _, f := range found {
+		if f == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package quadtree // Queries reading the children without locks
+
+// In a tree with one lock per node, a query used to take the Read Lock of
+// every node it visited. Now it only locks the root, for the whole query,
+// and the leaves, while it reads their points: the parents are crossed
+// through their published children (see publishChildren), an atomic
+// pointer that needs no lock.
+//
+// It is safe because a parent never changes its children in place:
+//   - a leaf publishes its children only once the split is complete,
+//     so a query sees either the leaf with all its points or the
+//     children with all of them;
+//   - collapse only drops empty children, and Clear, Rebuild and
+//     UnmarshalJSON need the root's Write Lock, which the query holds
+//     for reading. A query still inside dropped children reads them
+//     as they were, which is what it would have seen a moment earlier.
+//
+// With WithPooling, dropped nodes are recycled and reused elsewhere at
+// once: those trees keep the nested Read Locks.
+
+// readFree reports whether the queries of this tree can cross the
+// parents without locks
+func (qt *QuadTreeOf[T]) readFree() bool {
+	return qt.nodeLocks() && qt.pool == nil
+}
+
+// publishChildren makes the current children of this node visible to the
+// lock-free queries. The caller holds this node's Write Lock (or nobody
+// else can see the node yet).
+func (qt *QuadTreeOf[T]) publishChildren() {
+	if qt.northWest == nil {
+		qt.children.Store(nil)
+		return
+	}
+	qt.children.Store(&[4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast})
+}
+
+// queryReadFree is queryRecursive for the trees with one lock per node
+// (and no pooling): only the root and the leaves are locked
+func (qt *QuadTreeOf[T]) queryReadFree(rangeRect *Boundary, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
+	// The root's Read Lock keeps Clear, Rebuild and UnmarshalJSON away
+	// until the query is done (they replace the root's content)
+	qt.mu.RLock()
+	defer qt.mu.RUnlock()
+
+	// Same pruning as queryNested
+	if !qt.intersects(rangeRect) {
+		return
+	}
+	if qt.northWest == nil {
+		qt.scanLeaf(rangeRect, keep, found)
+		return
+	}
+	for _, child := range [4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast} {
+		child.queryUnlocked(rangeRect, keep, found)
+	}
+}
+
+// queryUnlocked is the recursive part of queryReadFree: it only takes
+// the Read Lock of the leaves
+func (qt *QuadTreeOf[T]) queryUnlocked(rangeRect *Boundary, keep func(*PointOf[T]) bool, found *[]*PointOf[T]) {
+	// The edges of a node below the root never change: no lock needed
+	if !qt.intersects(rangeRect) {
+		return
+	}
+
+	children := qt.children.Load()
+	if children == nil {
+		// A leaf, as far as we know: its points need its Read Lock
+		qt.rlock()
+		if qt.northWest == nil {
+			qt.scanLeaf(rangeRect, keep, found)
+			qt.runlock()
+			return
+		}
+		// It was split in the meantime: go on with its (complete) children
+		children = &[4]*QuadTreeOf[T]{qt.northWest, qt.northEast, qt.southWest, qt.southEast}
+		qt.runlock()
+	}
+
+	for _, child := range children {
+		child.queryUnlocked(rangeRect, keep, found)
+	}
+}
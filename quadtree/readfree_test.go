@@ -0,0 +1,77 @@
+package quadtree // Tests for the queries reading the children without locks
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// TestQuadTreeReadFreeQuery verifies that the lock-free queries never miss
+// a point while writers split and collapse the nodes around it
+func TestQuadTreeReadFreeQuery(t *testing.T) {
+	world := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+	qt := NewQuadTree(world, 2, WithNodeLocks())
+	if !qt.readFree() {
+		t.Fatal("WithNodeLocks: lock-free queries expected")
+	}
+	if NewQuadTree(world, 2, WithNodeLocks(), WithPooling()).readFree() {
+		t.Error("WithPooling: nested Read Locks expected")
+	}
+
+	// A few points that stay in the tree for the whole test
+	area := &Boundary{X: 10, Y: 10, Width: 5, Height: 5}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		qt.Insert(&Point{X: 5 + rng.Float64()*10, Y: 5 + rng.Float64()*10, Data: i})
+	}
+
+	// --- Test 1: Writers churn around them, readers always find all of them ---
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 2000; i++ {
+				// Points in the same area: the leaves there keep
+				// splitting and collapsing
+				p := &Point{X: 5 + rng.Float64()*10, Y: 5 + rng.Float64()*10, Data: -(w*10000 + i + 1)}
+				qt.Insert(p)
+				qt.Remove(p)
+			}
+		}(w)
+	}
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				kept := 0
+				for _, p := range qt.Query(area) {
+					if p.Data.(int) >= 0 {
+						kept++
+					}
+				}
+				if kept != 20 {
+					t.Errorf("Query during the writes: 20 points expected, got %d", kept)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	// --- Test 2: Once the writers are done, the tree holds just the 20 ---
+	if qt.Count() != 20 || len(qt.Query(&world)) != 20 {
+		t.Errorf("20 points expected, Count %d, Query %d", qt.Count(), len(qt.Query(&world)))
+	}
+}
@@ -0,0 +1,137 @@
+package quadtree // Rebuilding the QuadTree from its current points
+
+import (
+	"errors" // Import errors package (New)
+	"fmt"    // Import formatting package (Errorf)
+)
+
+// ErrInvalidCapacity is returned (wrapped) by SetCapacity and the checked
+// constructors for a capacity below 1
+var ErrInvalidCapacity = errors.New("quadtree: capacity must be at least 1")
+
+// Rebuild rebuilds the tree from the points it currently holds, with the
+// same boundary, capacity and options. After hours of moving points the
+// shape reflects historical positions: a rebuild removes the stale splits.
+// It returns the statistics of the new tree (compare them with Stats()
+// taken before the call) and the points that could not be re-inserted
+// because they are out of bounds (this should never happen): those points
+// are no longer in the tree and are returned instead of silently dropped.
+func (qt *QuadTreeOf[T]) Rebuild() (TreeStats, []*PointOf[T]) {
+	return qt.rebuild(0)
+}
+
+// SetCapacity changes the number of points a node holds before it splits,
+// and rebuilds the tree (like Rebuild) so that the whole structure follows
+// the new value, not just the future splits. It is safe to call while
+// other goroutines use the tree: the new tree is built on the side while
+// they keep reading and writing the old one, and they only wait for the
+// final swap (see rebuild). They never see a half-built tree.
+func (qt *QuadTreeOf[T]) SetCapacity(n int) error {
+	if n < 1 {
+		return fmt.Errorf("%w (got %d)", ErrInvalidCapacity, n)
+	}
+	// Same boundary, same points: nothing can be rejected, unless the tree is broken
+	if _, rejected := qt.rebuild(n); len(rejected) > 0 {
+		return fmt.Errorf("%w: %d points lost in the rebuild", ErrNotPlaced, len(rejected))
+	}
+	return nil
+}
+
+// rebuild is Rebuild with a new capacity (0 keeps the current one).
+// The expensive part, inserting every point into a new tree, runs without
+// blocking anybody: it works on a snapshot of the points taken with Read
+// Locks. The Write Lock is only held at the end, to catch up with the
+// changes made in the meantime and swap the new structure in.
+func (qt *QuadTreeOf[T]) rebuild(capacity int) (TreeStats, []*PointOf[T]) {
+	if capacity == 0 {
+		qt.mu.RLock()
+		capacity = qt.capacity
+		qt.mu.RUnlock()
+	}
+
+	// --- Collect the current points (Read Locks only) ---
+	points := qt.AllPoints()
+
+	// --- Build the new tree on the side ---
+	opts := []Option{WithMaxDepth(qt.maxDepth), WithShards(qt.shardLevels())}
+	if qt.singleLock {
+		opts = append(opts, WithSingleLock())
+	} else {
+		opts = append(opts, WithNodeLocks())
+	}
+	// Keep recycling through the same pool
+	opts = append(opts, withPool(qt.pool))
+	fresh := NewQuadTreeOf[T](qt.boundary, capacity, opts...)
+	// Every point of the snapshot, and whether the new tree took it
+	placed := make(map[*PointOf[T]]bool, len(points))
+	for _, p := range points {
+		placed[p] = fresh.Insert(p)
+	}
+
+	// --- Lock the tree for the swap ---
+	// Every operation enters through the root: once the ones already
+	// inside are done (drain) nothing can change until we unlock
+	// (the ID index lock, if any, comes first: see idIndex)
+	if qt.ids != nil {
+		qt.ids.mu.Lock()
+		defer qt.ids.mu.Unlock()
+	}
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	// Let the inserts already past the root finish (see drain)
+	qt.drain()
+
+	// --- Catch up with the changes made during the build ---
+	// A stored point is never changed in place (Update inserts a new one),
+	// so comparing the pointers is enough: the points missing from the
+	// snapshot are new, the ones left in it were removed meanwhile.
+	// (the root is already locked: only the children take their Read Locks)
+	var rejected []*PointOf[T]
+	catchUp := func(p *PointOf[T]) bool {
+		ok, seen := placed[p]
+		if !seen {
+			ok = fresh.Insert(p)
+		}
+		delete(placed, p)
+		if !ok {
+			rejected = append(rejected, p)
+		}
+		return true
+	}
+	if qt.northWest == nil {
+		for _, p := range qt.points {
+			catchUp(p)
+		}
+	} else {
+		qt.northWest.forEachRecursive(catchUp)
+		qt.northEast.forEachRecursive(catchUp)
+		qt.southWest.forEachRecursive(catchUp)
+		qt.southEast.forEachRecursive(catchUp)
+	}
+	for p, ok := range placed {
+		if ok {
+			fresh.remove(p, true)
+		}
+	}
+	stats := fresh.Stats()
+
+	// --- Swap the new structure in (we still hold the Write Lock) ---
+	qt.capacity = capacity
+	qt.points = fresh.points
+	qt.xy = fresh.xy
+	qt.size.Store(fresh.size.Load())
+	qt.northWest = fresh.northWest
+	qt.northEast = fresh.northEast
+	qt.southWest = fresh.southWest
+	qt.southEast = fresh.southEast
+	qt.publishChildren()
+
+	// The points keep their labels and IDs, except the ones that left the tree
+	for _, p := range rejected {
+		qt.labels.drop(p)
+		qt.expiry.drop(p)
+		qt.ids.dropLocked(p)
+	}
+
+	return stats, rejected
+}
@@ -0,0 +1,178 @@
+package quadtree // Tests for the tree rebuild
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreeRebuild moves every point to a small area, so the old splits
+// become stale, and verifies that Rebuild keeps the points and shrinks the tree
+func TestQuadTreeRebuild(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(1))
+
+	// Spread the points all over the world...
+	points := make([]*PointOf[int], 0, 2000)
+	for i := 0; i < 2000; i++ {
+		p := &PointOf[int]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i}
+		qt.InsertWithLabels(p, "driver")
+		points = append(points, p)
+	}
+	// ...then move them all to the same city, keeping one point in each
+	// corner of the world so the old splits don't collapse by themselves
+	for i, p := range points[4:] {
+		points[i+4] = qt.Update(p, 12+rng.Float64(), 41+rng.Float64())
+	}
+
+	before := qt.Stats()
+	stats, rejected := qt.Rebuild()
+
+	// --- Test 1: nothing was lost ---
+	if len(rejected) != 0 {
+		t.Errorf("No point should be rejected, %d were", len(rejected))
+	}
+	if stats.Points != 2000 || qt.Count() != 2000 {
+		t.Errorf("2000 points expected, stats %d, Count %d", stats.Points, qt.Count())
+	}
+	for _, p := range points {
+		found := qt.Query(&Boundary{X: p.X, Y: p.Y, Width: 1e-9, Height: 1e-9})
+		if len(found) == 0 {
+			t.Fatalf("Point %d lost by the rebuild", p.Data)
+		}
+	}
+	if len(qt.QueryWithLabels(&Boundary{X: 0, Y: 0, Width: 180, Height: 90}, []string{"driver"}, nil)) != 2000 {
+		t.Errorf("The labels should survive the rebuild")
+	}
+
+	// --- Test 2: the stale splits are gone ---
+	if stats.Nodes >= before.Nodes {
+		t.Errorf("The rebuilt tree should be smaller: %d nodes before, %d after", before.Nodes, stats.Nodes)
+	}
+	if stats != qt.Stats() {
+		t.Errorf("Returned stats %+v don't match the tree %+v", stats, qt.Stats())
+	}
+
+	// --- Test 3: the options are kept ---
+	sharded := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4, WithShards(2))
+	sharded.Insert(&Point{X: 1, Y: 1})
+	sharded.Rebuild()
+	if sharded.shardLevels() != 2 {
+		t.Errorf("Rebuild lost the shard levels: %d", sharded.shardLevels())
+	}
+}
+
+// TestQuadTreeSetCapacity verifies that SetCapacity reshapes the whole tree
+// while other goroutines keep querying it
+func TestQuadTreeSetCapacity(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithIDIndex())
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&PointOf[int]{X: 12 + rng.Float64(), Y: 41 + rng.Float64(), Data: i})
+	}
+	before := qt.Stats()
+
+	// --- Test 1: a bigger capacity gives fewer, fuller leaves ---
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Queries during the swap see either the old or the new tree, never half of it
+		for i := 0; i < 100; i++ {
+			if n := len(qt.Query(&Boundary{X: 0, Y: 0, Width: 180, Height: 90})); n != 1000 {
+				t.Errorf("A query during SetCapacity found %d points", n)
+				return
+			}
+		}
+	}()
+	if err := qt.SetCapacity(64); err != nil {
+		t.Fatalf("SetCapacity(64): %v", err)
+	}
+	<-done
+
+	after := qt.Stats()
+	if after.Points != 1000 || after.Leaves >= before.Leaves {
+		t.Errorf("1000 points in fewer leaves expected: before %+v, after %+v", before, after)
+	}
+	if p, ok := qt.GetByID(500); !ok || len(qt.Query(&Boundary{X: p.X, Y: p.Y, Width: 1e-9, Height: 1e-9})) == 0 {
+		t.Errorf("Point 500 lost by SetCapacity")
+	}
+
+	// --- Test 2: future splits follow the new capacity ---
+	small := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	small.SetCapacity(8)
+	for i := 0; i < 8; i++ {
+		small.Insert(&Point{X: float64(i), Y: float64(i), Data: i})
+	}
+	if small.Stats().Leaves != 1 {
+		t.Errorf("8 points with capacity 8 must not split, got %d leaves", small.Stats().Leaves)
+	}
+
+	// --- Test 3: invalid capacities are rejected ---
+	for _, n := range []int{0, -3} {
+		if err := small.SetCapacity(n); !errors.Is(err, ErrInvalidCapacity) {
+			t.Errorf("SetCapacity(%d): ErrInvalidCapacity expected, got %v", n, err)
+		}
+	}
+}
+
+// TestQuadTreeSetCapacityConcurrentWrites verifies that the inserts, moves
+// and removes made while the new tree is being built are not lost by the swap
+func TestQuadTreeSetCapacityConcurrentWrites(t *testing.T) {
+	for _, opts := range [][]Option{{WithIDIndex()}, {WithIDIndex(), WithShards(2)}} {
+		qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, opts...)
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			qt.Insert(&PointOf[int]{X: rng.Float64()*360 - 180, Y: rng.Float64()*180 - 90, Data: i})
+		}
+
+		// A writer keeps changing the tree during the rebuilds...
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			wrng := rand.New(rand.NewSource(2))
+			next := 2000
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := wrng.Intn(next)
+				switch wrng.Intn(3) {
+				case 0:
+					qt.Insert(&PointOf[int]{X: wrng.Float64()*360 - 180, Y: wrng.Float64()*180 - 90, Data: next})
+					next++
+				case 1:
+					qt.MoveByID(id, wrng.Float64()*360-180, wrng.Float64()*180-90)
+				case 2:
+					qt.RemoveByID(id)
+				}
+			}
+		}()
+		// ...which alternate between two capacities
+		for i := 0; i < 20; i++ {
+			if err := qt.SetCapacity(8 + 56*(i%2)); err != nil {
+				t.Fatalf("SetCapacity: %v", err)
+			}
+		}
+		close(stop)
+		<-done
+
+		// --- Test: the tree and the ID index still agree, point by point ---
+		// (the index is kept apart from the nodes: it is the reference)
+		wantCount := len(qt.ids.byID)
+		all := qt.AllPoints()
+		if len(all) != wantCount || qt.Count() != wantCount {
+			t.Fatalf("%d points expected, AllPoints %d, Count %d", wantCount, len(all), qt.Count())
+		}
+		for _, p := range all {
+			if q, ok := qt.GetByID(p.Data); !ok || q != p {
+				t.Fatalf("Point %d is in the tree but not in the ID index", p.Data)
+			}
+		}
+		if stats := qt.Stats(); stats.Points != wantCount {
+			t.Errorf("Stats count %d points, %d expected", stats.Points, wantCount)
+		}
+	}
+}
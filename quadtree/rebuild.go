@@ -0,0 +1,123 @@
+package quadtree // Deterministic reconstruction of a QuadTree
+
+import (
+	"encoding/binary" // Import binary encoding (to hash floats)
+	"fmt"             // Import formatting package (to hash Data)
+	"hash/fnv"        // Import FNV hash (for Fingerprint)
+	"io"              // Import io package (Writer)
+	"math"            // Import math package (Float64bits)
+	"sort"            // Import sorting package
+)
+
+// RebuildDeterministic builds a new tree from a flat list of points.
+// The points are first sorted along a space-filling curve (Z-order),
+// so the same set of points always produces the same tree, with the
+// same points in the same order in every leaf, whatever the original order.
+// Points outside the boundary are skipped, exactly like Insert does.
+func RebuildDeterministic[T comparable](boundary Boundary, capacity int, points []*PointOf[T]) *QuadTreeOf[T] {
+	// Work on a copy: the caller's slice must not be reordered
+	sorted := make([]*PointOf[T], len(points))
+	copy(sorted, points)
+
+	// Compute the Z-order key of every point once
+	keys := make(map[*PointOf[T]]uint64, len(sorted))
+	for _, p := range sorted {
+		keys[p] = mortonKey(&boundary, p.X, p.Y)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		// Primary order: position along the Z-order curve
+		if keys[a] != keys[b] {
+			return keys[a] < keys[b]
+		}
+		// Same cell of the curve: order by exact coordinates...
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		// ...and finally by the textual form of the Data
+		return fmt.Sprint(a.Data) < fmt.Sprint(b.Data)
+	})
+
+	qt := NewQuadTreeOf[T](boundary, capacity)
+	for _, p := range sorted {
+		qt.Insert(p)
+	}
+
+	return qt
+}
+
+// mortonKey maps (x, y) to its position on the Z-order curve covering the boundary.
+// Each coordinate is scaled to 32 bits and the bits are interleaved.
+func mortonKey(b *Boundary, x, y float64) uint64 {
+	return interleave(scaleToUint32(x, b.X-b.Width, b.Width*2)) |
+		interleave(scaleToUint32(y, b.Y-b.Height, b.Height*2))<<1
+}
+
+// scaleToUint32 maps v from [min, min+size] to [0, MaxUint32], clamping outside values
+func scaleToUint32(v, min, size float64) uint32 {
+	if size <= 0 {
+		return 0
+	}
+	t := (v - min) / size
+	if t <= 0 || math.IsNaN(t) {
+		return 0
+	}
+	if t >= 1 {
+		return math.MaxUint32
+	}
+	return uint32(t * math.MaxUint32)
+}
+
+// interleave spreads the 32 bits of v over the even bits of a uint64
+func interleave(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// Fingerprint returns a hash of the tree structure and of the points
+// stored in every leaf (in order). Two trees with the same fingerprint
+// have the same shape and the same contents.
+func (qt *QuadTreeOf[T]) Fingerprint() uint64 {
+	h := fnv.New64a()
+	qt.fingerprintRecursive(h)
+	return h.Sum64()
+}
+
+// fingerprintRecursive feeds this node (and its subtree) into the hash
+func (qt *QuadTreeOf[T]) fingerprintRecursive(h io.Writer) {
+	qt.rlock()
+	defer qt.runlock()
+
+	var buf [8]byte
+
+	// If this is a "leaf" node, hash a marker followed by its points
+	if qt.northWest == nil {
+		h.Write([]byte{'L'})
+		for _, p := range qt.points {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(p.X))
+			h.Write(buf[:])
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(p.Y))
+			h.Write(buf[:])
+			h.Write([]byte(fmt.Sprint(p.Data)))
+		}
+		// Close the leaf so that points can't "move" between leaves unnoticed
+		h.Write([]byte{'/'})
+		return
+	}
+
+	// If this is a "parent" node, hash a marker followed by the four children
+	h.Write([]byte{'P'})
+	qt.northWest.fingerprintRecursive(h)
+	qt.northEast.fingerprintRecursive(h)
+	qt.southWest.fingerprintRecursive(h)
+	qt.southEast.fingerprintRecursive(h)
+}
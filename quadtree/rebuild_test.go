@@ -0,0 +1,61 @@
+package quadtree // Tests for the deterministic reconstruction
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestRebuildDeterministic verifies that the same set of points,
+// given in two different orders, produces exactly the same tree.
+func TestRebuildDeterministic(t *testing.T) {
+	boundary := Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+
+	// Generate a reproducible set of points
+	rng := rand.New(rand.NewSource(42))
+	points := make([]*Point, 0, 500)
+	for i := 0; i < 500; i++ {
+		points = append(points, &Point{
+			X:    (rng.Float64() * 360) - 180,
+			Y:    (rng.Float64() * 180) - 90,
+			Data: i,
+		})
+	}
+
+	// Make a second, shuffled copy of the same list
+	shuffled := make([]*Point, len(points))
+	copy(shuffled, points)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	a := RebuildDeterministic(boundary, 4, points)
+	b := RebuildDeterministic(boundary, 4, shuffled)
+
+	// --- Test 1: Both trees contain every point ---
+	if a.Count() != len(points) || b.Count() != len(points) {
+		t.Fatalf("Count: %d expected, got %d and %d", len(points), a.Count(), b.Count())
+	}
+
+	// --- Test 2: Both trees have the same number of nodes ---
+	if na, nb := nodeCount(a), nodeCount(b); na != nb {
+		t.Errorf("Node count differs: %d vs %d", na, nb)
+	}
+
+	// --- Test 3: Both trees have the same structure and leaf order ---
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("Fingerprints differ for the same set of points")
+	}
+
+	// --- Test 4: A different set of points gives a different fingerprint ---
+	c := RebuildDeterministic(boundary, 4, points[1:])
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("Fingerprints should differ for different sets of points")
+	}
+}
+
+// nodeCount returns the number of nodes (parents and leaves) in the tree
+func nodeCount[T comparable](qt *QuadTreeOf[T]) int {
+	if qt.northWest == nil {
+		return 1
+	}
+	return 1 + nodeCount(qt.northWest) + nodeCount(qt.northEast) +
+		nodeCount(qt.southWest) + nodeCount(qt.southEast)
+}
@@ -0,0 +1,65 @@
+package quadtree // Binary snapshot of the QuadTree
+
+import (
+	"encoding/gob" // Import gob encoding (compact binary format)
+	"errors"       // Import errors package
+	"fmt"          // Import formatting package (for errors)
+	"io"           // Import io package (Reader, Writer)
+)
+
+// snapshotVersion is the version byte written at the start of every snapshot.
+// It must be incremented whenever nodeRecord changes in a way that
+// older snapshots can't be decoded anymore (gob tolerates new fields).
+const snapshotVersion byte = 1
+
+// ErrSnapshotVersion is returned when loading a snapshot written
+// with an unknown (e.g. newer) format version
+var ErrSnapshotVersion = errors.New("quadtree: unsupported snapshot version")
+
+// SaveSnapshot writes the whole tree to w in a compact binary format:
+// a version byte followed by the gob encoding of the tree.
+// The writers are stopped while the tree is copied (not while it is
+// written to w), so the snapshot is a consistent view even while other
+// goroutines keep inserting, whatever the lock mode (see lockWhole).
+// For a QuadTree[any], custom Data types must be registered with gob.Register.
+func (qt *QuadTreeOf[T]) SaveSnapshot(w io.Writer) error {
+	root := qt.rootRecord()
+
+	if _, err := w.Write([]byte{snapshotVersion}); err != nil {
+		return fmt.Errorf("quadtree: writing snapshot: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(root); err != nil {
+		return fmt.Errorf("quadtree: writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot rebuilds a (non-generic) QuadTree from a snapshot written by SaveSnapshot
+func LoadSnapshot(r io.Reader) (*QuadTree, error) {
+	return LoadSnapshotOf[any](r)
+}
+
+// LoadSnapshotOf rebuilds a QuadTree storing Data of type T
+// from a snapshot written by SaveSnapshot
+func LoadSnapshotOf[T comparable](r io.Reader) (*QuadTreeOf[T], error) {
+	// --- Version check ---
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return nil, fmt.Errorf("quadtree: reading snapshot: %w", err)
+	}
+	if version[0] != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d (expected %d)", ErrSnapshotVersion, version[0], snapshotVersion)
+	}
+
+	// --- Decoding ---
+	var root nodeRecord[T]
+	if err := gob.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("quadtree: reading snapshot: %w", err)
+	}
+
+	qt := &QuadTreeOf[T]{}
+	if err := qt.loadRecord(&root); err != nil {
+		return nil, err
+	}
+	return qt, nil
+}
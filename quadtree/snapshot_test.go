@@ -0,0 +1,188 @@
+package quadtree // Tests for the binary snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// TestQuadTreeSnapshotRoundTrip verifies that a tree written with
+// SaveSnapshot is reloaded with the same structure and content.
+func TestQuadTreeSnapshotRoundTrip(t *testing.T) {
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&PointOf[string]{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: "driver"})
+	}
+
+	var buf bytes.Buffer
+	if err := qt.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	loaded, err := LoadSnapshotOf[string](&buf)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+
+	// --- Test 1: Same number of points ---
+	if loaded.Count() != qt.Count() {
+		t.Errorf("Count: %d expected, got %d", qt.Count(), loaded.Count())
+	}
+	// --- Test 2: Same structure and leaf content ---
+	if loaded.Fingerprint() != qt.Fingerprint() {
+		t.Error("Fingerprint differs after the round-trip")
+	}
+}
+
+// TestQuadTreeSnapshotVersion verifies that snapshots with an
+// unknown format version are rejected.
+func TestQuadTreeSnapshotVersion(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 4)
+	qt.Insert(&Point{X: 1, Y: 1, Data: "p1"})
+
+	var buf bytes.Buffer
+	if err := qt.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// Pretend the snapshot was written by a newer version
+	data := buf.Bytes()
+	data[0] = snapshotVersion + 1
+
+	if _, err := LoadSnapshot(bytes.NewReader(data)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("LoadSnapshot with unknown version: ErrSnapshotVersion expected, got %v", err)
+	}
+	// An empty input is also an error, not a panic
+	if _, err := LoadSnapshot(bytes.NewReader(nil)); err == nil {
+		t.Error("LoadSnapshot on empty input should fail")
+	}
+}
+
+// TestQuadTreeSnapshotConcurrent verifies that a snapshot taken
+// while other goroutines insert is always a consistent tree.
+func TestQuadTreeSnapshotConcurrent(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				qt.Insert(&Point{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: i})
+			}
+		}(int64(w))
+	}
+
+	// Take snapshots while the writers are running
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := qt.SaveSnapshot(&buf); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+		if _, err := LoadSnapshot(&buf); err != nil {
+			t.Fatalf("LoadSnapshot of a concurrent snapshot failed: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+// TestQuadTreeCopiesWithNodeLocks verifies that the whole-tree copies
+// (Snapshot, Clone, SaveSnapshot, MarshalJSON) of a tree with one lock per
+// node are consistent: each writer inserts 0, 1, 2... in order, so a copy
+// must hold exactly the first k values of every writer, never a gap
+func TestQuadTreeCopiesWithNodeLocks(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 4, WithShards(2))
+
+	const writers, perWriter = 4, 20000
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWriter; i++ {
+				qt.Insert(&PointOf[int]{X: (rng.Float64() * 360) - 180, Y: (rng.Float64() * 180) - 90, Data: w*perWriter + i})
+			}
+		}(w)
+	}
+
+	// consistent reports what is wrong with a copy holding these points
+	consistent := func(count int, points []*PointOf[int]) string {
+		if count != len(points) {
+			return fmt.Sprintf("Count %d but %d points", count, len(points))
+		}
+		seen := make(map[int]bool, len(points))
+		for _, p := range points {
+			if seen[p.Data] {
+				return fmt.Sprintf("point %d copied twice", p.Data)
+			}
+			seen[p.Data] = true
+		}
+		for _, p := range points {
+			if i := p.Data % perWriter; i > 0 && !seen[p.Data-1] {
+				return fmt.Sprintf("point %d copied without %d", p.Data, p.Data-1)
+			}
+		}
+		return ""
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	// Copy the tree again and again while the writers are running
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		// --- Test 1: Snapshot ---
+		view := qt.Snapshot()
+		all := view.Query(&Boundary{X: 0, Y: 0, Width: 180, Height: 90})
+		if msg := consistent(view.Count(), all); msg != "" {
+			t.Fatalf("Snapshot: %s", msg)
+		}
+
+		// --- Test 2: Clone ---
+		clone := qt.Clone()
+		if msg := consistent(clone.Count(), clone.AllPoints()); msg != "" {
+			t.Fatalf("Clone: %s", msg)
+		}
+
+		// --- Test 3: SaveSnapshot ---
+		var buf bytes.Buffer
+		if err := qt.SaveSnapshot(&buf); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+		loaded, err := LoadSnapshotOf[int](&buf)
+		if err != nil {
+			t.Fatalf("LoadSnapshot failed: %v", err)
+		}
+		if msg := consistent(loaded.Count(), loaded.AllPoints()); msg != "" {
+			t.Fatalf("SaveSnapshot: %s", msg)
+		}
+
+		// --- Test 4: MarshalJSON ---
+		data, err := json.Marshal(qt)
+		if err != nil {
+			t.Fatalf("MarshalJSON failed: %v", err)
+		}
+		fromJSON := NewQuadTreeOf[int](Boundary{}, 1)
+		if err := json.Unmarshal(data, fromJSON); err != nil {
+			t.Fatalf("UnmarshalJSON failed: %v", err)
+		}
+		if msg := consistent(fromJSON.Count(), fromJSON.AllPoints()); msg != "" {
+			t.Fatalf("MarshalJSON: %s", msg)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package quadtree // Distance-sorted range queries on the QuadTree
+
+import (
+	"fmt"     // Import formatting package (Sprint)
+	"sort"    // Import sort package (Slice)
+	"strings" // Import strings package (Compare)
+)
+
+// QuerySorted is like Query, but returns the points ordered by their
+// distance from (fromX, fromY), closest first (Euclidean distance
+// in degree space, or the tree's DistanceFunc, like NearestNeighbor).
+// Each distance is computed once per point. Ties are broken by Y, then
+// by X, then by Data (see SortByDistance), so the order depends neither
+// on the shape of the tree nor on the order of the inserts.
+func (qt *QuadTreeOf[T]) QuerySorted(rangeRect *Boundary, fromX, fromY float64) []*PointOf[T] {
+	found := qt.Query(rangeRect)
+
+	// Compute the distances once, instead of at every comparison
+	dist := qt.distanceFunc(nil)
+	if dist == nil {
+		dist = SquaredEuclidean
+	}
+	candidates := make([]neighbor[T], len(found))
+	for i, p := range found {
+		candidates[i] = neighbor[T]{p: p, dist: dist(fromX, fromY, p.X, p.Y)}
+	}
+	sortNeighbors(candidates, nil)
+
+	for i, n := range candidates {
+		found[i] = n.p
+	}
+	return found
+}
+
+// SortByDistance sorts points (e.g. the copies of QueryCopy, or the
+// results of several trees put together) by their distance from
+// (fromX, fromY) measured with fn (nil: SquaredEuclidean), closest first.
+// The points at the same distance are ordered by cmp, e.g. by ID to page
+// through them with a (distance, ID) cursor. A nil cmp gives the order of
+// QuerySorted: by Y, then by X, and the points at the same spot by Data,
+// compared through fmt.Sprint (for a struct, its first field first).
+// Either way equal inputs always come out in the same order, as long as
+// cmp only returns 0 for equal Data.
+func SortByDistance[T comparable](points []PointOf[T], fromX, fromY float64, fn DistanceFunc, cmp func(a, b T) int) {
+	if fn == nil {
+		fn = SquaredEuclidean
+	}
+	candidates := make([]neighbor[T], len(points))
+	for i := range points {
+		p := &points[i]
+		candidates[i] = neighbor[T]{p: p, dist: fn(fromX, fromY, p.X, p.Y)}
+	}
+	sortNeighbors(candidates, cmp)
+
+	sorted := make([]PointOf[T], len(points))
+	for i, n := range candidates {
+		sorted[i] = *n.p
+	}
+	copy(points, sorted)
+}
+
+// sortNeighbors sorts candidates closest first, then by cmp (nil: by Y,
+// X and Data, see SortByDistance)
+func sortNeighbors[T comparable](candidates []neighbor[T], cmp func(a, b T) int) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if cmp != nil {
+			return cmp(a.p.Data, b.p.Data) < 0
+		}
+		if a.p.Y != b.p.Y {
+			return a.p.Y < b.p.Y
+		}
+		if a.p.X != b.p.X {
+			return a.p.X < b.p.X
+		}
+		// Only for the points at the same spot: Sprint is slow, but rare
+		return a.p.Data != b.p.Data && strings.Compare(fmt.Sprint(a.p.Data), fmt.Sprint(b.p.Data)) < 0
+	})
+}
@@ -0,0 +1,90 @@
+package quadtree // Tests for the distance-sorted queries
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestQuadTreeQuerySorted checks that QuerySorted returns the same points
+// as Query, closest first, with a stable order for ties
+func TestQuadTreeQuerySorted(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		qt.Insert(&Point{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: i})
+	}
+	area := &Boundary{X: 10, Y: 10, Width: 40, Height: 40}
+
+	// --- Test 1: same points as Query, closest first ---
+	sorted := qt.QuerySorted(area, 10, 10)
+	if len(sorted) != len(qt.Query(area)) {
+		t.Fatalf("QuerySorted: %d points expected, got %d", len(qt.Query(area)), len(sorted))
+	}
+	distSq := func(p *Point) float64 { return (p.X-10)*(p.X-10) + (p.Y-10)*(p.Y-10) }
+	for i := 1; i < len(sorted); i++ {
+		if distSq(sorted[i]) < distSq(sorted[i-1]) {
+			t.Fatalf("QuerySorted: point %d is closer than point %d", i, i-1)
+		}
+	}
+
+	// --- Test 2: ties are broken by Y, then X ---
+	ties := NewQuadTree(Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 1)
+	ties.Insert(&Point{X: 1, Y: 0, Data: "east"})
+	ties.Insert(&Point{X: 0, Y: 1, Data: "north"})
+	ties.Insert(&Point{X: -1, Y: 0, Data: "west"})
+	ties.Insert(&Point{X: 0, Y: -1, Data: "south"})
+	got := ties.QuerySorted(&Boundary{X: 0, Y: 0, Width: 10, Height: 10}, 0, 0)
+	want := []string{"south", "west", "east", "north"}
+	for i, p := range got {
+		if p.Data != want[i] {
+			t.Errorf("Tie order: %v expected at %d, got %v", want[i], i, p.Data)
+		}
+	}
+}
+
+// TestQuadTreeQuerySortedSameSpot checks that the points at the same spot
+// come out by Data, whatever the order of the inserts
+func TestQuadTreeQuerySortedSameSpot(t *testing.T) {
+	type driver struct {
+		ID    string
+		State string
+	}
+	area := &Boundary{X: 0, Y: 0, Width: 10, Height: 10}
+	for _, order := range [][]string{{"a", "b", "c"}, {"c", "a", "b"}, {"b", "c", "a"}} {
+		// --- Test 1: QuerySorted ---
+		qt := NewQuadTreeOf[driver](*area, 1)
+		for _, id := range order {
+			qt.Insert(&PointOf[driver]{X: 1, Y: 1, Data: driver{ID: id, State: "busy"}})
+		}
+		got := qt.QuerySorted(area, 0, 0)
+		if len(got) != 3 || got[0].Data.ID != "a" || got[1].Data.ID != "b" || got[2].Data.ID != "c" {
+			t.Errorf("Inserted as %v: a, b, c expected", order)
+		}
+
+		// --- Test 2: SortByDistance, on copies ---
+		points := []PointOf[driver]{{X: 5, Y: 5, Data: driver{ID: "far"}}}
+		for _, id := range order {
+			points = append(points, PointOf[driver]{X: 1, Y: 1, Data: driver{ID: id}})
+		}
+		SortByDistance(points, 0, 0, HaversineDistance, nil)
+		if points[0].Data.ID != "a" || points[1].Data.ID != "b" || points[2].Data.ID != "c" || points[3].Data.ID != "far" {
+			t.Errorf("SortByDistance of %v: a, b, c, far expected, got %v", order, points)
+		}
+	}
+
+	// --- Test 3: with cmp, equal distances are ordered by it alone ---
+	points := []PointOf[driver]{
+		{X: 0, Y: 1, Data: driver{ID: "z-north"}},
+		{X: 2, Y: 0, Data: driver{ID: "far"}},
+		{X: 0, Y: -1, Data: driver{ID: "y-south"}},
+		{X: -1, Y: 0, Data: driver{ID: "x-west"}},
+	}
+	SortByDistance(points, 0, 0, nil, func(a, b driver) int { return strings.Compare(a.ID, b.ID) })
+	want := []string{"x-west", "y-south", "z-north", "far"}
+	for i, p := range points {
+		if p.Data.ID != want[i] {
+			t.Errorf("cmp order: %s expected at %d, got %s", want[i], i, p.Data.ID)
+		}
+	}
+}
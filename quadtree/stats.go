@@ -0,0 +1,67 @@
+package quadtree // Shape statistics of the QuadTree
+
+// TreeStats describes the shape of a tree, for debugging and capacity tuning
+type TreeStats struct {
+	Points           int     `json:"points"`              // Total number of points
+	Nodes            int     `json:"nodes"`               // Total number of nodes (root included)
+	Leaves           int     `json:"leaves"`              // Nodes without children
+	EmptyLeaves      int     `json:"empty_leaves"`        // Leaves without points
+	Depth            int     `json:"depth"`               // Levels below the root (0 = not subdivided)
+	AvgPointsPerLeaf float64 `json:"avg_points_per_leaf"` // Points / Leaves
+}
+
+// Stats collects the statistics of the tree in a single traversal.
+// It takes Read Locks, so it is safe to call while the tree is being modified.
+func (qt *QuadTreeOf[T]) Stats() TreeStats {
+	var stats TreeStats
+	qt.statsRecursive(0, &stats)
+
+	if stats.Leaves > 0 {
+		stats.AvgPointsPerLeaf = float64(stats.Points) / float64(stats.Leaves)
+	}
+	return stats
+}
+
+// statsRecursive is the internal helper that visits every node.
+// 'level' is the depth of this node below the node Stats was called on.
+func (qt *QuadTreeOf[T]) statsRecursive(level int, stats *TreeStats) {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	stats.Nodes++
+	stats.Depth = max(stats.Depth, level)
+
+	// If this is a "leaf" node, count its points
+	if qt.northWest == nil {
+		stats.Leaves++
+		stats.Points += len(qt.points)
+		if len(qt.points) == 0 {
+			stats.EmptyLeaves++
+		}
+		return
+	}
+
+	// If this is a "parent" node, visit the four children
+	qt.northWest.statsRecursive(level+1, stats)
+	qt.northEast.statsRecursive(level+1, stats)
+	qt.southWest.statsRecursive(level+1, stats)
+	qt.southEast.statsRecursive(level+1, stats)
+}
+
+// Depth returns the maximum depth reached by the tree: 0 for a root
+// that was never subdivided, plus 1 for every level of children
+func (qt *QuadTreeOf[T]) Depth() int {
+	// Acquire a Read Lock, like queryRecursive
+	qt.rlock()
+	defer qt.runlock()
+
+	// A "leaf" node adds no levels
+	if qt.northWest == nil {
+		return 0
+	}
+
+	// A "parent" node is one level above its deepest child
+	return 1 + max(qt.northWest.Depth(), qt.northEast.Depth(),
+		qt.southWest.Depth(), qt.southEast.Depth())
+}
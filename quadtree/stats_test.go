@@ -0,0 +1,75 @@
+package quadtree // Tests for the shape statistics
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestQuadTreeDepth verifies Depth and its value in TreeStats
+func TestQuadTreeDepth(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// --- Test 1: an empty root ---
+	if d := qt.Depth(); d != 0 {
+		t.Errorf("Empty tree: depth 0 expected, got %d", d)
+	}
+
+	// --- Test 2: one split ---
+	for _, xy := range [][2]float64{{-50, -50}, {50, -50}, {-50, 50}} {
+		qt.Insert(&Point{X: xy[0], Y: xy[1]})
+	}
+	if d := qt.Depth(); d != 1 {
+		t.Errorf("After one split: depth 1 expected, got %d", d)
+	}
+
+	// --- Test 3: points packed in a corner push the depth down ---
+	for i := 0; i < 10; i++ {
+		qt.Insert(&Point{X: 90 + float64(i)*0.1, Y: 90, Data: i})
+	}
+	if d := qt.Depth(); d != maxDepthOf(qt) {
+		t.Errorf("Depth() = %d, but the deepest node is at %d", d, maxDepthOf(qt))
+	}
+
+	stats := qt.Stats()
+	if stats.Depth != qt.Depth() || stats.Points != 13 {
+		t.Errorf("Stats: %+v does not match the tree", stats)
+	}
+}
+
+// TestQuadTreeStats verifies every field of Stats on a known layout
+func TestQuadTreeStats(t *testing.T) {
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2)
+
+	// --- Test 1: an empty tree is a single empty leaf ---
+	expected := TreeStats{Nodes: 1, Leaves: 1, EmptyLeaves: 1}
+	if stats := qt.Stats(); stats != expected {
+		t.Errorf("Empty tree: %+v expected, got %+v", expected, stats)
+	}
+
+	// --- Test 2: three points split the root once ---
+	// NW, NE and SW get one point each, SE stays empty
+	for _, xy := range [][2]float64{{-50, -50}, {50, -50}, {-50, 50}} {
+		qt.Insert(&Point{X: xy[0], Y: xy[1]})
+	}
+	expected = TreeStats{Points: 3, Nodes: 5, Leaves: 4, EmptyLeaves: 1, Depth: 1, AvgPointsPerLeaf: 0.75}
+	if stats := qt.Stats(); stats != expected {
+		t.Errorf("After one split: %+v expected, got %+v", expected, stats)
+	}
+
+	// --- Test 3: a larger tree stays consistent ---
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		qt.Insert(&Point{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: i})
+	}
+	stats := qt.Stats()
+	if stats.Points != qt.Count() {
+		t.Errorf("Points: %d expected, got %d", qt.Count(), stats.Points)
+	}
+	// Every subdivision turns 1 leaf into 4: nodes = 4*parents + 1
+	if parents := stats.Nodes - stats.Leaves; stats.Nodes != 4*parents+1 {
+		t.Errorf("Inconsistent node count: %+v", stats)
+	}
+	if stats.Depth != qt.Depth() {
+		t.Errorf("Depth: %d expected, got %d", qt.Depth(), stats.Depth)
+	}
+}
@@ -0,0 +1,199 @@
+package quadtree // Per-point time to live (TTL) and the expiry sweeper
+
+import (
+	"sync"        // Import concurrency package (RWMutex, Once, WaitGroup)
+	"sync/atomic" // Import atomic package (Int64)
+	"time"        // Import time package (Duration, Time, Ticker)
+)
+
+// expiryIndex keeps the expiry of the points inserted WithTTL.
+// Like the labels, it lives on the root, is kept in sync by
+// Insert/Remove/Update and has its own lock, independent from the node locks.
+type expiryIndex[T comparable] struct {
+	mu      sync.RWMutex
+	byPoint map[*PointOf[T]]expiry
+	// Number of points with a TTL: trees without any skip the lock entirely
+	count atomic.Int64
+	// The clock (see WithClock), nil for time.Now
+	now func() time.Time
+}
+
+// expiry is the TTL of a point and the moment it runs out
+type expiry struct {
+	ttl time.Duration
+	at  time.Time
+}
+
+// WithClock makes the TTLs of the tree use now instead of time.Now,
+// e.g. a fake clock in tests (a nil now keeps time.Now)
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.clock = now
+	}
+}
+
+// clock returns the current time
+func (ei *expiryIndex[T]) clock() time.Time {
+	if ei.now != nil {
+		return ei.now()
+	}
+	return time.Now()
+}
+
+// set gives p a TTL starting now
+func (ei *expiryIndex[T]) set(p *PointOf[T], ttl time.Duration) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	// The map is created lazily: trees without TTLs pay nothing
+	if ei.byPoint == nil {
+		ei.byPoint = map[*PointOf[T]]expiry{}
+	}
+	if _, ok := ei.byPoint[p]; !ok {
+		ei.count.Add(1)
+	}
+	ei.byPoint[p] = expiry{ttl: ttl, at: ei.clock().Add(ttl)}
+}
+
+// drop forgets the TTL of p
+func (ei *expiryIndex[T]) drop(p *PointOf[T]) {
+	if ei.count.Load() == 0 {
+		return
+	}
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	if _, ok := ei.byPoint[p]; ok {
+		delete(ei.byPoint, p)
+		ei.count.Add(-1)
+	}
+}
+
+// move hands the TTL of 'from' over to 'to' (used by Update).
+// A point that moves is still alive: its TTL starts over.
+func (ei *expiryIndex[T]) move(from, to *PointOf[T]) {
+	if ei.count.Load() == 0 {
+		return
+	}
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	e, ok := ei.byPoint[from]
+	if !ok {
+		return
+	}
+	delete(ei.byPoint, from)
+	ei.byPoint[to] = expiry{ttl: e.ttl, at: ei.clock().Add(e.ttl)}
+}
+
+// reset empties the index
+func (ei *expiryIndex[T]) reset() {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	ei.byPoint = nil
+	ei.count.Store(0)
+}
+
+// live wraps keep so that it also leaves out the points expired by now.
+// Every search passes it down to its leaf scan, which calls it under the
+// leaf's Read Lock (the node locks always come before the expiry lock).
+// Without any TTL in the tree, keep is returned as is (nil included), so
+// the searches pay nothing and the counts keep their fast paths.
+func (ei *expiryIndex[T]) live(keep func(*PointOf[T]) bool) func(*PointOf[T]) bool {
+	if ei.count.Load() == 0 {
+		return keep
+	}
+	now := ei.clock()
+	return func(p *PointOf[T]) bool {
+		ei.mu.RLock()
+		e, ok := ei.byPoint[p]
+		ei.mu.RUnlock()
+		if ok && !now.Before(e.at) {
+			return false
+		}
+		return keep == nil || keep(p)
+	}
+}
+
+// expired returns the points whose TTL has run out
+func (ei *expiryIndex[T]) expired() []*PointOf[T] {
+	if ei.count.Load() == 0 {
+		return nil
+	}
+	ei.mu.RLock()
+	defer ei.mu.RUnlock()
+
+	now := ei.clock()
+	var expired []*PointOf[T]
+	for p, e := range ei.byPoint {
+		if !now.Before(e.at) {
+			expired = append(expired, p)
+		}
+	}
+	return expired
+}
+
+// InsertWithTTL inserts p, to be removed once ttl has passed
+// (see StartExpiry). Until then, every search and every count already
+// leaves it out once it is expired (see expiryIndex.live).
+// Update (or MoveByID) starts the TTL over: a point that moves is alive.
+// A ttl <= 0 inserts p without expiry, like Insert.
+func (qt *QuadTreeOf[T]) InsertWithTTL(p *PointOf[T], ttl time.Duration) bool {
+	if !qt.Insert(p) {
+		return false
+	}
+	if ttl > 0 {
+		qt.expiry.set(p, ttl)
+	}
+	return true
+}
+
+// RemoveExpired removes the points whose TTL has run out and returns
+// how many it removed. StartExpiry calls it periodically.
+func (qt *QuadTreeOf[T]) RemoveExpired() int {
+	removed := 0
+	for _, p := range qt.expiry.expired() {
+		// Like Remove, this also drops the TTL (and the labels, and the ID),
+		// but only p itself goes: another point equal to it, e.g. inserted
+		// again after p was removed, has a TTL of its own
+		if qt.removePoint(p, true) {
+			removed++
+		} else {
+			// Not in the tree anymore (removed or moved meanwhile)
+			qt.expiry.drop(p)
+		}
+	}
+	return removed
+}
+
+// StartExpiry starts a goroutine calling RemoveExpired every interval.
+// The returned stop func ends it and waits until it is done; calling it
+// again is a no-op. A non-positive interval starts nothing.
+func (qt *QuadTreeOf[T]) StartExpiry(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				qt.RemoveExpired()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
@@ -0,0 +1,243 @@
+package quadtree // Tests for the per-point TTL
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the tests move by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// TestQuadTreeTTL verifies that expired points leave the query results
+// right away, and the tree once RemoveExpired runs
+func TestQuadTreeTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2,
+		WithIDIndex(), WithClock(clock.Now))
+	area := &Boundary{X: 0, Y: 0, Width: 100, Height: 100}
+
+	qt.InsertWithTTL(&PointOf[string]{X: 1, Y: 1, Data: "short"}, time.Minute)
+	qt.InsertWithTTL(&PointOf[string]{X: 2, Y: 2, Data: "long"}, time.Hour)
+	qt.InsertWithTTL(&PointOf[string]{X: 3, Y: 3, Data: "forever"}, 0)
+	qt.Insert(&PointOf[string]{X: 4, Y: 4, Data: "plain"})
+
+	// --- Test 1: nothing expired yet ---
+	if got := len(qt.Query(area)); got != 4 {
+		t.Fatalf("4 points expected, got %d", got)
+	}
+	if n := qt.RemoveExpired(); n != 0 {
+		t.Errorf("Nothing to remove expected, got %d", n)
+	}
+
+	// --- Test 2: expired points are filtered out before the sweep ---
+	clock.Advance(time.Minute) // Exactly the TTL of "short"
+	for _, p := range qt.Query(area) {
+		if p.Data == "short" {
+			t.Errorf("The expired point must not be returned by Query")
+		}
+	}
+	if got := len(qt.QueryFilter(area, nil)); got != 3 {
+		t.Errorf("QueryFilter: 3 points expected, got %d", got)
+	}
+	if qt.Count() != 4 {
+		t.Errorf("Not swept yet: 4 points still in the tree expected, got %d", qt.Count())
+	}
+
+	// --- Test 3: the sweep removes it from the tree and the ID index ---
+	if n := qt.RemoveExpired(); n != 1 {
+		t.Errorf("1 point removed expected, got %d", n)
+	}
+	if _, ok := qt.GetByID("short"); ok || qt.Count() != 3 {
+		t.Errorf("short must be gone, %d points left", qt.Count())
+	}
+
+	// --- Test 4: a move starts the TTL over ---
+	clock.Advance(50 * time.Minute)
+	if qt.MoveByID("long", 5, 5) == nil {
+		t.Fatal("MoveByID failed")
+	}
+	clock.Advance(30 * time.Minute) // 80 minutes after the insert, 30 after the move
+	if n := qt.RemoveExpired(); n != 0 {
+		t.Errorf("The moved point must still be alive, %d removed", n)
+	}
+	clock.Advance(30 * time.Minute)
+	if n := qt.RemoveExpired(); n != 1 {
+		t.Errorf("The moved point must expire an hour after the move, %d removed", n)
+	}
+
+	// --- Test 5: points without TTL never expire ---
+	clock.Advance(24 * 365 * time.Hour)
+	if n := qt.RemoveExpired(); n != 0 || qt.Count() != 2 {
+		t.Errorf("forever and plain must stay, %d removed, %d left", n, qt.Count())
+	}
+
+	// --- Test 6: Remove and Clear drop the TTLs ---
+	p := &PointOf[string]{X: 6, Y: 6, Data: "removed"}
+	qt.InsertWithTTL(p, time.Second)
+	qt.Remove(p)
+	qt.InsertWithTTL(&PointOf[string]{X: 7, Y: 7, Data: "cleared"}, time.Second)
+	qt.Clear()
+	if n := qt.expiry.count.Load(); n != 0 {
+		t.Errorf("No TTL left expected, got %d", n)
+	}
+}
+
+// TestQuadTreeTTLEverySearch verifies that every search and every count
+// leaves out the expired points, before any sweep
+func TestQuadTreeTTLEverySearch(t *testing.T) {
+	for _, opts := range [][]Option{{}, {WithShards(1)}} {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 180, Height: 90}, 2,
+			append(opts, WithClock(clock.Now))...)
+		// Even Data expire, odd Data stay: the expired ones are the closest
+		for i := 0; i < 40; i++ {
+			p := &PointOf[int]{X: float64(i) * 0.1, Y: 0, Data: i}
+			if i%2 == 0 {
+				qt.InsertWithTTL(p, time.Minute)
+			} else {
+				qt.Insert(p)
+			}
+		}
+		clock.Advance(time.Minute)
+		area := &Boundary{X: 0, Y: 0, Width: 180, Height: 90}
+		target := &PointOf[int]{X: 0, Y: 0}
+
+		// alive checks that found holds want points, none of them expired
+		alive := func(name string, found []*PointOf[int], want int) {
+			t.Helper()
+			if len(found) != want {
+				t.Errorf("%s: %d points expected, got %d", name, want, len(found))
+			}
+			for _, p := range found {
+				if p.Data%2 == 0 {
+					t.Errorf("%s returned the expired point %d", name, p.Data)
+					return
+				}
+			}
+		}
+
+		// --- Test 1: the range searches ---
+		found, _ := qt.QueryContext(context.Background(), area)
+		alive("QueryContext", found, 20)
+		found, _ = qt.QueryWrappedContext(context.Background(), &Boundary{X: 180, Y: 0, Width: 180, Height: 90})
+		alive("QueryWrappedContext", found, 20)
+		copies, _ := qt.QueryWrappedCopyContext(context.Background(), area)
+		found = found[:0]
+		for i := range copies {
+			found = append(found, &copies[i])
+		}
+		alive("QueryWrappedCopyContext", found, 20)
+		found = found[:0]
+		qt.ForEachInRange(area, func(p *PointOf[int]) bool {
+			found = append(found, p)
+			return true
+		})
+		alive("ForEachInRange", found, 20)
+		alive("QueryLimit", qt.QueryLimit(area, 5), 5)
+		alive("QueryCircle", qt.QueryCircle(0, 0, 10), 20)
+		alive("QueryMulti", qt.QueryMulti([]*Boundary{area})[0], 20)
+
+		// --- Test 2: the nearest searches ---
+		if p, _, ok := qt.Nearest(target); !ok || p.Data != 1 {
+			t.Errorf("Nearest: the live point 1 expected, got %v", p)
+		}
+		alive("QueryKNearest", qt.QueryKNearest(target, 3), 3)
+
+		// --- Test 3: the counts ---
+		if n := qt.CountInRange(area); n != 20 {
+			t.Errorf("CountInRange: 20 expected, got %d", n)
+		}
+		if n := qt.CountGrid(area, 1, 1)[0][0]; n != 20 {
+			t.Errorf("CountGrid: 20 expected, got %d", n)
+		}
+
+		// --- Test 4: a Snapshot leaves the expired points out too ---
+		view := qt.Snapshot()
+		alive("View Query", view.Query(area), 20)
+		alive("View QueryKNearest", view.QueryKNearest(target, 3), 3)
+		if view.Count() != 20 || view.CountInRange(area) != 20 {
+			t.Errorf("View: 20 points expected, Count %d, CountInRange %d", view.Count(), view.CountInRange(area))
+		}
+
+		// --- Test 5: the saved JSON leaves them out as well (no TTL is saved) ---
+		data, err := json.Marshal(qt)
+		if err != nil {
+			t.Fatalf("MarshalJSON failed: %v", err)
+		}
+		loaded := NewQuadTreeOf[int](Boundary{}, 1)
+		if err := json.Unmarshal(data, loaded); err != nil {
+			t.Fatalf("UnmarshalJSON failed: %v", err)
+		}
+		if loaded.Count() != 20 {
+			t.Errorf("JSON: 20 points expected, got %d", loaded.Count())
+		}
+	}
+}
+
+// TestQuadTreeRemoveExpiredExact verifies that RemoveExpired only removes
+// the expired point itself, never another point equal to it
+func TestQuadTreeRemoveExpiredExact(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	qt := NewQuadTreeOf[string](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4, WithClock(clock.Now))
+
+	// p leaves the tree, but the sweep still sees its TTL (as when a
+	// Remove runs concurrently), and q, equal to p, takes its place
+	p := &PointOf[string]{X: 1, Y: 1, Data: "driver"}
+	qt.InsertWithTTL(p, time.Minute)
+	qt.remove(p, true)
+	q := &PointOf[string]{X: 1, Y: 1, Data: "driver"}
+	qt.Insert(q)
+
+	clock.Advance(time.Minute)
+	if n := qt.RemoveExpired(); n != 0 {
+		t.Errorf("Nothing to remove expected, got %d", n)
+	}
+	if found := qt.Query(&Boundary{X: 1, Y: 1, Width: 1, Height: 1}); len(found) != 1 || found[0] != q {
+		t.Errorf("q must stay in the tree, found %v", found)
+	}
+	if n := qt.expiry.count.Load(); n != 0 {
+		t.Errorf("The TTL of p must be dropped, %d left", n)
+	}
+}
+
+// TestQuadTreeStartExpiry runs the sweeper on a real ticker, with the
+// TTLs on the fake clock: the point is already expired, so the first
+// tick removes it
+func TestQuadTreeStartExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	qt := NewQuadTree(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 2, WithClock(clock.Now))
+	qt.InsertWithTTL(&Point{X: 1, Y: 1, Data: "ghost"}, time.Second)
+	clock.Advance(time.Second)
+
+	stop := qt.StartExpiry(time.Millisecond)
+	defer stop()
+	deadline := time.Now().Add(3 * time.Second)
+	for qt.Count() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if qt.Count() != 0 {
+		t.Errorf("The sweeper did not remove the expired point within 3 seconds")
+	}
+
+	// stop waits for the sweeper and can be called twice
+	stop()
+	stop()
+}
@@ -0,0 +1,69 @@
+package quadtree // Boundary validation and the checked constructors
+
+import (
+	"errors" // Import errors package (New)
+	"fmt"    // Import formatting package (Errorf)
+)
+
+// ErrInvalidBoundary is returned (wrapped) by Boundary.Validate and the
+// checked constructors for a boundary no point could ever fit in
+var ErrInvalidBoundary = errors.New("quadtree: invalid boundary")
+
+// Validate checks that the boundary can hold points: a finite center,
+// a finite and positive Width and Height, and edges that are still
+// distinct once computed (X-Width < X+Width). The last one catches
+// sizes too small for the center, e.g. Width 1 around X 1e20, where
+// float64 rounding makes the box empty. The error wraps ErrInvalidBoundary.
+func (b *Boundary) Validate() error {
+	if !isFinite(b.X) || !isFinite(b.Y) {
+		return fmt.Errorf("%w: center (%v, %v) is not finite", ErrInvalidBoundary, b.X, b.Y)
+	}
+	// !(> 0) also rejects NaN
+	if !(b.Width > 0) || !(b.Height > 0) || !isFinite(b.Width) || !isFinite(b.Height) {
+		return fmt.Errorf("%w: width %v and height %v must be positive and finite", ErrInvalidBoundary, b.Width, b.Height)
+	}
+	minX, maxX := b.X-b.Width, b.X+b.Width
+	minY, maxY := b.Y-b.Height, b.Y+b.Height
+	if !isFinite(minX) || !isFinite(maxX) || !isFinite(minY) || !isFinite(maxY) {
+		return fmt.Errorf("%w: edges overflow float64", ErrInvalidBoundary)
+	}
+	if !(minX < maxX) || !(minY < maxY) {
+		return fmt.Errorf("%w: width %v or height %v is too small for center (%v, %v)", ErrInvalidBoundary, b.Width, b.Height, b.X, b.Y)
+	}
+	return nil
+}
+
+// NewBoundaryFromCorners builds the Boundary going from (minX, minY) to
+// (maxX, maxY). Width and Height are half-sizes, which is easy to get
+// wrong by hand: a box from 0 to 40 has Width 20, not 40. It returns an
+// error (wrapping ErrInvalidBoundary) unless min < max on both axes and
+// every corner is finite.
+func NewBoundaryFromCorners(minX, minY, maxX, maxY float64) (Boundary, error) {
+	// !(<) also rejects NaN
+	if !(minX < maxX) || !(minY < maxY) {
+		return Boundary{}, fmt.Errorf("%w: corners (%v, %v) - (%v, %v) must have min < max", ErrInvalidBoundary, minX, minY, maxX, maxY)
+	}
+	b := fromMinMax(minX, minY, maxX, maxY)
+	if err := b.Validate(); err != nil {
+		return Boundary{}, err
+	}
+	return b, nil
+}
+
+// NewQuadTreeChecked is NewQuadTree, but it reports a malformed boundary
+// (see Boundary.Validate) or a capacity below 1 instead of building a tree
+// that rejects every point (NewQuadTree silently raises the capacity to 1).
+func NewQuadTreeChecked(boundary Boundary, capacity int, opts ...Option) (*QuadTree, error) {
+	return NewQuadTreeOfChecked[any](boundary, capacity, opts...)
+}
+
+// NewQuadTreeOfChecked is NewQuadTreeChecked for a QuadTree storing Data of type T
+func NewQuadTreeOfChecked[T comparable](boundary Boundary, capacity int, opts ...Option) (*QuadTreeOf[T], error) {
+	if err := boundary.Validate(); err != nil {
+		return nil, err
+	}
+	if capacity < 1 {
+		return nil, fmt.Errorf("%w (got %d)", ErrInvalidCapacity, capacity)
+	}
+	return NewQuadTreeOf[T](boundary, capacity, opts...), nil
+}
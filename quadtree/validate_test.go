@@ -0,0 +1,85 @@
+package quadtree // Tests for the boundary validation
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestBoundaryValidate verifies which boundaries can hold points
+func TestBoundaryValidate(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+
+	// --- Test 1: valid boundaries ---
+	for _, b := range []Boundary{
+		{X: 0, Y: 0, Width: 180, Height: 90},
+		{X: -50, Y: 1e6, Width: 1e-3, Height: 1e-3},
+	} {
+		if err := b.Validate(); err != nil {
+			t.Errorf("%+v: valid expected, got %v", b, err)
+		}
+	}
+
+	// --- Test 2: malformed boundaries ---
+	for name, b := range map[string]Boundary{
+		"zero width":      {X: 0, Y: 0, Width: 0, Height: 90},
+		"negative height": {X: 0, Y: 0, Width: 180, Height: -1},
+		"NaN center":      {X: nan, Y: 0, Width: 1, Height: 1},
+		"Inf center":      {X: 0, Y: -inf, Width: 1, Height: 1},
+		"NaN width":       {X: 0, Y: 0, Width: nan, Height: 1},
+		"Inf height":      {X: 0, Y: 0, Width: 1, Height: inf},
+		"overflow":        {X: math.MaxFloat64, Y: 0, Width: math.MaxFloat64, Height: 1},
+		"degenerate":      {X: 1e20, Y: 0, Width: 1, Height: 1},
+	} {
+		if err := b.Validate(); !errors.Is(err, ErrInvalidBoundary) {
+			t.Errorf("%s: ErrInvalidBoundary expected, got %v", name, err)
+		}
+	}
+}
+
+// TestNewQuadTreeChecked verifies that the checked constructor reports
+// what NewQuadTree silently accepts
+func TestNewQuadTreeChecked(t *testing.T) {
+	// --- Test 1: a valid tree works like NewQuadTree ---
+	qt, err := NewQuadTreeChecked(Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4)
+	if err != nil || !qt.Insert(&Point{X: 1, Y: 1}) {
+		t.Fatalf("Valid tree expected, got %v", err)
+	}
+
+	// --- Test 2: bad boundary, bad capacity ---
+	if _, err := NewQuadTreeChecked(Boundary{Width: 0, Height: 10}, 4); !errors.Is(err, ErrInvalidBoundary) {
+		t.Errorf("Zero width: ErrInvalidBoundary expected, got %v", err)
+	}
+	if _, err := NewQuadTreeOfChecked[string](Boundary{Width: 10, Height: 10}, 0); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Capacity 0: ErrInvalidCapacity expected, got %v", err)
+	}
+}
+
+// TestNewBoundaryFromCorners verifies the conversion from corners to
+// center and half-sizes, and back through the accessors
+func TestNewBoundaryFromCorners(t *testing.T) {
+	// --- Test 1: a 40x20 box is 20x10 in half-sizes ---
+	b, err := NewBoundaryFromCorners(0, 10, 40, 30)
+	if err != nil {
+		t.Fatalf("Valid corners: %v", err)
+	}
+	if b != (Boundary{X: 20, Y: 20, Width: 20, Height: 10}) {
+		t.Errorf("{20 20 20 10} expected, got %+v", b)
+	}
+	if b.MinX() != 0 || b.MinY() != 10 || b.MaxX() != 40 || b.MaxY() != 30 {
+		t.Errorf("Accessors: (0, 10) - (40, 30) expected, got (%v, %v) - (%v, %v)", b.MinX(), b.MinY(), b.MaxX(), b.MaxY())
+	}
+
+	// --- Test 2: swapped, empty or non-finite corners ---
+	for _, c := range [][4]float64{
+		{40, 10, 0, 30},                           // minX > maxX
+		{0, 10, 40, 10},                           // minY == maxY
+		{math.NaN(), 0, 1, 1},                     // NaN
+		{0, 0, math.Inf(1), 1},                    // Inf
+		{-math.MaxFloat64, 0, math.MaxFloat64, 1}, // Width overflows
+	} {
+		if _, err := NewBoundaryFromCorners(c[0], c[1], c[2], c[3]); !errors.Is(err, ErrInvalidBoundary) {
+			t.Errorf("%v: ErrInvalidBoundary expected, got %v", c, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package quadtree // Read-only snapshots queried without locks
+
+// QuadTreeView is a frozen copy of a tree, returned by Snapshot.
+// Nothing can change it, so its queries take no lock at all: any number
+// of goroutines can query it while writers keep changing the live tree
+// (once the Snapshot is taken: the writers wait while it copies).
+// The results reflect the tree at the time of the Snapshot: the points
+// whose TTL had run out then are not in the view (see InsertWithTTL),
+// while the others stay in it until the next Snapshot, even past their TTL.
+type QuadTreeView[T comparable] struct {
+	root *QuadTreeOf[T]
+}
+
+// Snapshot returns a read-only view of the tree as it is now.
+// Readers that tolerate slightly stale results query the view instead of
+// the tree, and take a new Snapshot from time to time (e.g. every second)
+// to catch up with the writers.
+//
+// This is not a copy-on-write view: every Snapshot is a full O(n) copy of
+// the nodes and of the points (like Clone, without the labels and the ID
+// index), and the writers of the live tree are stopped for the whole copy
+// (see lockWhole: the root's Read Lock with a single lock, its Write Lock
+// and a drain of the inserts with node locks), so it is one consistent
+// state of the tree. Readers query for free afterwards, but each refresh
+// costs a copy and a writer stall that grow with the tree: about 4 ms at
+// 10k points, 55 ms at 100k and 0.5 s at 1M on one core (see
+// BenchmarkSnapshotWriterStall). Refresh seldom on large trees.
+//
+// Memory: a view costs as much as the tree. Two views, or a view and the
+// tree, never share anything, so the old views are freed by the garbage
+// collector as soon as their last reader drops them.
+// See BenchmarkSnapshotQuery for the query side.
+func (qt *QuadTreeOf[T]) Snapshot() *QuadTreeView[T] {
+	// The points already expired (see InsertWithTTL) are left out, as
+	// every search of the tree would
+	root := qt.cloneRoot(nil, qt.expiry.live(nil))
+	root.freeze()
+	return &QuadTreeView[T]{root: root}
+}
+
+// freeze turns a cloned subtree into a lock-free, read-only one:
+// rlock/runlock become no-ops on every node
+func (qt *QuadTreeOf[T]) freeze() {
+	qt.noLock = true
+	qt.singleLock = false
+	qt.fixed = false
+	// Never hand the view's nodes back to the live tree's pool
+	qt.pool = nil
+	if qt.northWest != nil {
+		qt.northWest.freeze()
+		qt.northEast.freeze()
+		qt.southWest.freeze()
+		qt.southEast.freeze()
+	}
+}
+
+// Count returns the number of points in the view
+func (v *QuadTreeView[T]) Count() int {
+	return v.root.Count()
+}
+
+// Query finds all the points of the view within rangeRect (see QuadTreeOf.Query)
+func (v *QuadTreeView[T]) Query(rangeRect *Boundary) []*PointOf[T] {
+	return v.root.Query(rangeRect)
+}
+
+// QueryInto appends the points within rangeRect to buf (see QuadTreeOf.QueryInto)
+func (v *QuadTreeView[T]) QueryInto(rangeRect *Boundary, buf []*PointOf[T]) []*PointOf[T] {
+	return v.root.QueryInto(rangeRect, buf)
+}
+
+// QueryWrapped is Query for a box that may cross the antimeridian
+// (see QuadTreeOf.QueryWrapped)
+func (v *QuadTreeView[T]) QueryWrapped(rangeRect *Boundary) []*PointOf[T] {
+	return v.root.QueryWrapped(rangeRect)
+}
+
+// QueryKNearest returns the k points of the view closest to center
+// (see QuadTreeOf.QueryKNearest)
+func (v *QuadTreeView[T]) QueryKNearest(center *PointOf[T], k int) []*PointOf[T] {
+	return v.root.QueryKNearest(center, k)
+}
+
+// QueryKNearestFunc is QueryKNearest measuring the distances with fn
+// (see QuadTreeOf.QueryKNearestFunc)
+func (v *QuadTreeView[T]) QueryKNearestFunc(center *PointOf[T], k int, fn DistanceFunc) []*PointOf[T] {
+	return v.root.QueryKNearestFunc(center, k, fn)
+}
+
+// CountInRange returns the number of points of the view within rangeRect
+func (v *QuadTreeView[T]) CountInRange(rangeRect *Boundary) int {
+	return v.root.CountInRange(rangeRect)
+}
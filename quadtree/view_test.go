@@ -0,0 +1,66 @@
+package quadtree // Tests for the read-only snapshots
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// TestQuadTreeSnapshot verifies that a view keeps the tree as it was
+// when it was taken, whatever the writers do afterwards
+func TestQuadTreeSnapshot(t *testing.T) {
+	qt := NewQuadTreeOf[int](Boundary{X: 0, Y: 0, Width: 100, Height: 100}, 4, WithPooling())
+	rng := rand.New(rand.NewSource(3))
+	points := make([]*PointOf[int], 500)
+	for i := range points {
+		points[i] = &PointOf[int]{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: i}
+		qt.Insert(points[i])
+	}
+	area := &Boundary{X: 20, Y: 20, Width: 30, Height: 30}
+	expected := len(qt.Query(area))
+
+	view := qt.Snapshot()
+
+	// --- Test 1: same content as the tree ---
+	if view.Count() != 500 || len(view.Query(area)) != expected || view.CountInRange(area) != expected {
+		t.Fatalf("View: 500 points and %d in the area expected, got %d and %d", expected, view.Count(), len(view.Query(area)))
+	}
+
+	// --- Test 2: later changes to the tree don't reach the view ---
+	for i, p := range points[:250] {
+		if moved := qt.Update(p, -90, -90); moved != nil {
+			// With pooling the old point is zeroed and reused
+			qt.ReleasePoint(p)
+			points[i] = moved
+		}
+	}
+	qt.Insert(&PointOf[int]{X: 20, Y: 20, Data: 1000})
+	if view.Count() != 500 || len(view.Query(area)) != expected {
+		t.Errorf("The view changed with the tree: %d points, %d in the area", view.Count(), len(view.Query(area)))
+	}
+	for _, p := range view.Query(&Boundary{X: 0, Y: 0, Width: 100, Height: 100}) {
+		if p.Data == 0 && p.X == 0 && p.Y == 0 {
+			t.Fatal("The view shares its points with the tree")
+		}
+	}
+
+	// --- Test 3: lock-free queries while the tree is being written ---
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			qt.Insert(&PointOf[int]{X: rng.Float64()*200 - 100, Y: rng.Float64()*200 - 100, Data: 2000 + i})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if n := len(view.Query(area)); n != expected {
+				t.Errorf("View query during writes: %d points expected, got %d", expected, n)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter gives every client IP a token bucket: it holds up to 'burst'
+// tokens, refilled at 'rate' tokens per second, and each request takes one.
+// A client can send a burst of requests at once, then 'rate' per second.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time // time.Now, replaced by the tests
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is the state of one client: its tokens at time 'last'
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateSweepInterval is how often the buckets of idle clients are dropped
+const rateSweepInterval = time.Minute
+
+// newRateLimiter returns a limiter allowing 'rate' requests per second per IP,
+// with bursts of up to 'burst' requests. A rate of 0 disables it (nil).
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// allow takes a token from the bucket of 'ip'. When there is none left,
+// it returns false and how long until the next one.
+func (l *rateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		// A new client starts with a full bucket
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	}
+	// Refill for the time elapsed since the last request
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops the buckets that are full again: their clients have been
+// idle long enough that a fresh bucket is the same. The caller holds the lock.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for ip, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// middleware returns the middleware that rejects the requests beyond the
+// limit with 429 Too Many Requests and a Retry-After header.
+// With rate limiting disabled there is no middleware at all.
+// Clients are told apart by the address of the connection, not by
+// X-Forwarded-For, which any client can set: behind a reverse proxy,
+// all the clients of the proxy share its limit.
+func (l *rateLimiter) middleware() []gin.HandlerFunc {
+	if l == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{func(c *gin.Context) {
+		if ok, wait := l.allow(c.RemoteIP()); !ok {
+			// Retry-After is in whole seconds: round up
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, slow down"})
+			return
+		}
+		c.Next()
+	}}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRateLimit checks the token bucket of each client IP: the burst
+// goes through, the next request gets 429, and the tokens come back with time
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := newRateLimiter(2, 3) // 2 requests/s, bursts of 3
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(limiter.middleware()...)
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// --- Test 1: the burst passes, then 429 with Retry-After ---
+	for i := 0; i < 3; i++ {
+		if w := get("10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Request %d of the burst: status 200 expected, got %d", i+1, w.Code)
+		}
+	}
+	w := get("10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Beyond the burst: status 429 expected, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After 1 expected, got %q", got)
+	}
+
+	// --- Test 2: other IPs have their own bucket, whatever the port ---
+	if w := get("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("Another IP: status 200 expected, got %d", w.Code)
+	}
+	if w := get("10.0.0.1:5678"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Same IP, other port: status 429 expected, got %d", w.Code)
+	}
+
+	// --- Test 3: tokens come back at the rate ---
+	now = now.Add(500 * time.Millisecond) // One token
+	if w := get("10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("After 0.5s: status 200 expected, got %d", w.Code)
+	}
+	if w := get("10.0.0.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Token already used: status 429 expected, got %d", w.Code)
+	}
+
+	// --- Test 4: idle clients are swept ---
+	now = now.Add(2 * rateSweepInterval)
+	get("10.0.0.3:1234")
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Only the bucket of the last client expected, got %d buckets", len(limiter.buckets))
+	}
+
+	// --- Test 5: a rate of 0 disables the limiter ---
+	if newRateLimiter(0, 10).middleware() != nil {
+		t.Error("No middleware expected with a rate of 0")
+	}
+}
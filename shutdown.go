@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"GeoRunner/quadtree"
+)
+
+// serveUntilSignal runs the HTTP server until SIGINT or SIGTERM arrives,
+// then stops accepting connections and waits (up to drainTimeout)
+// for the in-flight requests. If snapshotPath is set (-snapshot-path or
+// SNAPSHOT_PATH), the tree of each fleet is saved next to it before
+// returning (see saveSnapshots).
+func serveUntilSignal(srv *http.Server, drainTimeout time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("API server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	// A second signal kills the process the usual way
+	stop()
+	log.Printf("Shutting down, draining requests for up to %s...", drainTimeout)
+
+	// Long-lived streams (/ws/nearby, /stream, /events/nearby) never finish on their own:
+	// they are cut when the timeout expires
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("Drain incomplete: %v", err)
+	}
+
+	if snapshotPath != "" {
+		saveSnapshots(snapshotPath)
+	}
+}
+
+// loadSnapshots puts back in every fleet the drivers that saveSnapshots
+// saved at the last shutdown. A fleet without a file starts empty (e.g.
+// on the first start); a file that can't be read is an error, rather
+// than a fresh start that would overwrite it at the next shutdown.
+func loadSnapshots(path string) error {
+	for _, f := range allFleets() {
+		fleetPath := path + "." + f.Name
+		n, err := loadSnapshot(fleetPath, f.Tree)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("fleet %s: %w", f.Name, err)
+		}
+		log.Printf("%d %s drivers restored from %s", n, f.Name, fleetPath)
+	}
+	return nil
+}
+
+// loadSnapshot reads the snapshot at path and adds its drivers to tree,
+// returning how many were added. The loaded tree has neither the ID index
+// nor the observer of the fleet trees (see newFleets): only its points
+// are kept.
+func loadSnapshot(path string, tree *quadtree.QuadTreeOf[DriverData]) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	loaded, err := quadtree.LoadSnapshotOf[DriverData](file)
+	if err != nil {
+		return 0, err
+	}
+	return tree.BatchInsert(loaded.AllPoints()), nil
+}
+
+// saveSnapshots saves the tree of every fleet to its own file,
+// named after path and the fleet (e.g. drivers.snapshot.car)
+func saveSnapshots(path string) {
+	for _, f := range allFleets() {
+		fleetPath := path + "." + f.Name
+		if err := saveSnapshot(fleetPath, f.Tree); err != nil {
+			log.Printf("Snapshot of fleet %s not saved: %v", f.Name, err)
+			continue
+		}
+		log.Printf("Snapshot of %d %s drivers saved to %s", f.Tree.Count(), f.Name, fleetPath)
+	}
+}
+
+// saveSnapshot writes a tree to path in the binary snapshot format.
+// It writes to a temporary file first and renames it, so a crash
+// halfway never leaves a truncated snapshot behind.
+func saveSnapshot(path string, tree *quadtree.QuadTreeOf[DriverData]) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Harmless after the rename: the temporary name is gone by then
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp makes the file private (0600): use the usual permissions
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tree.SaveSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GeoRunner/quadtree"
+)
+
+// TestSaveSnapshot saves the tree and loads it back
+func TestSaveSnapshot(t *testing.T) {
+	tree := resetFleets(t)
+	tree.Insert(&quadtree.PointOf[DriverData]{X: 10, Y: 20, Data: DriverData{ID: "d1", State: stateBusy}})
+	tree.Insert(&quadtree.PointOf[DriverData]{X: -30, Y: 40, Data: DriverData{ID: "d2"}})
+
+	path := filepath.Join(t.TempDir(), "drivers.snapshot")
+	if err := saveSnapshot(path, tree); err != nil {
+		t.Fatalf("saveSnapshot: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Opening the snapshot: %v", err)
+	}
+	defer f.Close()
+	loaded, err := quadtree.LoadSnapshotOf[DriverData](f)
+	if err != nil {
+		t.Fatalf("LoadSnapshotOf: %v", err)
+	}
+	if loaded.Count() != 2 {
+		t.Errorf("2 drivers expected in the snapshot, got %d", loaded.Count())
+	}
+	// The state is saved with the driver
+	if found := loaded.Query(&quadtree.Boundary{X: 10, Y: 20, Width: 1, Height: 1}); len(found) != 1 || found[0].Data.State != stateBusy {
+		t.Errorf("d1 busy expected in the snapshot, got %v", found)
+	}
+
+	// No temporary file is left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Only the snapshot expected in the directory, got %d files", len(entries))
+	}
+}
+
+// TestLoadSnapshots saves every fleet, starts again from empty fleets and
+// checks that the drivers come back in their fleet, findable by ID
+func TestLoadSnapshots(t *testing.T) {
+	resetFleets(t)
+	addDriver(fleets["car"], &quadtree.PointOf[DriverData]{X: 10, Y: 20, Data: DriverData{ID: "d1", State: stateBusy, SpeedKmh: 40}})
+	addDriver(fleets["bike"], &quadtree.PointOf[DriverData]{X: -30, Y: 40, Data: DriverData{ID: "d2"}})
+	path := filepath.Join(t.TempDir(), "drivers.snapshot")
+	saveSnapshots(path)
+
+	// --- Test 1: the round trip ---
+	resetFleets(t)
+	if err := loadSnapshots(path); err != nil {
+		t.Fatalf("loadSnapshots: %v", err)
+	}
+	f, p := fleetOf("d1")
+	if f == nil || f.Name != "car" || p.X != 10 || p.Y != 20 || p.Data.State != stateBusy || p.Data.SpeedKmh != 40 {
+		t.Errorf("d1 busy at (20, 10) in car expected, got %v %+v", f, p)
+	}
+	if f, _ := fleetOf("d2"); f == nil || f.Name != "bike" {
+		t.Errorf("d2 expected in bike, got %v", f)
+	}
+	// The restored drivers are indexed like the others: they can be moved
+	if _, _, err := moveDriver("d2", 41, -31, nil); err != nil {
+		t.Errorf("Moving a restored driver: %v", err)
+	}
+
+	// --- Test 2: no file is a fresh start, a broken one an error ---
+	resetFleets(t)
+	if err := loadSnapshots(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("No snapshot: a fresh start expected, got %v", err)
+	}
+	if err := os.WriteFile(path+".truck", []byte("not a snapshot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadSnapshots(path); err == nil {
+		t.Errorf("Broken snapshot: an error expected")
+	}
+}
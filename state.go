@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math/rand"
+
+	"GeoRunner/quadtree"
+)
+
+// The states of a driver. The state is part of the driver's Data
+// (DriverData.State), so it follows the driver through its moves.
+const (
+	stateAvailable = "available"
+	stateBusy      = "busy"
+	stateOffline   = "offline"
+)
+
+// stateTransition is the chance, at every tick, of switching to another state
+type stateTransition struct {
+	to     string
+	chance float64
+}
+
+// stateTransitions drives the simulated drivers: e.g. an available driver
+// takes a ride 5% of the times, and a busy one ends it 10% of the times.
+var stateTransitions = map[string][]stateTransition{
+	stateAvailable: {{stateBusy, 0.05}, {stateOffline, 0.01}},
+	stateBusy:      {{stateAvailable, 0.10}},
+	stateOffline:   {{stateAvailable, 0.05}},
+}
+
+// validState reports whether s is one of the driver states
+func validState(s string) bool {
+	_, ok := stateTransitions[s]
+	return ok
+}
+
+// nextState draws the state of the next tick
+func nextState(rng *rand.Rand, state string) string {
+	r := rng.Float64()
+	for _, t := range stateTransitions[state] {
+		if r < t.chance {
+			return t.to
+		}
+		r -= t.chance
+	}
+	return state
+}
+
+// setDriverState changes the state of a driver of fleet f.
+// It returns false if the driver is not registered there.
+func setDriverState(f *Fleet, id, state string) bool {
+	updated := f.Tree.UpdateByID(byID(id), func(cur quadtree.PointOf[DriverData]) quadtree.PointOf[DriverData] {
+		cur.Data.State = state
+		// An offline driver is parked
+		if state == stateOffline {
+			cur.Data.SpeedKmh = 0
+		}
+		return cur
+	})
+	if updated == nil {
+		return false
+	}
+	// The watchers following the movements see the new state too
+	hub.publish(f, updated, false)
+	return true
+}
+
+// driverState returns the state of a driver found in a fleet tree.
+// A driver without a state counts as available.
+func driverState(p *quadtree.PointOf[DriverData]) string {
+	if p.Data.State == "" {
+		return stateAvailable
+	}
+	return p.Data.State
+}
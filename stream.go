@@ -0,0 +1,361 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// watcherBuffer is the number of events a watcher can queue.
+// Beyond it, the events of a client too slow to keep up are coalesced
+// (see nearbyWatcher.backlog).
+const watcherBuffer = 256
+
+// NearbyEvent is pushed to a watcher when a driver enters or leaves its area
+// (or moves within it, for the watchers that asked for it). Driver is
+// always complete (see newDriverResponse): a removed driver leaves with
+// its last position and state.
+type NearbyEvent struct {
+	Event  string         `json:"event"` // "enter", "leave" or "move"
+	Driver DriverResponse `json:"driver"`
+}
+
+// nearbyWatcher is a client watching a search area
+type nearbyWatcher struct {
+	// The area, split where it crosses the antimeridian (see
+	// quadtree.SplitWrapped), like the box of /find-nearby
+	parts     []quadtree.Boundary
+	withMoves bool // Also send "move" events for the drivers inside the area
+	events    chan NearbyEvent
+
+	mu     sync.Mutex
+	inside map[string]bool // Drivers currently inside the area
+
+	// When 'events' is full, the next events wait in 'backlog' instead
+	// (one per driver, in the order the drivers first appeared) until
+	// the client has read the queue: a slow client skips the intermediate
+	// positions, but always ends up with the right set of drivers.
+	overflow bool
+	backlog  map[string]NearbyEvent
+	order    []string
+}
+
+// watcherHub is the pub/sub registry of the watchers: every driver change
+// is published to it, and each watcher receives the events of its own area
+type watcherHub struct {
+	mu       sync.RWMutex
+	watchers map[*nearbyWatcher]struct{}
+}
+
+var hub = &watcherHub{watchers: map[*nearbyWatcher]struct{}{}}
+
+// subscribe registers a watcher for box and returns it, together with
+// the drivers already inside box (to be sent as the first "enter" events).
+// The box wraps across the antimeridian, as in QueryWrapped.
+func (h *watcherHub) subscribe(box quadtree.Boundary, withMoves bool) (*nearbyWatcher, []NearbyEvent) {
+	w := &nearbyWatcher{
+		parts:     quadtree.SplitWrapped(worldBoundary, box),
+		withMoves: withMoves,
+		events:    make(chan NearbyEvent, watcherBuffer),
+		inside:    map[string]bool{},
+		backlog:   map[string]NearbyEvent{},
+	}
+
+	// Hold the watcher lock while taking the initial snapshot: the changes
+	// published in the meantime wait for it, and are then applied on top
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	// Watchers follow the drivers of every fleet (IDs are unique across fleets)
+	var initial []NearbyEvent
+	for _, f := range allFleets() {
+		for _, part := range w.parts {
+			f.Tree.ForEachInRange(&part, func(p *quadtree.PointOf[DriverData]) bool {
+				w.inside[p.Data.ID] = true
+				initial = append(initial, NearbyEvent{Event: "enter", Driver: newDriverResponse(f, p)})
+				return true
+			})
+		}
+	}
+	return w, initial
+}
+
+// contains reports whether (x, y) is inside the area of the watcher
+func (w *nearbyWatcher) contains(x, y float64) bool {
+	for _, part := range w.parts {
+		if part.ContainsXY(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsubscribe removes a watcher and closes its channel
+func (h *watcherHub) unsubscribe(w *nearbyWatcher) {
+	// publish sends while holding the Read Lock:
+	// under the Write Lock nobody can be sending on the channel
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.watchers[w]; ok {
+		delete(h.watchers, w)
+		close(w.events)
+	}
+}
+
+// publish notifies the watchers that a driver of fleet f is now p
+// (removed is true when p is its last point before it was removed)
+func (h *watcherHub) publish(f *Fleet, p *quadtree.PointOf[DriverData], removed bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for w := range h.watchers {
+		w.notify(f, p, removed)
+	}
+}
+
+// notify queues the event (if any) that the new point of a driver means
+// for this watcher. It never blocks the publisher (a driver goroutine or
+// a request): if the client can't keep up, the event goes to the backlog.
+func (w *nearbyWatcher) notify(f *Fleet, p *quadtree.PointOf[DriverData], removed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	event, ok := w.observe(f, p, removed)
+	if !ok {
+		return
+	}
+	// Once the queue overflowed, the events keep going to the backlog
+	// until it is flushed, so they never overtake the older ones
+	if !w.overflow {
+		select {
+		case w.events <- event:
+			return
+		default:
+			w.overflow = true
+		}
+	}
+	w.coalesce(event)
+}
+
+// coalesce merges event into the backlog entry of its driver, so that
+// the backlog only tells the client the net change since the queue overflowed
+func (w *nearbyWatcher) coalesce(event NearbyEvent) {
+	id := event.Driver.ID
+	old, ok := w.backlog[id]
+	if !ok {
+		w.backlog[id] = event
+		w.order = append(w.order, id)
+		return
+	}
+
+	switch {
+	// Entered and left again: the client never needs to know
+	case old.Event == "enter" && event.Event == "leave":
+		delete(w.backlog, id) // Its place in 'order' is skipped by flush
+		return
+	// Still entering, at the latest position
+	case old.Event == "enter":
+		event.Event = "enter"
+	// Left and came back: for the client it only moved (if it wants moves)
+	case old.Event == "leave" && event.Event == "enter":
+		if !w.withMoves {
+			delete(w.backlog, id)
+			return
+		}
+		event.Event = "move"
+	}
+	w.backlog[id] = event
+}
+
+// flush returns the backlog once the client has read the whole queue
+// (nil otherwise), and sends the next events to the queue again.
+// The streaming handlers call it after every event they write.
+func (w *nearbyWatcher) flush() []NearbyEvent {
+	if len(w.events) > 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.overflow = false
+	if len(w.backlog) == 0 {
+		return nil
+	}
+	events := make([]NearbyEvent, 0, len(w.backlog))
+	for _, id := range w.order {
+		if event, ok := w.backlog[id]; ok {
+			events = append(events, event)
+			// A driver that left the backlog and came back is listed twice
+			delete(w.backlog, id)
+		}
+	}
+	w.order = w.order[:0]
+	return events
+}
+
+// observe updates the watcher state with the new point of a driver of
+// fleet f and returns the event to send, if the driver entered or left
+// the area (or moved within it). The caller holds the watcher lock.
+func (w *nearbyWatcher) observe(f *Fleet, p *quadtree.PointOf[DriverData], removed bool) (NearbyEvent, bool) {
+	id := p.Data.ID
+	wasInside := w.inside[id]
+	isInside := !removed && w.contains(p.X, p.Y)
+
+	switch {
+	case isInside && !wasInside:
+		w.inside[id] = true
+		return NearbyEvent{Event: "enter", Driver: newDriverResponse(f, p)}, true
+	case !isInside && wasInside:
+		delete(w.inside, id)
+		return NearbyEvent{Event: "leave", Driver: newDriverResponse(f, p)}, true
+	case isInside && w.withMoves:
+		return NearbyEvent{Event: "move", Driver: newDriverResponse(f, p)}, true
+	}
+	return NearbyEvent{}, false
+}
+
+// parseWatchArea reads the area of the streaming endpoints:
+// ?lat=...&lon=...&radius=... (radius in degrees, optional).
+// On error it replies with 400 and returns false.
+func parseWatchArea(c *gin.Context) (quadtree.Boundary, bool) {
+
+	lat, lon, ok := parseLatLon(c)
+	if !ok {
+		return quadtree.Boundary{}, false
+	}
+
+	box := quadtree.Boundary{X: lon, Y: lat, Width: searchRadiusX, Height: searchRadiusY}
+	if radiusStr := c.Query("radius"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || !(radius >= 0) || math.IsInf(radius, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'radius' must be a non-negative number"})
+			return quadtree.Boundary{}, false
+		}
+		box.Width, box.Height = radius, radius
+	}
+	return box, true
+}
+
+// upgrader turns the HTTP requests into WebSocket connections.
+// It accepts the same origins as the CORS configuration of the API.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: originAllowed,
+}
+
+// handleNearbyStream streams the drivers entering and leaving an area:
+// GET /ws/nearby?lat=...&lon=...&radius=... (see parseWatchArea).
+// The drivers already inside are sent first, as "enter" events.
+func handleNearbyStream(c *gin.Context) {
+	streamWebSocket(c, false)
+}
+
+// handleStream is /ws/nearby with the movements too:
+// GET /stream?lat=...&lon=...&radius=... sends the drivers already inside
+// as "enter" events, then every "enter", "move" and "leave" in the area.
+func handleStream(c *gin.Context) {
+	streamWebSocket(c, true)
+}
+
+// streamWebSocket upgrades the request to a WebSocket and streams the
+// events of the area to it until the client disconnects
+func streamWebSocket(c *gin.Context, withMoves bool) {
+
+	box, ok := parseWatchArea(c)
+	if !ok {
+		return
+	}
+
+	// Upgrade replies with an HTTP error by itself on failure
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	w, initial := hub.subscribe(box, withMoves)
+	defer hub.unsubscribe(w)
+
+	// We never expect messages from the client, but reading is how
+	// we notice that it disconnected
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range initial {
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case event := <-w.events:
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+			for _, event := range w.flush() {
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// handleNearbyEvents is the Server-Sent Events version of /ws/nearby, for the
+// clients that can't use WebSockets: GET /events/nearby?lat=...&lon=...&radius=...
+// Every change affecting the area is sent as "event: enter|move|leave"
+// followed by a "data:" line with the JSON DriverResponse.
+func handleNearbyEvents(c *gin.Context) {
+
+	box, ok := parseWatchArea(c)
+	if !ok {
+		return
+	}
+
+	w, initial := hub.subscribe(box, true)
+	defer hub.unsubscribe(w)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	for _, event := range initial {
+		c.SSEvent(event.Event, event.Driver)
+	}
+	c.Writer.Flush()
+
+	for {
+		select {
+		// The client went away: returning unsubscribes the watcher
+		case <-c.Request.Context().Done():
+			return
+		case event := <-w.events:
+			c.SSEvent(event.Event, event.Driver)
+			for _, event := range w.flush() {
+				c.SSEvent(event.Event, event.Driver)
+			}
+			c.Writer.Flush()
+		}
+	}
+}
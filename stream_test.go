@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"GeoRunner/quadtree"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// TestNearbyEvents opens the SSE stream and checks that the movements
+// of a driver in the area arrive as events within 3 seconds
+func TestNearbyEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+
+	r := gin.New()
+	r.GET("/events/nearby", handleNearbyEvents)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events/nearby?lat=10&lon=10&radius=1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Opening the stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type text/event-stream expected, got %q", ct)
+	}
+
+	// The stream is open: the driver enters the area, moves within it, leaves it
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 10.5, Y: 10.5, Data: DriverData{ID: "d1"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+	moveDriver("d1", 10.2, 9.8, nil)
+	moveDriver("d1", 50, 50, nil)
+
+	// Read "event:" / "data:" pairs until the 3 events arrive (or the timeout)
+	expected := []string{"enter", "move", "leave"}
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for len(expected) > 0 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			var driver DriverResponse
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &driver); err != nil {
+				t.Fatalf("Invalid data line %q: %v", line, err)
+			}
+			if event != expected[0] || driver.ID != "d1" {
+				t.Errorf("Event %q for d1 expected, got %q for %q", expected[0], event, driver.ID)
+			}
+			expected = expected[1:]
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("Events %v not received within 3 seconds (%v)", expected, scanner.Err())
+	}
+}
+
+// TestStream opens the WebSocket stream and checks that it starts with
+// the drivers already in the area, then follows their movements
+func TestStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resetFleets(t)
+
+	r := gin.New()
+	r.GET("/stream", handleStream)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// d1 is already in the area when the client connects
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 10.5, Y: 10.5, Data: DriverData{ID: "d1"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/stream?lat=10&lon=10&radius=1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Opening the stream: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+
+	var first NearbyEvent
+	if err := conn.ReadJSON(&first); err != nil {
+		t.Fatalf("Reading the initial set: %v", err)
+	}
+	if first.Event != "enter" || first.Driver.ID != "d1" {
+		t.Fatalf("Initial \"enter\" for d1 expected, got %+v", first)
+	}
+
+	// Then d2 enters, moves within the area and leaves it
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: 9.5, Y: 9.5, Data: DriverData{ID: "d2"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+	moveDriver("d2", 10.2, 9.8, nil)
+	moveDriver("d2", 50, 50, nil)
+
+	for _, expected := range []string{"enter", "move", "leave"} {
+		var event NearbyEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("Event %q not received: %v", expected, err)
+		}
+		if event.Event != expected || event.Driver.ID != "d2" {
+			t.Errorf("Event %q for d2 expected, got %q for %q", expected, event.Event, event.Driver.ID)
+		}
+	}
+}
+
+// TestWatcherBacklog fills the queue of a watcher nobody reads and checks
+// that the events beyond it are coalesced into the net change per driver
+func TestWatcherBacklog(t *testing.T) {
+	w := &nearbyWatcher{
+		parts:     []quadtree.Boundary{{X: 0, Y: 0, Width: 10, Height: 10}},
+		withMoves: true,
+		events:    make(chan NearbyEvent, 1),
+		inside:    map[string]bool{},
+		backlog:   map[string]NearbyEvent{},
+	}
+	f := &Fleet{Name: "car"}
+	at := func(id string, x, y float64) *quadtree.PointOf[DriverData] {
+		return &quadtree.PointOf[DriverData]{X: x, Y: y, Data: DriverData{ID: id}}
+	}
+
+	w.notify(f, at("queued", 1, 1), false) // Fills the queue
+	w.notify(f, at("a", 2, 2), false)      // Enters...
+	w.notify(f, at("a", 3, 3), false)      // ...and moves: one "enter" at (3, 3)
+	w.notify(f, at("b", 4, 4), false)      // Enters...
+	w.notify(f, at("b", 4, 4), true)       // ...and is removed: nothing
+	w.notify(f, at("queued", 50, 50), false)
+	w.notify(f, at("queued", 5, 5), false) // Leaves and comes back: a "move"
+
+	// Nothing is flushed while the queue still holds events
+	if events := w.flush(); events != nil {
+		t.Fatalf("No backlog expected before the queue is read, got %+v", events)
+	}
+	if event := <-w.events; event.Event != "enter" || event.Driver.ID != "queued" {
+		t.Fatalf("Queued \"enter\" expected, got %+v", event)
+	}
+
+	events := w.flush()
+	expected := []NearbyEvent{
+		{Event: "enter", Driver: DriverResponse{ID: "a", Fleet: "car", State: stateAvailable, Lat: 3, Lon: 3}},
+		{Event: "move", Driver: DriverResponse{ID: "queued", Fleet: "car", State: stateAvailable, Lat: 5, Lon: 5}},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Backlog %+v expected, got %+v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Backlog event %d: %+v expected, got %+v", i, expected[i], events[i])
+		}
+	}
+
+	// The backlog is flushed: the next events go to the queue again
+	w.notify(f, at("a", 6, 6), false)
+	if event := <-w.events; event.Event != "move" || event.Driver.ID != "a" {
+		t.Errorf("Queued \"move\" for a expected, got %+v", event)
+	}
+}
+
+// TestStreamAntimeridian watches an area crossing the antimeridian and
+// checks that the drivers on both sides of it are followed, and that
+// every event carries the whole driver (fleet and state included)
+func TestStreamAntimeridian(t *testing.T) {
+	resetFleets(t)
+	if err := addDriver(fleets[defaultFleet], &quadtree.PointOf[DriverData]{X: -179.5, Y: 0, Data: DriverData{ID: "west", State: stateBusy}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+
+	w, initial := hub.subscribe(quadtree.Boundary{X: 179.5, Y: 0, Width: 2, Height: 2}, true)
+	defer hub.unsubscribe(w)
+
+	// --- Test 1: the initial set reaches across the antimeridian ---
+	if len(initial) != 1 || initial[0].Driver != (DriverResponse{ID: "west", Fleet: "car", State: stateBusy, Lat: 0, Lon: -179.5}) {
+		t.Fatalf("Initial \"enter\" for west (car, busy) expected, got %+v", initial)
+	}
+
+	// --- Test 2: so do the incremental events, with every field ---
+	if err := addDriver(fleets["bike"], &quadtree.PointOf[DriverData]{X: 50, Y: 0, Data: DriverData{ID: "rider"}}); err != nil {
+		t.Fatalf("addDriver: %v", err)
+	}
+	speed := 30.0
+	moveDriver("rider", 0.5, 179.8, &speed) // Enters from the East side
+	moveDriver("rider", 0.5, -179.8, nil)   // Crosses the antimeridian
+	setDriverState(fleets["bike"], "rider", stateBusy)
+	removeDriver("rider") // Leaves from where it was
+
+	expected := []string{"enter", "move", "move", "leave"}
+	for i, name := range expected {
+		event := <-w.events
+		d := event.Driver
+		if event.Event != name || d.ID != "rider" || d.Fleet != "bike" || d.Lat != 0.5 || d.SpeedKmh != speed {
+			t.Errorf("Event %d: %q for rider (bike, 30 km/h, lat 0.5) expected, got %+v", i, name, event)
+		}
+		if want := map[bool]string{false: stateAvailable, true: stateBusy}[i >= 2]; d.State != want {
+			t.Errorf("Event %d: state %q expected, got %q", i, want, d.State)
+		}
+	}
+}